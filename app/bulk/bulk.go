@@ -0,0 +1,220 @@
+// Package bulk verifies a directory of certificates offline, e.g. to audit the
+// certificates partners sent, and writes a CSV or NDJSON report of the results.
+package bulk
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+var ErrUnknownFormat = errors.New("unknown report format")
+
+type Verdict string
+
+const (
+	Valid   Verdict = "valid"
+	Invalid Verdict = "invalid"
+	// Error is the verdict of a file that could not be parsed or a certificate that could not be verified.
+	Error Verdict = "error"
+)
+
+// Extensions are the file extensions read from the directory.
+var Extensions = []string{".pem", ".crt", ".cer", ".der", ".p7c", ".p7b"}
+
+// Row is the report line of a certificate.
+type Row struct {
+	File    string `json:"file"`
+	Sha256  string `json:"sha256,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	TppId   string `json:"tpp_id,omitempty"`
+	TppName string `json:"tpp_name,omitempty"`
+	Usage   string `json:"usage,omitempty"`
+	// Scopes maps the countries to the services the TPP may use there.
+	Scopes  map[string][]string `json:"scopes,omitempty"`
+	Verdict Verdict             `json:"verdict"`
+	// Reasons are the codes of the checks that failed, Warnings of those that only warn.
+	Reasons  []models.CheckCode `json:"reasons,omitempty"`
+	Warnings []models.CheckCode `json:"warnings,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+type leaf struct {
+	file string
+	cert *cert.ParsedCert
+}
+
+// Verify verifies every end-entity certificate found in the files of the directory and its
+// subdirectories. CA certificates found in the directory are supplied as intermediates with
+// every certificate, so that chains can be built from certificates sent separately.
+// Rows are in the order of the files, and of the certificates within a file.
+func Verify(ctx context.Context, v verify.BatchVerifier, dir string) ([]Row, error) {
+	var leaves []leaf
+	var rows []Row
+	var chain []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !slices.Contains(Extensions, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		certs, err := cert.ParseCerts(data)
+		if err != nil {
+			rows = append(rows, Row{File: name, Verdict: Error, Error: err.Error()})
+			return nil
+		}
+		for _, crt := range certs {
+			if crt.Cert.IsCA {
+				chain = append(chain, string(crt.Pem()))
+			} else {
+				leaves = append(leaves, leaf{file: name, cert: crt})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(leaves); start += verify.MaxBatchSize {
+		batch := leaves[start:min(start+verify.MaxBatchSize, len(leaves))]
+		req := verify.BatchVerifyRequest{Items: make([]verify.VerifyRequest, len(batch))}
+		for i, l := range batch {
+			req.Items[i] = verify.VerifyRequest{Cert: string(l.cert.Pem()), Chain: chain}
+		}
+		res, err := v.VerifyBatch(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range res.Results {
+			rows = append(rows, newRow(batch[item.Index], item))
+		}
+	}
+	// Unparsable files were added while walking, restore the order of the files
+	slices.SortStableFunc(rows, func(a, b Row) int { return strings.Compare(a.File, b.File) })
+	return rows, nil
+}
+
+func newRow(l leaf, item verify.BatchVerifyItem) Row {
+	row := Row{
+		File:    l.file,
+		Sha256:  l.cert.Sha256(),
+		Subject: l.cert.Cert.Subject.String(),
+		Usage:   string(l.cert.Usage()),
+	}
+	if item.Error != "" {
+		row.Verdict, row.Error = Error, item.Error
+		return row
+	}
+	res := item.Result
+	row.Verdict = Invalid
+	if res.Valid {
+		row.Verdict = Valid
+	}
+	if res.TPP != nil {
+		row.TppId, row.TppName = res.TPP.Id, res.TPP.NameLatin
+	}
+	row.Scopes = res.Scopes
+	for _, check := range res.Checks {
+		switch check.Status {
+		case models.CheckStatusFail, models.CheckStatusError:
+			row.Reasons = append(row.Reasons, check.Code)
+		case models.CheckStatusWarning:
+			row.Warnings = append(row.Warnings, check.Code)
+		}
+	}
+	return row
+}
+
+// Failed reports whether any certificate is not valid.
+func Failed(rows []Row) bool {
+	return slices.ContainsFunc(rows, func(r Row) bool { return r.Verdict != Valid })
+}
+
+// Write writes the rows as "csv" or "ndjson".
+func Write(w io.Writer, format string, rows []Row) error {
+	switch format {
+	case "csv":
+		return writeCSV(w, rows)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+var csvHeader = []string{"file", "sha256", "subject", "tpp_id", "tpp_name", "usage", "scopes", "verdict", "reasons", "warnings", "error"}
+
+// writeCSV writes a line per row. Scopes are written as "FI:AIS|PIS SE:AIS",
+// reason and warning codes separated by spaces.
+func writeCSV(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		countries := make([]string, 0, len(row.Scopes))
+		for country := range row.Scopes {
+			countries = append(countries, country)
+		}
+		slices.Sort(countries)
+		scopes := make([]string, len(countries))
+		for i, country := range countries {
+			scopes[i] = country + ":" + strings.Join(row.Scopes[country], "|")
+		}
+		record := []string{
+			row.File, row.Sha256, row.Subject, row.TppId, row.TppName, row.Usage,
+			strings.Join(scopes, " "), string(row.Verdict),
+			joinCodes(row.Reasons), joinCodes(row.Warnings), row.Error,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func joinCodes(codes []models.CheckCode) string {
+	s := make([]string, len(codes))
+	for i, code := range codes {
+		s[i] = string(code)
+	}
+	return strings.Join(s, " ")
+}
+
+// Summary returns a line counting the rows by verdict.
+func Summary(rows []Row) string {
+	counts := map[Verdict]int{}
+	for _, row := range rows {
+		counts[row.Verdict]++
+	}
+	return fmt.Sprintf("%d certificates: %d valid, %d invalid, %d errors", len(rows), counts[Valid], counts[Invalid], counts[Error])
+}
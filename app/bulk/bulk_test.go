@@ -0,0 +1,166 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+// stubVerifier rejects the given certificate and accepts the others.
+type stubVerifier struct {
+	reject string
+	req    verify.BatchVerifyRequest
+}
+
+func (s *stubVerifier) VerifyBatch(ctx context.Context, req verify.BatchVerifyRequest) (*verify.BatchVerifyResponse, error) {
+	s.req = req
+	res := &verify.BatchVerifyResponse{}
+	for i, item := range req.Items {
+		result := &verify.VerifyResponse{
+			Valid:  true,
+			TPP:    &models.TppResponse{Id: "PSDFIN-FINFSA-12345678", NameLatin: "Test TPP"},
+			Scopes: map[string][]string{"SE": {"AIS"}, "FI": {"AIS", "PIS"}},
+			Checks: []models.Check{
+				{Name: models.CheckChain, Status: models.CheckStatusPass, Code: models.CodeChainTrusted},
+				{Name: models.CheckSubjectName, Status: models.CheckStatusWarning, Code: models.CodeNameMismatch},
+			},
+		}
+		if item.Cert == s.reject {
+			result.Valid = false
+			result.Checks = append(result.Checks, models.Check{Name: models.CheckRevocation, Status: models.CheckStatusFail, Code: models.CodeRevoked})
+		}
+		res.Results = append(res.Results, verify.BatchVerifyItem{Index: i, Result: result})
+	}
+	return res, nil
+}
+
+func copyTestFile(t *testing.T, dir, name, target string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "chains", "production", name))
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, target)), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, target), data, 0o600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+}
+
+// setupDir returns a directory of test certificates and the PEM of its QWAC.
+func setupDir(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	copyTestFile(t, dir, "leaf.pem", "a-leaf.pem")
+	copyTestFile(t, dir, "qwac.pem", "partner/qwac.crt")
+	copyTestFile(t, dir, "intermediate.pem", "intermediate.pem")
+	if err := os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("skipped"), 0o600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "partner", "qwac.crt"))
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	certs, err := cert.ParseCerts(data)
+	if err != nil {
+		t.Fatalf("Failed to parse test file: %v", err)
+	}
+	return dir, string(certs[0].Pem())
+}
+
+func TestVerify(t *testing.T) {
+	dir, qwac := setupDir(t)
+	v := &stubVerifier{reject: qwac}
+	rows, err := Verify(context.Background(), v, dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(v.req.Items) != 2 {
+		t.Fatalf("Expected 2 certificates to be verified, got %d", len(v.req.Items))
+	}
+	if len(v.req.Items[0].Chain) != 1 {
+		t.Errorf("Expected the intermediate to be supplied, got %d chain certificates", len(v.req.Items[0].Chain))
+	}
+	want := []struct {
+		file    string
+		verdict Verdict
+	}{
+		{"a-leaf.pem", Valid},
+		{"broken.pem", Error},
+		{filepath.Join("partner", "qwac.crt"), Invalid},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %+v", len(want), rows)
+	}
+	for i, w := range want {
+		if rows[i].File != w.file || rows[i].Verdict != w.verdict {
+			t.Errorf("Expected %s to be %s, got %s %s", w.file, w.verdict, rows[i].File, rows[i].Verdict)
+		}
+	}
+	if len(rows[2].Reasons) != 1 || rows[2].Reasons[0] != models.CodeRevoked {
+		t.Errorf("Expected reason %s, got %v", models.CodeRevoked, rows[2].Reasons)
+	}
+	if len(rows[0].Warnings) != 1 || rows[0].Sha256 == "" || rows[0].TppId == "" {
+		t.Errorf("Expected the certificate, TPP and warnings, got %+v", rows[0])
+	}
+	if !Failed(rows) || Failed(rows[:1]) {
+		t.Error("Expected only a report with invalid certificates to fail")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, qwac := setupDir(t)
+	rows, err := Verify(context.Background(), &stubVerifier{reject: qwac}, dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, "csv", rows); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected a valid CSV, got %v", err)
+	}
+	if len(records) != len(rows)+1 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		t.Fatalf("Expected a header and %d lines, got %v", len(rows), records)
+	}
+	if scopes := records[1][6]; scopes != "FI:AIS|PIS SE:AIS" {
+		t.Errorf("Expected scopes sorted by country, got %q", scopes)
+	}
+	if reasons := records[3][8]; reasons != string(models.CodeRevoked) {
+		t.Errorf("Expected reason %s, got %q", models.CodeRevoked, reasons)
+	}
+
+	out.Reset()
+	if err := Write(&out, "ndjson", rows); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(rows) {
+		t.Fatalf("Expected %d lines, got %d", len(rows), len(lines))
+	}
+	var row Row
+	if err := json.Unmarshal([]byte(lines[1]), &row); err != nil || row.Verdict != Error || row.Error == "" {
+		t.Errorf("Expected the error row, got %s", lines[1])
+	}
+
+	if err := Write(&out, "xlsx", rows); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected error %v, got %v", ErrUnknownFormat, err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"log"
 	"strings"
 	"time"
@@ -20,6 +21,9 @@ import (
 	"github.com/fullsailor/pkcs7"
 )
 
+// ErrInvalidNcaId is returned for a PSD2 QcStatement whose NCA identifier has no country code.
+var ErrInvalidNcaId = errors.New("invalid NCA identifier")
+
 type PSD2QcType struct {
 	RolesOfPSP []Role
 	NCAName    string
@@ -289,6 +293,32 @@ func (c *ParsedCert) OBScopes() ([]models.Scope, error) {
 			return models.ScopeUnknown
 		}
 	}
+	psd2, err := c.psd2QcType()
+	if err != nil || psd2 == nil {
+		return nil, err
+	}
+	roles := make([]models.Scope, 0)
+	for _, role := range psd2.RolesOfPSP {
+		roles = append(roles, roleToScope(role.Value))
+	}
+	return roles, nil
+}
+
+// Roles returns the PSD2 roles of the certificate.
+func (c *ParsedCert) Roles() ([]models.ObRole, error) {
+	psd2, err := c.psd2QcType()
+	if err != nil || psd2 == nil {
+		return nil, err
+	}
+	roles := make([]models.ObRole, 0, len(psd2.RolesOfPSP))
+	for _, role := range psd2.RolesOfPSP {
+		roles = append(roles, role.Value)
+	}
+	return roles, nil
+}
+
+// psd2QcType decodes the PSD2 QcStatement, nil if the certificate does not have one.
+func (c *ParsedCert) psd2QcType() (*PSD2QcType, error) {
 	for _, ext := range c.Cert.Extensions {
 		if !ext.Id.Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 3}) {
 			continue
@@ -316,11 +346,7 @@ func (c *ParsedCert) OBScopes() ([]models.Scope, error) {
 				if err != nil {
 					return nil, err
 				}
-				roles := make([]models.Scope, 0)
-				for _, role := range psd2.RolesOfPSP {
-					roles = append(roles, roleToScope(role.Value))
-				}
-				return roles, nil
+				return &psd2, nil
 			}
 		}
 	}
@@ -328,12 +354,18 @@ func (c *ParsedCert) OBScopes() ([]models.Scope, error) {
 }
 
 func (c *ParsedCert) CertificateResponse() (*models.CertificateResponse, error) {
+	return c.CertificateResponseAt(time.Now())
+}
+
+// CertificateResponseAt returns the certificate response as of the given time.
+func (c *ParsedCert) CertificateResponseAt(at time.Time) (*models.CertificateResponse, error) {
 	certScopes, err := c.OBScopes()
 	if err != nil {
 		return nil, err
 	}
 	return &models.CertificateResponse{
-		Expired:      c.Expired(),
+		Sha256:       c.Sha256(),
+		Expired:      c.ExpiredAt(at),
 		Scopes:       certScopes,
 		SerialNumber: c.Cert.SerialNumber.String(),
 		Issuer:       pkixNameToMap(c.Cert.Issuer),
@@ -341,6 +373,8 @@ func (c *ParsedCert) CertificateResponse() (*models.CertificateResponse, error)
 		NotBefore:    c.Cert.NotBefore.String(),
 		NotAfter:     c.Cert.NotAfter.String(),
 		Usage:        c.Usage(),
+		ValidFrom:    c.Cert.NotBefore,
+		ValidUntil:   c.Cert.NotAfter,
 	}, nil
 }
 
@@ -385,11 +419,15 @@ func pkixNameToMap(name pkix.Name) map[string]any {
 }
 
 func (c *ParsedCert) Expired() bool {
+	return c.ExpiredAt(time.Now())
+}
+
+// ExpiredAt reports whether the certificate is outside of its validity period at the given time.
+func (c *ParsedCert) ExpiredAt(at time.Time) bool {
 	if c.Cert == nil {
 		return false
 	}
-	now := time.Now()
-	return !(now.After(c.Cert.NotBefore) && now.Before(c.Cert.NotAfter))
+	return !(at.After(c.Cert.NotBefore) && at.Before(c.Cert.NotAfter))
 }
 
 func (c *ParsedCert) Usage() models.CertUsage {
@@ -412,39 +450,16 @@ func (c *ParsedCert) Sha256() string {
 }
 
 func (c *ParsedCert) NCA() (*NCA, error) {
-	for _, ext := range c.Cert.Extensions {
-		if !ext.Id.Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 3}) {
-			continue
-		}
-		var qcStatements []QCStatement
-		_, err := asn1.Unmarshal(ext.Value, &qcStatements)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, stmt := range qcStatements {
-			switch {
-			// case stmt.ID.Equal(asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 5}):
-			// 	var urlStructs []URLStruct
-			// 	_, err := asn1.Unmarshal(stmt.Value.FullBytes, &urlStructs)
-			// 	if err != nil {
-			// 		fmt.Println(err)
-			// 	}
-			// 	for _, urlStruct := range urlStructs {
-			// 		fmt.Println(urlStruct.URL, urlStruct.Lang)
-			// 	}
-			case stmt.ID.Equal(asn1.ObjectIdentifier{0, 4, 0, 19495, 2}):
-				var psd2 PSD2QcType
-				_, err := asn1.Unmarshal(stmt.Value.FullBytes, &psd2)
-				if err != nil {
-					return nil, err
-				}
-				country := psd2.NCAId[:2]
-				return &NCA{Country: country, Name: psd2.NCAName, Id: psd2.NCAId}, nil
-			}
-		}
+	psd2, err := c.psd2QcType()
+	if err != nil || psd2 == nil {
+		return nil, err
 	}
-	return nil, nil
+	// The NCA identifier starts with the country code, e.g. FI-FINFSA
+	if len(psd2.NCAId) < 2 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidNcaId, psd2.NCAId)
+	}
+	country := psd2.NCAId[:2]
+	return &NCA{Country: country, Name: psd2.NCAName, Id: psd2.NCAId}, nil
 }
 
 type PolicyInformation struct {
@@ -0,0 +1,31 @@
+package clock
+
+import "time"
+
+// Clock provides the current time, so that verification can be evaluated
+// at an arbitrary instant and tests do not depend on the wall clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System returns the wall clock.
+func System() Clock {
+	return systemClock{}
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// Fixed returns a clock that is always at the given instant.
+func Fixed(at time.Time) Clock {
+	return fixedClock(at)
+}
@@ -0,0 +1,21 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// ErrEvidenceNotFound is returned by GetEvidence when there is no such evidence or it has expired.
+var ErrEvidenceNotFound = errors.New("evidence not found")
+
+type EvidenceRepository interface {
+	AddEvidence(ctx context.Context, evidence *models.Evidence) error
+	// GetEvidence returns the evidence of the verification unless it expired before now.
+	GetEvidence(ctx context.Context, id string, now time.Time) (*models.Evidence, error)
+	// DeleteExpiredEvidence removes the evidence that expired before the given time
+	// and returns the number of removed bundles.
+	DeleteExpiredEvidence(ctx context.Context, before time.Time) (int64, error)
+}
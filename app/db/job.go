@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// ErrJobNotFound is returned by GetJob when there is no such job,
+// and by ClaimJob when no job can be claimed.
+var ErrJobNotFound = errors.New("job not found")
+
+type JobRepository interface {
+	AddJob(ctx context.Context, job *models.Job) error
+	GetJob(ctx context.Context, id string) (*models.Job, error)
+	// ClaimJob leases the oldest job that is queued, running or has a pending callback
+	// and is not leased at now, until the given time. The lease is taken atomically,
+	// so that a job is worked on by a single instance.
+	ClaimJob(ctx context.Context, now time.Time, until time.Time) (*models.Job, error)
+	// UpdateJob replaces the stored job.
+	UpdateJob(ctx context.Context, job *models.Job) error
+	// DeleteExpiredJobs removes the finished jobs that expired before the given time
+	// and returns the number of removed jobs.
+	DeleteExpiredJobs(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+var (
+	// ErrTenantNotFound is returned by GetTenantByApiKey and GetTenant when there is no such active tenant.
+	ErrTenantNotFound = errors.New("tenant not found")
+	// ErrPolicyNotFound is returned by GetPolicy when the repository has no such policy.
+	ErrPolicyNotFound = errors.New("policy not found")
+)
+
+type TenantRepository interface {
+	// GetTenantByApiKey returns the active tenant owning the API key with the given SHA-256 hash.
+	GetTenantByApiKey(ctx context.Context, keyHash string) (*models.Tenant, error)
+	// GetTenant returns the active tenant with the given ID.
+	GetTenant(ctx context.Context, id string) (*models.Tenant, error)
+	GetPolicy(ctx context.Context, name string) (*policy.Policy, error)
+	// AddUsage adds requests to the counter of the tenant for the given day.
+	AddUsage(ctx context.Context, tenantId string, day string, requests int64) error
+	// GetUsage returns the daily counters of the tenant since the given day, oldest first.
+	GetUsage(ctx context.Context, tenantId string, since string) ([]models.TenantUsage, error)
+}
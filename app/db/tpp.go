@@ -2,13 +2,57 @@ package db
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/botsman/tppVerifier/app/cert"
 	"github.com/botsman/tppVerifier/app/models"
 )
 
+// ErrTppNotFound is returned by GetTpp and GetTppByEntityCode when the registry has no such TPP.
+var ErrTppNotFound = errors.New("TPP not found")
+
+// TppFilter selects the TPPs returned by SearchTpps. Empty fields do not filter.
+type TppFilter struct {
+	// Name matches TPPs whose latin or native name contains it, ignoring case.
+	Name      string
+	Country   string
+	Authority string
+	// Type is the EBA entity type, e.g. PSD_AISP, PSD_PI or PSD_EMI.
+	Type    string
+	Service models.Service
+	// PassportedCountry matches TPPs that provide services in the country besides their home country.
+	// Together with Service, the service must be provided in that country.
+	PassportedCountry string
+	// Status matches TPPs with the authorization status at the time At.
+	Status models.AuthorizationStatus
+	At     time.Time
+	// After is the OB ID after which TPPs are returned, they are ordered by OB ID.
+	After string
+	Limit int
+}
+
+// TppChangeFilter selects the changes returned by GetTppChanges.
+type TppChangeFilter struct {
+	// After is the sequence number after which changes are returned.
+	After int64
+	// Since skips the changes recorded before it, if it is not zero.
+	Since time.Time
+	Limit int
+}
+
 type TppRepository interface {
+	// GetTpp returns the TPP with the given OB ID.
 	GetTpp(ctx context.Context, id string) (*models.TPP, error)
+	// GetTppByEntityCode returns the TPP with the given EBA entity code.
+	GetTppByEntityCode(ctx context.Context, code string) (*models.TPP, error)
+	// GetTppsByNationalReference returns the TPPs whose OB ID ends with the given national
+	// reference code, without dashes. Several authorities may have issued the same code.
+	GetTppsByNationalReference(ctx context.Context, code string) ([]*models.TPP, error)
+	// SearchTpps returns up to filter.Limit TPPs matching the filter, ordered by OB ID.
+	SearchTpps(ctx context.Context, filter TppFilter) ([]*models.TPP, error)
+	// GetTppChanges returns up to filter.Limit changes recorded by the registry import, in order.
+	GetTppChanges(ctx context.Context, filter TppChangeFilter) ([]models.TppChange, error)
 	GetRootCertificates(ctx context.Context) ([]string, error)
 	AddCertificate(ctx context.Context, cert *cert.ParsedCert) error
 }
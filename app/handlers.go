@@ -0,0 +1,440 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/jobs"
+	"github.com/botsman/tppVerifier/app/report"
+	"github.com/botsman/tppVerifier/app/tenant"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+// verifyHandler adapts a verify.Verifier to a gin handler. The result is rendered as an
+// ETSI TS 119 102-2 validation report if XML is requested with the Accept header or format=xml.
+func verifyHandler(v verify.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		asXML, ok := wantsXML(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid format parameter.",
+			})
+			return
+		}
+		var req verify.VerifyRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request format.",
+			})
+			return
+		}
+		if c.Query("explain") == "true" {
+			req.Explain = true
+		}
+		res, err := v.Verify(c.Request.Context(), req)
+		if err != nil {
+			status, msg := verifyError(err)
+			c.JSON(status, gin.H{
+				"error": msg,
+			})
+			return
+		}
+		if asXML {
+			out, err := report.New(res, validatorName).Marshal()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to render validation report.",
+				})
+				return
+			}
+			c.Data(http.StatusOK, "application/xml; charset=utf-8", out)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// validatorName identifies the verifier in validation reports.
+const validatorName = "tppVerifier"
+
+// wantsXML reports whether a validation report is requested. The format parameter
+// takes precedence over the Accept header; false is returned for an unknown format.
+func wantsXML(c *gin.Context) (asXML bool, ok bool) {
+	switch c.Query("format") {
+	case "":
+	case "json":
+		return false, true
+	case "xml":
+		return true, true
+	default:
+		return false, false
+	}
+	switch c.NegotiateFormat(gin.MIMEJSON, gin.MIMEXML, gin.MIMEXML2) {
+	case gin.MIMEXML, gin.MIMEXML2:
+		return true, true
+	}
+	return false, true
+}
+
+func verifyError(err error) (int, string) {
+	switch {
+	case errors.Is(err, verify.ErrInvalidCertificate):
+		return http.StatusBadRequest, "Invalid certificate format."
+	case errors.Is(err, verify.ErrNoCertificate):
+		return http.StatusBadRequest, "No valid certificate found"
+	case errors.Is(err, verify.ErrCertificateParse):
+		return http.StatusBadRequest, "Failed to parse certificate."
+	case errors.Is(err, verify.ErrNoScopes):
+		return http.StatusBadRequest, "No valid scopes found in the certificate"
+	case errors.Is(err, verify.ErrReceiptsDisabled):
+		return http.StatusBadRequest, "Receipts are not enabled."
+	case errors.Is(err, verify.ErrTppLookup):
+		return http.StatusInternalServerError, "Failed to retrieve TPP information."
+	default:
+		return http.StatusInternalServerError, "Failed to verify certificate."
+	}
+}
+
+// requestError answers the errors of an invalid request with their message, and other
+// errors without their details.
+func requestError(err error, invalid ...error) (int, gin.H) {
+	for _, target := range invalid {
+		if errors.Is(err, target) {
+			return http.StatusBadRequest, gin.H{"error": err.Error()}
+		}
+	}
+	log.Printf("Failed to process the request: %v", err)
+	return http.StatusInternalServerError, gin.H{"error": "Failed to process the request."}
+}
+
+// batchVerifyHandler adapts a verify.BatchVerifier to a gin handler.
+func batchVerifyHandler(v verify.BatchVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req verify.BatchVerifyRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request format.",
+			})
+			return
+		}
+		res, err := v.VerifyBatch(c.Request.Context(), req)
+		if err != nil {
+			c.JSON(requestError(err, verify.ErrEmptyBatch, verify.ErrBatchTooLarge))
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// pairVerifyHandler adapts a verify.PairVerifier to a gin handler.
+func pairVerifyHandler(v verify.PairVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req verify.PairVerifyRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request format.",
+			})
+			return
+		}
+		res, err := v.VerifyPair(c.Request.Context(), req)
+		if err != nil {
+			status, msg := verifyError(err)
+			c.JSON(status, gin.H{
+				"error": msg,
+			})
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// maxUsageDays bounds the period of the usage report.
+const maxUsageDays = 366
+
+// usageHandler returns the daily request counters of the calling tenant.
+func usageHandler(auth *tenant.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t := verify.TenantFromContext(c.Request.Context())
+		if t == nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Usage is only available with an API key.",
+			})
+			return
+		}
+		days := 30
+		if v := c.Query("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxUsageDays {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Invalid days parameter.",
+				})
+				return
+			}
+			days = n
+		}
+		usage, err := auth.Usage(c.Request.Context(), t.Id, days)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve usage.",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"tenant_id": t.Id,
+			"usage":     usage,
+		})
+	}
+}
+
+// keysHandler serves the JWK Set receipts are signed with.
+func keysHandler(v verify.ReceiptVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwks, err := v.ReceiptKeys()
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Receipts are not enabled.",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, jwks)
+	}
+}
+
+type receiptVerifyRequest struct {
+	Receipt string `json:"receipt" binding:"required"`
+}
+
+// receiptVerifyHandler checks the signature of a receipt and returns its claims.
+func receiptVerifyHandler(v verify.ReceiptVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req receiptVerifyRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request format.",
+			})
+			return
+		}
+		claims, err := v.VerifyReceipt(req.Receipt)
+		switch {
+		case errors.Is(err, verify.ErrReceiptsDisabled):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Receipts are not enabled.",
+			})
+		case err != nil:
+			c.JSON(http.StatusOK, gin.H{
+				"valid": false,
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusOK, gin.H{
+				"valid":  true,
+				"claims": claims,
+			})
+		}
+	}
+}
+
+// evidenceHandler returns the evidence of a verification by its ID.
+func evidenceHandler(v verify.EvidenceProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		evidence, err := v.GetEvidence(c.Request.Context(), c.Param("id"))
+		switch {
+		case errors.Is(err, verify.ErrEvidenceDisabled):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Evidence preservation is not enabled.",
+			})
+		case errors.Is(err, db.ErrEvidenceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Verification not found.",
+			})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve evidence.",
+			})
+		default:
+			c.JSON(http.StatusOK, evidence)
+		}
+	}
+}
+
+// submitJobHandler queues a verification and returns the job without waiting for it.
+func submitJobHandler(q jobs.JobQueue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req jobs.Request
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request format.",
+			})
+			return
+		}
+		job, err := q.Submit(c.Request.Context(), req)
+		switch {
+		case errors.Is(err, jobs.ErrCallbacksDisabled):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Callbacks are not enabled.",
+			})
+		case errors.Is(err, jobs.ErrInvalidCallback):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid callback URL.",
+			})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to submit job.",
+			})
+		default:
+			c.Header("Location", c.Request.URL.Path+"/"+job.Id)
+			c.JSON(http.StatusAccepted, job)
+		}
+	}
+}
+
+// jobHandler returns a job by its ID, with the result once it succeeded.
+func jobHandler(q jobs.JobQueue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := q.Get(c.Request.Context(), c.Param("id"))
+		switch {
+		case errors.Is(err, db.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Job not found.",
+			})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve job.",
+			})
+		default:
+			c.JSON(http.StatusOK, job)
+		}
+	}
+}
+
+// tppHandler returns the registry record of a TPP by its OB ID, EBA entity code or national reference code.
+func tppHandler(v verify.TppLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tpp, err := v.LookupTpp(c.Request.Context(), c.Param("id"))
+		switch {
+		case errors.Is(err, db.ErrTppNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "TPP not found.",
+			})
+		case errors.Is(err, verify.ErrAmbiguousTppId):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve TPP information.",
+			})
+		default:
+			c.JSON(http.StatusOK, tpp)
+		}
+	}
+}
+
+// tppLookupHandler returns the registry records of several TPPs.
+func tppLookupHandler(v verify.TppLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req verify.TppLookupRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request format.",
+			})
+			return
+		}
+		res, err := v.LookupTpps(c.Request.Context(), req)
+		if err != nil {
+			c.JSON(requestError(err, verify.ErrNoTppIds, verify.ErrTooManyTppIds))
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// tppSearchHandler returns a page of the TPPs matching the query parameters.
+func tppSearchHandler(v verify.TppSearcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := verify.TppSearchRequest{
+			Name:              c.Query("name"),
+			Country:           c.Query("country"),
+			Authority:         c.Query("authority"),
+			Type:              c.Query("type"),
+			Service:           c.Query("service"),
+			PassportedCountry: c.Query("passported_country"),
+			Status:            c.Query("status"),
+			Cursor:            c.Query("cursor"),
+		}
+		var ok bool
+		if req.Limit, ok = queryLimit(c); !ok {
+			return
+		}
+		res, err := v.SearchTpps(c.Request.Context(), req)
+		switch {
+		case errors.Is(err, verify.ErrInvalidTppSearch):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to search TPPs.",
+			})
+		default:
+			c.JSON(http.StatusOK, res)
+		}
+	}
+}
+
+// tppChangesHandler returns a page of the registry changes since the since or cursor query parameter.
+func tppChangesHandler(v verify.TppChangeFeed) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := verify.TppChangesRequest{Cursor: c.Query("cursor")}
+		if since := c.Query("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Invalid since parameter.",
+				})
+				return
+			}
+			req.Since = t
+		}
+		var ok bool
+		if req.Limit, ok = queryLimit(c); !ok {
+			return
+		}
+		res, err := v.TppChanges(c.Request.Context(), req)
+		switch {
+		case errors.Is(err, verify.ErrInvalidTppSearch):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve TPP changes.",
+			})
+		default:
+			c.JSON(http.StatusOK, res)
+		}
+	}
+}
+
+// queryLimit returns the limit query parameter, 0 if it is not set. An invalid limit
+// is answered with 400 and false is returned.
+func queryLimit(c *gin.Context) (int, bool) {
+	v := c.Query("limit")
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid limit parameter.",
+		})
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,356 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/jobs"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+	"github.com/botsman/tppVerifier/app/tenant"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+type stubVerifier struct {
+	res *verify.VerifyResponse
+	err error
+	req verify.VerifyRequest
+}
+
+func (s *stubVerifier) Verify(ctx context.Context, req verify.VerifyRequest) (*verify.VerifyResponse, error) {
+	s.req = req
+	return s.res, s.err
+}
+
+func TestVerifyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		body     string
+		verifier *stubVerifier
+		want     int
+	}{
+		{"Valid", `{"cert": "abc"}`, &stubVerifier{res: &verify.VerifyResponse{Valid: true}}, http.StatusOK},
+		{"Invalid JSON", `{`, &stubVerifier{}, http.StatusBadRequest},
+		{"Invalid certificate", `{"cert": "abc"}`, &stubVerifier{err: verify.ErrInvalidCertificate}, http.StatusBadRequest},
+		{"No scopes", `{"cert": "abc"}`, &stubVerifier{err: verify.ErrNoScopes}, http.StatusBadRequest},
+		{"TPP lookup", `{"cert": "abc"}`, &stubVerifier{err: verify.ErrTppLookup}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/verify", verifyHandler(tt.verifier))
+			req, err := http.NewRequest(http.MethodPost, "/verify", bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("Couldn't create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status code %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestVerifyHandler_Format(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		want        int
+		contentType string
+	}{
+		{"Default", "", "", http.StatusOK, "application/json; charset=utf-8"},
+		{"Accept XML", "", "application/xml", http.StatusOK, "application/xml; charset=utf-8"},
+		{"Accept text XML", "", "text/xml", http.StatusOK, "application/xml; charset=utf-8"},
+		{"Accept any", "", "*/*", http.StatusOK, "application/json; charset=utf-8"},
+		{"Format XML", "?format=xml", "application/json", http.StatusOK, "application/xml; charset=utf-8"},
+		{"Format JSON", "?format=json", "application/xml", http.StatusOK, "application/json; charset=utf-8"},
+		{"Unknown format", "?format=pdf", "", http.StatusBadRequest, "application/json; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/verify", verifyHandler(&stubVerifier{res: &verify.VerifyResponse{Valid: true}}))
+			req, err := http.NewRequest(http.MethodPost, "/verify"+tt.query, bytes.NewBufferString(`{"cert": "abc"}`))
+			if err != nil {
+				t.Fatalf("Couldn't create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status code %d, got %d", tt.want, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, got)
+			}
+		})
+	}
+}
+
+func TestVerifyHandler_Explain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query string
+		body  string
+		want  bool
+	}{
+		{"", `{"cert": "abc"}`, false},
+		{"?explain=true", `{"cert": "abc"}`, true},
+		{"?explain=false", `{"cert": "abc"}`, false},
+		{"", `{"cert": "abc", "explain": true}`, true},
+	}
+	for _, tt := range tests {
+		verifier := &stubVerifier{res: &verify.VerifyResponse{Valid: true}}
+		r := gin.New()
+		r.POST("/verify", verifyHandler(verifier))
+		req, err := http.NewRequest(http.MethodPost, "/verify"+tt.query, bytes.NewBufferString(tt.body))
+		if err != nil {
+			t.Fatalf("Couldn't create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if verifier.req.Explain != tt.want {
+			t.Errorf("Expected explain %v for %q %s, got %v", tt.want, tt.query, tt.body, verifier.req.Explain)
+		}
+	}
+}
+
+type stubQueue struct {
+	req jobs.Request
+	err error
+}
+
+func (s *stubQueue) Submit(ctx context.Context, req jobs.Request) (*models.Job, error) {
+	s.req = req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.Job{Id: "job-1", Status: models.JobQueued}, nil
+}
+
+func (s *stubQueue) Get(ctx context.Context, id string) (*models.Job, error) {
+	if id != "job-1" {
+		return nil, db.ErrJobNotFound
+	}
+	return &models.Job{Id: id, Status: models.JobSucceeded}, nil
+}
+
+func TestJobHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		queue    *stubQueue
+		want     int
+		location string
+	}{
+		{"Submit", http.MethodPost, "/jobs", `{"cert": "abc", "callback_url": "https://example.com/cb"}`, &stubQueue{}, http.StatusAccepted, "/jobs/job-1"},
+		{"Submit invalid JSON", http.MethodPost, "/jobs", `{`, &stubQueue{}, http.StatusBadRequest, ""},
+		{"Callbacks disabled", http.MethodPost, "/jobs", `{"cert": "abc", "callback_url": "https://example.com/cb"}`, &stubQueue{err: jobs.ErrCallbacksDisabled}, http.StatusBadRequest, ""},
+		{"Invalid callback", http.MethodPost, "/jobs", `{"cert": "abc", "callback_url": "cb"}`, &stubQueue{err: jobs.ErrInvalidCallback}, http.StatusBadRequest, ""},
+		{"Get", http.MethodGet, "/jobs/job-1", "", &stubQueue{}, http.StatusOK, ""},
+		{"Get unknown", http.MethodGet, "/jobs/job-2", "", &stubQueue{}, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/jobs", submitJobHandler(tt.queue))
+			r.GET("/jobs/:id", jobHandler(tt.queue))
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("Couldn't create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status code %d, got %d", tt.want, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Errorf("Expected location %q, got %q", tt.location, got)
+			}
+		})
+	}
+	q := &stubQueue{}
+	r := gin.New()
+	r.POST("/jobs", submitJobHandler(q))
+	req, _ := http.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(`{"cert": "abc", "no_cache": true, "callback_url": "https://example.com/cb"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if q.req.Cert != "abc" || !q.req.NoCache || q.req.CallbackUrl != "https://example.com/cb" {
+		t.Errorf("Expected the verification request and callback URL, got %+v", q.req)
+	}
+}
+
+type stubTppLookup struct{}
+
+func (stubTppLookup) LookupTpp(ctx context.Context, id string) (*verify.TppRecord, error) {
+	switch id {
+	case "PSDFI-FINFSA-01110279":
+		return &verify.TppRecord{TPP: &models.TPP{OBID: id}, AuthorizationStatus: models.AuthorizationStatusAuthorized}, nil
+	case "44059":
+		return nil, fmt.Errorf("%w: PSDSE-FINA-44059, PSDDK-DFSA-44059", verify.ErrAmbiguousTppId)
+	case "broken":
+		return nil, verify.ErrTppLookup
+	}
+	return nil, db.ErrTppNotFound
+}
+
+func (stubTppLookup) LookupTpps(ctx context.Context, req verify.TppLookupRequest) (*verify.TppLookupResponse, error) {
+	if len(req.Ids) == 0 {
+		return nil, verify.ErrNoTppIds
+	}
+	return &verify.TppLookupResponse{}, nil
+}
+
+func (stubTppLookup) SearchTpps(ctx context.Context, req verify.TppSearchRequest) (*verify.TppSearchResponse, error) {
+	if req.Type != "" {
+		return nil, verify.ErrInvalidTppSearch
+	}
+	return &verify.TppSearchResponse{Tpps: []*verify.TppRecord{}}, nil
+}
+
+func (stubTppLookup) TppChanges(ctx context.Context, req verify.TppChangesRequest) (*verify.TppChangesResponse, error) {
+	if req.Cursor == "invalid" {
+		return nil, verify.ErrInvalidTppSearch
+	}
+	return &verify.TppChangesResponse{Changes: []models.TppChange{}}, nil
+}
+
+func TestTppHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"Get", http.MethodGet, "/tpp/PSDFI-FINFSA-01110279", "", http.StatusOK},
+		{"Get unknown", http.MethodGet, "/tpp/PSDFI-FINFSA-99999999", "", http.StatusNotFound},
+		{"Get ambiguous", http.MethodGet, "/tpp/44059", "", http.StatusConflict},
+		{"Get repository error", http.MethodGet, "/tpp/broken", "", http.StatusInternalServerError},
+		{"Usage is not a TPP", http.MethodGet, "/tpp/usage", "", http.StatusNoContent},
+		{"Lookup", http.MethodPost, "/tpp/lookup", `{"ids": ["44059"]}`, http.StatusOK},
+		{"Lookup without identifiers", http.MethodPost, "/tpp/lookup", `{"ids": []}`, http.StatusBadRequest},
+		{"Lookup invalid JSON", http.MethodPost, "/tpp/lookup", `{`, http.StatusBadRequest},
+		{"Search", http.MethodGet, "/tpp?country=FI&limit=10", "", http.StatusOK},
+		{"Search invalid filter", http.MethodGet, "/tpp?type=BANK", "", http.StatusBadRequest},
+		{"Search invalid limit", http.MethodGet, "/tpp?limit=ten", "", http.StatusBadRequest},
+		{"Changes", http.MethodGet, "/tpp/changes?since=2026-10-01T00:00:00Z", "", http.StatusOK},
+		{"Changes invalid since", http.MethodGet, "/tpp/changes?since=yesterday", "", http.StatusBadRequest},
+		{"Changes invalid cursor", http.MethodGet, "/tpp/changes?cursor=invalid", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/tpp", tppSearchHandler(stubTppLookup{}))
+			r.POST("/tpp/lookup", tppLookupHandler(stubTppLookup{}))
+			r.GET("/tpp/usage", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+			r.GET("/tpp/changes", tppChangesHandler(stubTppLookup{}))
+			r.GET("/tpp/:id", tppHandler(stubTppLookup{}))
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("Couldn't create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status code %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+type stubTenantRepo struct{}
+
+func (stubTenantRepo) GetTenantByApiKey(ctx context.Context, keyHash string) (*models.Tenant, error) {
+	if keyHash == tenant.HashApiKey("retail-key") {
+		return &models.Tenant{Id: "retail", AspspCountries: []string{"FI"}, IsActive: true}, nil
+	}
+	return nil, db.ErrTenantNotFound
+}
+
+func (stubTenantRepo) GetTenant(ctx context.Context, id string) (*models.Tenant, error) {
+	return nil, db.ErrTenantNotFound
+}
+
+func (stubTenantRepo) GetPolicy(ctx context.Context, name string) (*policy.Policy, error) {
+	return nil, db.ErrPolicyNotFound
+}
+
+func (stubTenantRepo) AddUsage(ctx context.Context, tenantId string, day string, requests int64) error {
+	return nil
+}
+
+func (stubTenantRepo) GetUsage(ctx context.Context, tenantId string, since string) ([]models.TenantUsage, error) {
+	return nil, nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		legacy  bool
+		want    int
+		tenant  string
+	}{
+		{"API key", map[string]string{"X-API-Key": "retail-key"}, true, http.StatusOK, "retail"},
+		{"Invalid API key", map[string]string{"X-API-Key": "other-key"}, true, http.StatusForbidden, ""},
+		{"Shared header", map[string]string{"X-Auth": "secret"}, true, http.StatusOK, ""},
+		{"Shared header disabled", map[string]string{"X-Auth": "secret"}, false, http.StatusForbidden, ""},
+		{"No credentials", nil, true, http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headerName, headerValue := "", ""
+			if tt.legacy {
+				headerName, headerValue = "X-Auth", "secret"
+			}
+			var got *verify.Tenant
+			r := gin.New()
+			r.Use(authMiddleware(tenant.NewAuthenticator(stubTenantRepo{}), "X-API-Key", headerName, headerValue, denyJSON))
+			r.GET("/", func(c *gin.Context) {
+				got = verify.TenantFromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil {
+				t.Fatalf("Couldn't create request: %v", err)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status code %d, got %d", tt.want, w.Code)
+			}
+			if tt.tenant != "" && (got == nil || got.Id != tt.tenant) {
+				t.Errorf("Expected tenant %s, got %+v", tt.tenant, got)
+			}
+			if tt.tenant == "" && got != nil {
+				t.Errorf("Expected no tenant, got %+v", got)
+			}
+		})
+	}
+}
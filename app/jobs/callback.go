@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+)
+
+var errInternalAddress = errors.New("callback address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, which IsPrivate does not include.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewCallbackClient returns the client callbacks are posted with. It does not connect to
+// loopback, private, link-local or other non-public addresses, as resolved when dialling,
+// and does not follow redirects, so that callback URLs cannot reach the internal network.
+func NewCallbackClient() *http.Client {
+	dialer := &net.Dialer{Timeout: callbackTimeout, Control: dialPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialled in place of the callback host
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// dialPublic is a net.Dialer Control function that refuses non-public addresses.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(ip) {
+		return fmt.Errorf("%w: %s", errInternalAddress, ip)
+	}
+	return nil
+}
+
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,382 @@
+// Package jobs runs verifications asynchronously, for clients whose gateway times out
+// on slow OCSP responders and AIA endpoints. Jobs are stored in the repository, so that
+// they survive restarts and are worked on by any instance.
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/clock"
+	"github.com/botsman/tppVerifier/app/db"
+	vhttp "github.com/botsman/tppVerifier/app/http"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/receipt"
+	"github.com/botsman/tppVerifier/app/tenant"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+var (
+	ErrCallbacksDisabled = errors.New("callbacks require a receipt key")
+	ErrInvalidCallback   = errors.New("invalid callback URL")
+)
+
+const (
+	// CallbackType is the typ header of callback signatures, see receipt.Signer.VerifyDetached.
+	CallbackType = "tpp-verification-callback+jws"
+	// SignatureHeader carries the detached JWS of the callback body.
+	SignatureHeader = "X-Signature"
+)
+
+const (
+	// lease bounds how long a job stays with an instance that stopped working on it.
+	// It must exceed verifyTimeout and callbackTimeout together.
+	lease           = 5 * time.Minute
+	verifyTimeout   = 2 * time.Minute
+	callbackTimeout = 10 * time.Second
+	// retryDelay is multiplied by the number of attempts made so far.
+	retryDelay          = time.Minute
+	maxAttempts         = 3
+	maxCallbackAttempts = 5
+	defaultRetention    = 24 * time.Hour
+	pollInterval        = time.Second
+)
+
+// Request is a verification request with an optional callback URL, to which
+// the finished job is posted.
+type Request struct {
+	verify.VerifyRequest
+	CallbackUrl string `json:"callback_url,omitempty"`
+}
+
+// JobQueue submits jobs and returns their state.
+type JobQueue interface {
+	Submit(ctx context.Context, req Request) (*models.Job, error)
+	Get(ctx context.Context, id string) (*models.Job, error)
+}
+
+var _ JobQueue = (*Queue)(nil)
+
+// TenantResolver returns a tenant by its ID, see tenant.Authenticator.
+type TenantResolver interface {
+	Tenant(ctx context.Context, id string) (*verify.Tenant, error)
+}
+
+type Queue struct {
+	repo       db.JobRepository
+	verifier   verify.Verifier
+	httpClient vhttp.Client
+	clock      clock.Clock
+	tenants    TenantResolver
+	signer     *receipt.Signer
+	retention  time.Duration
+	// callbackHosts are the hosts callbacks may be posted to, any if nil.
+	callbackHosts []string
+}
+
+// NewQueue creates a queue that posts callbacks with httpClient, see NewCallbackClient.
+func NewQueue(repo db.JobRepository, verifier verify.Verifier, httpClient vhttp.Client) *Queue {
+	return &Queue{
+		repo:       repo,
+		verifier:   verifier,
+		httpClient: httpClient,
+		clock:      clock.System(),
+		retention:  defaultRetention,
+	}
+}
+
+// SetTenants enables jobs of tenants, which are verified with the configuration
+// the tenant has when the job runs.
+func (q *Queue) SetTenants(tenants TenantResolver) {
+	q.tenants = tenants
+}
+
+// SetCallbackSigner enables callbacks. Callbacks are signed with the receipt key,
+// so that receivers check them with the keys published at /keys.
+func (q *Queue) SetCallbackSigner(signer *receipt.Signer) {
+	q.signer = signer
+}
+
+// SetCallbackHosts restricts callback URLs to the given hosts.
+func (q *Queue) SetCallbackHosts(hosts []string) {
+	q.callbackHosts = hosts
+}
+
+// SetRetention sets how long finished jobs are kept.
+func (q *Queue) SetRetention(retention time.Duration) {
+	q.retention = retention
+}
+
+// SetClock sets the clock used for leases, retries and expiry.
+func (q *Queue) SetClock(c clock.Clock) {
+	q.clock = c
+}
+
+// Submit stores a job for the request, to be run by any instance.
+func (q *Queue) Submit(ctx context.Context, req Request) (*models.Job, error) {
+	if req.CallbackUrl != "" {
+		if q.signer == nil {
+			return nil, ErrCallbacksDisabled
+		}
+		u, err := url.Parse(req.CallbackUrl)
+		if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCallback, req.CallbackUrl)
+		}
+		if q.callbackHosts != nil && !slices.ContainsFunc(q.callbackHosts, func(host string) bool {
+			return strings.EqualFold(host, u.Hostname())
+		}) {
+			return nil, fmt.Errorf("%w: host %q is not allowed", ErrInvalidCallback, u.Hostname())
+		}
+	}
+	request, err := json.Marshal(req.VerifyRequest)
+	if err != nil {
+		return nil, err
+	}
+	now := q.clock.Now()
+	job := &models.Job{
+		Id:          verify.NewId(),
+		Status:      models.JobQueued,
+		Request:     request,
+		CallbackUrl: req.CallbackUrl,
+		LeasedUntil: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if t := verify.TenantFromContext(ctx); t != nil {
+		job.TenantId = t.Id
+	}
+	if err := q.repo.AddJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Get returns a job. Tenants only see their own jobs.
+func (q *Queue) Get(ctx context.Context, id string) (*models.Job, error) {
+	job, err := q.repo.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tenantId := ""
+	if t := verify.TenantFromContext(ctx); t != nil {
+		tenantId = t.Id
+	}
+	if tenantId != "" && job.TenantId != tenantId {
+		return nil, db.ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Purge removes expired jobs.
+func (q *Queue) Purge(ctx context.Context) (int64, error) {
+	return q.repo.DeleteExpiredJobs(ctx, q.clock.Now())
+}
+
+// Run works on jobs with the given number of workers until the context is done.
+func (q *Queue) Run(ctx context.Context, workers int) {
+	done := make(chan struct{})
+	for range workers {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for ctx.Err() == nil {
+				if q.runOne(ctx) {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+				case <-time.After(pollInterval):
+				}
+			}
+		}()
+	}
+	for range workers {
+		<-done
+	}
+}
+
+// runOne claims a job and works on it. It returns false if there was no job to claim.
+func (q *Queue) runOne(ctx context.Context) (claimed bool) {
+	now := q.clock.Now()
+	job, err := q.repo.ClaimJob(ctx, now, now.Add(lease))
+	if errors.Is(err, db.ErrJobNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to claim a job: %v", err)
+		return false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic in job %s: %v\n%s", job.Id, r, debug.Stack())
+			q.abort(ctx, job)
+			claimed = true
+		}
+	}()
+	if job.Status == models.JobQueued || job.Status == models.JobRunning {
+		job.Status = models.JobRunning
+		job.Attempts++
+		job.UpdatedAt = now
+		if err := q.repo.UpdateJob(ctx, job); err != nil {
+			// The lease expires and the job is claimed again
+			log.Printf("Failed to start job %s: %v", job.Id, err)
+			return true
+		}
+		if !q.execute(ctx, job) {
+			q.save(ctx, job)
+			return true
+		}
+	}
+	if job.CallbackStatus == models.CallbackPending {
+		q.deliver(ctx, job)
+	}
+	q.save(ctx, job)
+	return true
+}
+
+// abort fails a job whose verification or callback panicked, instead of leaving it leased
+// to be claimed and to panic again. A failed job is still posted to its callback URL.
+func (q *Queue) abort(ctx context.Context, job *models.Job) {
+	now := q.clock.Now()
+	if job.Status == models.JobQueued || job.Status == models.JobRunning {
+		job.Status, job.Error = models.JobFailed, "Failed to process the job."
+		expires := now.Add(q.retention)
+		job.ExpiresAt = &expires
+		if job.CallbackUrl != "" {
+			job.CallbackStatus = models.CallbackPending
+		}
+	} else if job.CallbackStatus == models.CallbackPending {
+		job.CallbackStatus = models.CallbackFailed
+	}
+	job.LeasedUntil = now
+	q.save(ctx, job)
+}
+
+// execute verifies the request of the job. It returns false if the job is to be retried.
+func (q *Queue) execute(ctx context.Context, job *models.Job) bool {
+	res, err := q.verify(ctx, job)
+	now := q.clock.Now()
+	switch {
+	case err == nil:
+		if job.Result, err = json.Marshal(res); err != nil {
+			job.Status, job.Error = models.JobFailed, "Failed to encode the result."
+		} else {
+			job.Status = models.JobSucceeded
+		}
+	case permanent(err):
+		job.Status, job.Error = models.JobFailed, err.Error()
+	case job.Attempts < maxAttempts:
+		log.Printf("Job %s failed, retrying: %v", job.Id, err)
+		job.Status = models.JobQueued
+		job.LeasedUntil = now.Add(time.Duration(job.Attempts) * retryDelay)
+		return false
+	default:
+		log.Printf("Job %s failed: %v", job.Id, err)
+		job.Status = models.JobFailed
+		job.Error = fmt.Sprintf("Verification failed after %d attempts.", job.Attempts)
+	}
+	expires := now.Add(q.retention)
+	job.ExpiresAt = &expires
+	if job.CallbackUrl != "" {
+		job.CallbackStatus = models.CallbackPending
+	}
+	return true
+}
+
+func (q *Queue) verify(ctx context.Context, job *models.Job) (*verify.VerifyResponse, error) {
+	var req verify.VerifyRequest
+	if err := json.Unmarshal(job.Request, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", verify.ErrInvalidCertificate, err)
+	}
+	if job.TenantId != "" {
+		if q.tenants == nil {
+			return nil, fmt.Errorf("%w: tenants are not enabled", tenant.ErrUnauthorized)
+		}
+		t, err := q.tenants.Tenant(ctx, job.TenantId)
+		if err != nil {
+			return nil, err
+		}
+		ctx = verify.ContextWithTenant(ctx, t)
+	}
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	return q.verifier.Verify(ctx, req)
+}
+
+// permanent reports whether retrying the job cannot change its outcome.
+func permanent(err error) bool {
+	for _, target := range []error{
+		verify.ErrInvalidCertificate, verify.ErrNoCertificate, verify.ErrCertificateParse,
+		verify.ErrNoScopes, verify.ErrReceiptsDisabled, tenant.ErrUnauthorized,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver posts the finished job to its callback URL. Callbacks are delivered at least once,
+// receivers should ignore repeated callbacks of the same job.
+func (q *Queue) deliver(ctx context.Context, job *models.Job) {
+	job.CallbackAttempts++
+	err := q.post(ctx, job)
+	switch {
+	case err == nil:
+		job.CallbackStatus = models.CallbackDelivered
+	case job.CallbackAttempts < maxCallbackAttempts:
+		log.Printf("Failed to deliver the callback of job %s, retrying: %v", job.Id, err)
+		job.LeasedUntil = q.clock.Now().Add(time.Duration(job.CallbackAttempts) * retryDelay)
+	default:
+		log.Printf("Failed to deliver the callback of job %s: %v", job.Id, err)
+		job.CallbackStatus = models.CallbackFailed
+	}
+}
+
+func (q *Queue) post(ctx context.Context, job *models.Job) error {
+	// The body is the job as returned by the API, without the state of the callback itself
+	sent := *job
+	sent.CallbackStatus, sent.CallbackAttempts = "", 0
+	body, err := json.Marshal(&sent)
+	if err != nil {
+		return err
+	}
+	signature, err := q.signer.SignDetached(CallbackType, body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, callbackTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.CallbackUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// save stores the job. A job with work left keeps its lease, or the time of its next attempt.
+func (q *Queue) save(ctx context.Context, job *models.Job) {
+	job.UpdatedAt = q.clock.Now()
+	if err := q.repo.UpdateJob(ctx, job); err != nil {
+		log.Printf("Failed to store job %s: %v", job.Id, err)
+	}
+}
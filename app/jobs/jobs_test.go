@@ -0,0 +1,395 @@
+package jobs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/receipt"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+type memoryRepo struct {
+	jobs map[string]models.Job
+}
+
+func (r *memoryRepo) AddJob(ctx context.Context, job *models.Job) error {
+	r.jobs[job.Id] = *job
+	return nil
+}
+
+func (r *memoryRepo) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, db.ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (r *memoryRepo) ClaimJob(ctx context.Context, now time.Time, until time.Time) (*models.Job, error) {
+	var claimable []models.Job
+	for _, job := range r.jobs {
+		open := job.Status == models.JobQueued || job.Status == models.JobRunning || job.CallbackStatus == models.CallbackPending
+		if open && !job.LeasedUntil.After(now) {
+			claimable = append(claimable, job)
+		}
+	}
+	if len(claimable) == 0 {
+		return nil, db.ErrJobNotFound
+	}
+	slices.SortFunc(claimable, func(a, b models.Job) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	job := claimable[0]
+	job.LeasedUntil = until
+	r.jobs[job.Id] = job
+	return &job, nil
+}
+
+func (r *memoryRepo) UpdateJob(ctx context.Context, job *models.Job) error {
+	if _, ok := r.jobs[job.Id]; !ok {
+		return db.ErrJobNotFound
+	}
+	r.jobs[job.Id] = *job
+	return nil
+}
+
+func (r *memoryRepo) DeleteExpiredJobs(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	for id, job := range r.jobs {
+		if job.ExpiresAt != nil && !job.ExpiresAt.After(before) {
+			delete(r.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// stubVerifier fails with the queued errors before succeeding.
+type stubVerifier struct {
+	errs   []error
+	calls  int
+	tenant string
+}
+
+func (v *stubVerifier) Verify(ctx context.Context, req verify.VerifyRequest) (*verify.VerifyResponse, error) {
+	v.calls++
+	if t := verify.TenantFromContext(ctx); t != nil {
+		v.tenant = t.Id
+	}
+	if len(v.errs) > 0 {
+		err := v.errs[0]
+		v.errs = v.errs[1:]
+		return nil, err
+	}
+	return &verify.VerifyResponse{Valid: true, Policy: "default"}, nil
+}
+
+type panicVerifier struct{}
+
+func (panicVerifier) Verify(ctx context.Context, req verify.VerifyRequest) (*verify.VerifyResponse, error) {
+	panic("nil certificate")
+}
+
+type panicClient struct{}
+
+func (panicClient) Do(req *http.Request) (*http.Response, error) {
+	panic("nil transport")
+}
+
+type stubTenants struct{}
+
+func (stubTenants) Tenant(ctx context.Context, id string) (*verify.Tenant, error) {
+	return &verify.Tenant{Id: id}, nil
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// callbackServer records the callbacks it receives and answers with the given status.
+type callbackServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	status     int
+	bodies     [][]byte
+	signatures []string
+}
+
+func newCallbackServer(t *testing.T) *callbackServer {
+	s := &callbackServer{status: http.StatusNoContent}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bodies = append(s.bodies, body)
+		s.signatures = append(s.signatures, r.Header.Get(SignatureHeader))
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestQueue(t *testing.T, v verify.Verifier) (*Queue, *memoryRepo, *testClock, *receipt.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := receipt.NewSigner(key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	repo := &memoryRepo{jobs: map[string]models.Job{}}
+	c := &testClock{now: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+	q := NewQueue(repo, v, http.DefaultClient)
+	q.SetClock(c)
+	q.SetCallbackSigner(signer)
+	q.SetTenants(stubTenants{})
+	return q, repo, c, signer
+}
+
+func TestQueue(t *testing.T) {
+	v := &stubVerifier{}
+	q, _, _, signer := newTestQueue(t, v)
+	server := newCallbackServer(t)
+	q.httpClient = server.Client()
+	ctx := verify.ContextWithTenant(context.Background(), &verify.Tenant{Id: "retail"})
+
+	job, err := q.Submit(ctx, Request{VerifyRequest: verify.VerifyRequest{Cert: "cert"}, CallbackUrl: server.URL})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if job.Status != models.JobQueued || job.TenantId != "retail" {
+		t.Errorf("Expected a queued job of retail, got %+v", job)
+	}
+	if !q.runOne(context.Background()) {
+		t.Fatal("Expected a job to be claimed")
+	}
+	if q.runOne(context.Background()) {
+		t.Error("Expected no job left")
+	}
+	if v.calls != 1 || v.tenant != "retail" {
+		t.Errorf("Expected a single verification of retail, got %d of %q", v.calls, v.tenant)
+	}
+
+	got, err := q.Get(ctx, job.Id)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.Status != models.JobSucceeded || got.CallbackStatus != models.CallbackDelivered || got.ExpiresAt == nil {
+		t.Errorf("Expected a succeeded job with a delivered callback, got %+v", got)
+	}
+	var res verify.VerifyResponse
+	if err := json.Unmarshal(got.Result, &res); err != nil || !res.Valid {
+		t.Errorf("Expected a valid result, got %s", got.Result)
+	}
+	other := verify.ContextWithTenant(context.Background(), &verify.Tenant{Id: "corporate"})
+	if _, err := q.Get(other, job.Id); !errors.Is(err, db.ErrJobNotFound) {
+		t.Errorf("Expected the job to be hidden from other tenants, got %v", err)
+	}
+
+	if len(server.bodies) != 1 {
+		t.Fatalf("Expected a single callback, got %d", len(server.bodies))
+	}
+	if _, err := signer.VerifyDetached(server.signatures[0], CallbackType, server.bodies[0]); err != nil {
+		t.Errorf("Expected a valid callback signature, got %v", err)
+	}
+	var sent models.Job
+	if err := json.Unmarshal(server.bodies[0], &sent); err != nil {
+		t.Fatalf("Expected a JSON job, got %v", err)
+	}
+	if sent.Id != job.Id || sent.Status != models.JobSucceeded || sent.CallbackStatus != "" {
+		t.Errorf("Expected the succeeded job without its callback state, got %+v", sent)
+	}
+}
+
+func TestQueue_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		status   models.JobStatus
+		attempts int
+	}{
+		{"Transient error", []error{verify.ErrTppLookup}, models.JobSucceeded, 2},
+		{"Permanent error", []error{verify.ErrCertificateParse}, models.JobFailed, 1},
+		{"Attempts exhausted", []error{verify.ErrTppLookup, verify.ErrTppLookup, verify.ErrTppLookup}, models.JobFailed, maxAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, repo, c, _ := newTestQueue(t, &stubVerifier{errs: tt.errs})
+			job, err := q.Submit(context.Background(), Request{VerifyRequest: verify.VerifyRequest{Cert: "cert"}})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			for range 10 {
+				if !q.runOne(context.Background()) {
+					// Wait for the next attempt
+					c.now = c.now.Add(maxAttempts * retryDelay)
+				}
+			}
+			got := repo.jobs[job.Id]
+			if got.Status != tt.status || got.Attempts != tt.attempts {
+				t.Errorf("Expected %s after %d attempts, got %s after %d", tt.status, tt.attempts, got.Status, got.Attempts)
+			}
+			if tt.status == models.JobFailed && got.Error == "" {
+				t.Error("Expected an error message")
+			}
+		})
+	}
+}
+
+func TestQueue_Panic(t *testing.T) {
+	q, repo, c, _ := newTestQueue(t, panicVerifier{})
+	q.httpClient = panicClient{}
+	job, err := q.Submit(context.Background(), Request{VerifyRequest: verify.VerifyRequest{Cert: "cert"}, CallbackUrl: "https://aspsp.example.com/jobs"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !q.runOne(context.Background()) {
+		t.Fatal("Expected the job to be claimed")
+	}
+	got := repo.jobs[job.Id]
+	if got.Status != models.JobFailed || got.Error == "" || got.ExpiresAt == nil || got.LeasedUntil.After(c.now) {
+		t.Fatalf("Expected a failed job with its lease released, got %+v", got)
+	}
+
+	// The callback of the failed job panics as well
+	if !q.runOne(context.Background()) {
+		t.Fatal("Expected the callback to be attempted")
+	}
+	if got := repo.jobs[job.Id]; got.CallbackStatus != models.CallbackFailed || got.CallbackAttempts != 1 {
+		t.Errorf("Expected a failed callback after 1 attempt, got %s after %d", got.CallbackStatus, got.CallbackAttempts)
+	}
+	if q.runOne(context.Background()) {
+		t.Error("Expected no job to be claimed again")
+	}
+}
+
+func TestQueue_CallbackRetry(t *testing.T) {
+	v := &stubVerifier{}
+	q, repo, c, _ := newTestQueue(t, v)
+	server := newCallbackServer(t)
+	q.httpClient = server.Client()
+	server.status = http.StatusServiceUnavailable
+	job, err := q.Submit(context.Background(), Request{VerifyRequest: verify.VerifyRequest{Cert: "cert"}, CallbackUrl: server.URL})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	q.runOne(context.Background())
+	if got := repo.jobs[job.Id]; got.CallbackStatus != models.CallbackPending || got.CallbackAttempts != 1 {
+		t.Fatalf("Expected a pending callback after 1 attempt, got %s after %d", got.CallbackStatus, got.CallbackAttempts)
+	}
+	if q.runOne(context.Background()) {
+		t.Error("Expected the callback to be retried later")
+	}
+
+	server.status = http.StatusOK
+	c.now = c.now.Add(retryDelay)
+	if !q.runOne(context.Background()) {
+		t.Fatal("Expected the callback to be retried")
+	}
+	got := repo.jobs[job.Id]
+	if got.CallbackStatus != models.CallbackDelivered || got.CallbackAttempts != 2 {
+		t.Errorf("Expected a delivered callback after 2 attempts, got %s after %d", got.CallbackStatus, got.CallbackAttempts)
+	}
+	if v.calls != 1 {
+		t.Errorf("Expected the verification not to be repeated, got %d calls", v.calls)
+	}
+}
+
+func TestSubmit_Callback(t *testing.T) {
+	q, _, _, _ := newTestQueue(t, &stubVerifier{})
+	for _, callbackUrl := range []string{"ftp://example.com/jobs", "/jobs", "https://", "http://example.com/jobs"} {
+		_, err := q.Submit(context.Background(), Request{CallbackUrl: callbackUrl})
+		if !errors.Is(err, ErrInvalidCallback) {
+			t.Errorf("Expected %q to be rejected, got %v", callbackUrl, err)
+		}
+	}
+
+	q.SetCallbackHosts([]string{"aspsp.example.com"})
+	if _, err := q.Submit(context.Background(), Request{CallbackUrl: "https://internal.example.com/jobs"}); !errors.Is(err, ErrInvalidCallback) {
+		t.Errorf("Expected a host that is not allowed to be rejected, got %v", err)
+	}
+	if _, err := q.Submit(context.Background(), Request{CallbackUrl: "https://ASPSP.example.com:8443/jobs"}); err != nil {
+		t.Errorf("Expected an allowed host to be accepted, got %v", err)
+	}
+
+	q.SetCallbackSigner(nil)
+	_, err := q.Submit(context.Background(), Request{CallbackUrl: "https://example.com/jobs"})
+	if !errors.Is(err, ErrCallbacksDisabled) {
+		t.Errorf("Expected error %v, got %v", ErrCallbacksDisabled, err)
+	}
+}
+
+func TestCallbackClient(t *testing.T) {
+	server := newCallbackServer(t)
+	resp, err := NewCallbackClient().Post(server.URL, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, errInternalAddress) {
+		t.Errorf("Expected the loopback address to be refused, got %v", err)
+	}
+	if len(server.bodies) != 0 {
+		t.Errorf("Expected no callback, got %d", len(server.bodies))
+	}
+
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.ip)); got != tt.public {
+			t.Errorf("Expected %s public %v, got %v", tt.ip, tt.public, got)
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	q, repo, c, _ := newTestQueue(t, &stubVerifier{})
+	job, err := q.Submit(context.Background(), Request{VerifyRequest: verify.VerifyRequest{Cert: "cert"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	q.runOne(context.Background())
+	if n, _ := q.Purge(context.Background()); n != 0 {
+		t.Errorf("Expected no expired jobs, got %d", n)
+	}
+	c.now = c.now.Add(defaultRetention)
+	if n, _ := q.Purge(context.Background()); n != 1 {
+		t.Errorf("Expected 1 expired job, got %d", n)
+	}
+	if _, ok := repo.jobs[job.Id]; ok {
+		t.Error("Expected the job to be removed")
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -19,20 +20,68 @@ const (
 	PISP Service = "PIS"
 )
 
+// TPP is a registry record. Id is the EBA entity code, OBID the organization identifier
+// of the certificates, which contains the national reference code.
 type TPP struct {
-	NameLatin    string               `bson:"name_latin"`
-	NameNative   string               `bson:"name_native"`
-	Id           string               `bson:"id"`
-	OBID         string               `bson:"ob_id"`
-	Authority    string               `bson:"authority"`
-	Country      string               `bson:"country"`
-	Services     map[string][]Service `bson:"services"`
-	AuthorizedAt *time.Time           `bson:"authorized_at"`
-	WithdrawnAt  *time.Time           `bson:"withdrawn_at"`
-	Type         string               `bson:"type"`
-	CreatedAt    time.Time            `bson:"created_at"`
-	UpdatedAt    time.Time            `bson:"updated_at"`
-	Registry     string               `bson:"registry"`
+	NameLatin  string `bson:"name_latin" json:"name_latin"`
+	NameNative string `bson:"name_native" json:"name_native"`
+	Id         string `bson:"id" json:"id"`
+	OBID       string `bson:"ob_id" json:"ob_id"`
+	Authority  string `bson:"authority" json:"authority"`
+	Country    string `bson:"country" json:"country"`
+	// Services maps the home and passported countries to the services the TPP may provide there.
+	Services     map[string][]Service `bson:"services" json:"services"`
+	AuthorizedAt *time.Time           `bson:"authorized_at" json:"authorized_at,omitempty"`
+	WithdrawnAt  *time.Time           `bson:"withdrawn_at" json:"withdrawn_at,omitempty"`
+	Type         string               `bson:"type" json:"type"`
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time            `bson:"updated_at" json:"updated_at"`
+	Registry     string               `bson:"registry" json:"registry"`
+}
+
+type AuthorizationStatus string
+
+const (
+	AuthorizationStatusAuthorized    AuthorizationStatus = "AUTHORIZED"
+	AuthorizationStatusWithdrawn     AuthorizationStatus = "WITHDRAWN"
+	AuthorizationStatusNotAuthorized AuthorizationStatus = "NOT_YET_AUTHORIZED"
+)
+
+// AuthorizationStatus returns the registry authorization status of the TPP at the given time.
+// Only the latest authorization and withdrawal dates are stored, so a withdrawal
+// followed by a re-authorization is treated as authorized from the re-authorization on.
+func (t *TPP) AuthorizationStatus(at time.Time) AuthorizationStatus {
+	if t.AuthorizedAt == nil || t.AuthorizedAt.IsZero() || at.Before(*t.AuthorizedAt) {
+		return AuthorizationStatusNotAuthorized
+	}
+	if t.WithdrawnAt != nil && !t.WithdrawnAt.IsZero() && !at.Before(*t.WithdrawnAt) && !t.WithdrawnAt.Before(*t.AuthorizedAt) {
+		return AuthorizationStatusWithdrawn
+	}
+	return AuthorizationStatusAuthorized
+}
+
+type TppChangeType string
+
+const (
+	TppAdded           TppChangeType = "added"
+	TppWithdrawn       TppChangeType = "withdrawn"
+	TppServicesChanged TppChangeType = "services_changed"
+	TppNameChanged     TppChangeType = "name_changed"
+	// TppRemoved is a TPP that is no longer in the register.
+	TppRemoved TppChangeType = "removed"
+)
+
+// TppChange is a change of a TPP found by the registry import. Changes are numbered
+// in the order they were recorded, a single import may record several for a TPP.
+type TppChange struct {
+	Seq  int64         `bson:"seq" json:"seq"`
+	Type TppChangeType `bson:"type" json:"type"`
+	// Id is the EBA entity code of the TPP.
+	Id   string `bson:"id" json:"id"`
+	OBID string `bson:"ob_id" json:"ob_id"`
+	// TPP is the imported record, or the last stored one if the TPP was removed.
+	TPP        *TPP      `bson:"tpp" json:"tpp"`
+	RecordedAt time.Time `bson:"recorded_at" json:"recorded_at"`
 }
 
 type Register string
@@ -52,10 +101,15 @@ const (
 type ObRole string
 
 const (
+	PSP_AS ObRole = "PSP_AS"
 	PSP_PI ObRole = "PSP_PI"
 	PSP_AI ObRole = "PSP_AI"
+	PSP_IC ObRole = "PSP_IC"
 )
 
+// ObRoles are the PSD2 roles of ETSI TS 119 495.
+var ObRoles = []ObRole{PSP_AS, PSP_PI, PSP_AI, PSP_IC}
+
 type Scope string
 
 const (
@@ -65,21 +119,216 @@ const (
 )
 
 type CertificateResponse struct {
+	Sha256       string         `json:"sha256"`
 	Expired      bool           `json:"expired"`
 	Scopes       []Scope        `json:"scopes"`
 	SerialNumber string         `json:"serial_number"`
 	Issuer       map[string]any `json:"issuer"`
 	Subject      map[string]any `json:"subject"`
-	NotBefore    string         `json:"not_before"`
-	NotAfter     string         `json:"not_after"`
-	Usage        CertUsage      `json:"usage"`
+	// NotBefore and NotAfter are formatted with time.Time.String, as returned by the v1 API.
+	NotBefore string    `json:"not_before"`
+	NotAfter  string    `json:"not_after"`
+	Usage     CertUsage `json:"usage"`
+	// ValidFrom and ValidUntil are NotBefore and NotAfter as times, returned by the v2 API.
+	ValidFrom  time.Time `json:"-"`
+	ValidUntil time.Time `json:"-"`
 }
 
 type TppResponse struct {
-	Id         string               `json:"id"`
-	NameLatin  string               `json:"name_latin"`
-	NameNative string               `json:"name_native"`
-	Authority  string               `json:"authority"`
-	Services   map[string][]Service `json:"services"`
-	Country    string               `json:"country,omitempty"`
+	Id                  string               `json:"id"`
+	NameLatin           string               `json:"name_latin"`
+	NameNative          string               `json:"name_native"`
+	Authority           string               `json:"authority"`
+	Services            map[string][]Service `json:"services"`
+	Country             string               `json:"country,omitempty"`
+	AuthorizationStatus AuthorizationStatus  `json:"authorization_status"`
+	AuthorizedAt        *time.Time           `json:"authorized_at,omitempty"`
+	WithdrawnAt         *time.Time           `json:"withdrawn_at,omitempty"`
+	// UpdatedAt is the last update of the registry record.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type CheckName string
+
+const (
+	CheckParse      CheckName = "parse"
+	CheckUsage      CheckName = "usage"
+	CheckSandbox    CheckName = "sandbox"
+	CheckRegistry   CheckName = "registry"
+	CheckChain      CheckName = "chain"
+	CheckRevocation CheckName = "revocation"
+	CheckScope      CheckName = "scope"
+	// Consistency checks between the QcStatement NCA, the organization identifier and the registry
+	CheckNcaOrganization CheckName = "nca_organization_id"
+	CheckNcaAuthority    CheckName = "nca_authority"
+	CheckNcaCountry      CheckName = "nca_country"
+	// Comparison of the certificate subject with the registry record
+	CheckSubjectName    CheckName = "subject_name"
+	CheckSubjectCountry CheckName = "subject_country"
+	// Checks of the verification policy
+	CheckRole        CheckName = "role"
+	CheckCertAge     CheckName = "cert_age"
+	CheckQtspCountry CheckName = "qtsp_country"
+	// Consistency checks between the certificates of a QWAC and QSealC pair
+	CheckPairOrganization CheckName = "pair_organization"
+	CheckPairNca          CheckName = "pair_nca"
+	CheckPairTpp          CheckName = "pair_tpp"
+)
+
+type CheckStatus string
+
+const (
+	CheckStatusPass    CheckStatus = "pass"
+	CheckStatusFail    CheckStatus = "fail"
+	CheckStatusSkipped CheckStatus = "skipped"
+	CheckStatusError   CheckStatus = "error"
+	// CheckStatusWarning is a check that did not pass but does not invalidate the result.
+	CheckStatusWarning CheckStatus = "warning"
+)
+
+// CheckCode is a stable, machine-readable outcome of a single check.
+type CheckCode string
+
+const (
+	CodeSkipped                 CheckCode = "SKIPPED"
+	CodeCertParsed              CheckCode = "CERT_PARSED"
+	CodeUsageValid              CheckCode = "USAGE_VALID"
+	CodeUsageUnknown            CheckCode = "USAGE_UNKNOWN"
+	CodeUsageMismatch           CheckCode = "USAGE_MISMATCH"
+	CodeNotSandbox              CheckCode = "NOT_SANDBOX"
+	CodeSandbox                 CheckCode = "SANDBOX_CERTIFICATE"
+	CodeTppFound                CheckCode = "TPP_FOUND"
+	CodeTppNotFound             CheckCode = "TPP_NOT_FOUND"
+	CodeRegistryCheckDisabled   CheckCode = "REGISTRY_CHECK_DISABLED"
+	CodeTppWithdrawn            CheckCode = "TPP_WITHDRAWN"
+	CodeTppNotAuthorized        CheckCode = "TPP_NOT_YET_AUTHORIZED"
+	CodeChainTrusted            CheckCode = "CHAIN_TRUSTED"
+	CodeChainUntrusted          CheckCode = "CHAIN_UNTRUSTED"
+	CodeChainUnavailable        CheckCode = "CHAIN_UNAVAILABLE"
+	CodeNotRevoked              CheckCode = "NOT_REVOKED"
+	CodeRevoked                 CheckCode = "REVOKED"
+	CodeOcspMissing             CheckCode = "OCSP_URL_MISSING"
+	CodeOcspUnavailable         CheckCode = "OCSP_UNAVAILABLE"
+	CodeOcspInvalidResponse     CheckCode = "OCSP_INVALID_RESPONSE"
+	CodeScopesGranted           CheckCode = "SCOPES_GRANTED"
+	CodeNoScopes                CheckCode = "NO_SCOPES"
+	CodeNoAllowedCountry        CheckCode = "NO_ALLOWED_COUNTRY"
+	CodeOrganizationMatch       CheckCode = "ORGANIZATION_ID_MATCH"
+	CodeOrganizationMismatch    CheckCode = "ORGANIZATION_ID_MISMATCH"
+	CodeNcaMatch                CheckCode = "NCA_MATCH"
+	CodeNcaMismatch             CheckCode = "NCA_MISMATCH"
+	CodeTppMatch                CheckCode = "TPP_MATCH"
+	CodeTppMismatch             CheckCode = "TPP_MISMATCH"
+	CodeNcaInvalid              CheckCode = "NCA_INVALID"
+	CodeNcaOrganizationMatch    CheckCode = "NCA_ORGANIZATION_ID_MATCH"
+	CodeNcaOrganizationMismatch CheckCode = "NCA_ORGANIZATION_ID_MISMATCH"
+	CodeNcaAuthorityMatch       CheckCode = "NCA_AUTHORITY_MATCH"
+	CodeNcaAuthorityMismatch    CheckCode = "NCA_AUTHORITY_MISMATCH"
+	CodeNcaCountryMatch         CheckCode = "NCA_COUNTRY_MATCH"
+	CodeNcaCountryMismatch      CheckCode = "NCA_COUNTRY_MISMATCH"
+	CodeNameMatch               CheckCode = "NAME_MATCH"
+	CodeNameMismatch            CheckCode = "NAME_MISMATCH"
+	CodeSubjectCountryMatch     CheckCode = "SUBJECT_COUNTRY_MATCH"
+	CodeSubjectCountryMismatch  CheckCode = "SUBJECT_COUNTRY_MISMATCH"
+	CodeUsageNotAllowed         CheckCode = "USAGE_NOT_ALLOWED"
+	CodeRoleAllowed             CheckCode = "ROLE_ALLOWED"
+	CodeRoleNotAllowed          CheckCode = "ROLE_NOT_ALLOWED"
+	CodeCertAgeValid            CheckCode = "CERT_AGE_VALID"
+	CodeCertTooOld              CheckCode = "CERT_TOO_OLD"
+	CodeQtspCountryAllowed      CheckCode = "QTSP_COUNTRY_ALLOWED"
+	CodeQtspCountryNotAllowed   CheckCode = "QTSP_COUNTRY_NOT_ALLOWED"
+)
+
+type Check struct {
+	Name    CheckName   `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Code    CheckCode   `json:"code"`
+	Details string      `json:"details,omitempty"`
+	// Score is the similarity of the compared values between 0 and 1, for fuzzy checks.
+	Score *float64 `json:"score,omitempty"`
+}
+
+// Tenant is a consumer of the verifier, e.g. a business unit of the ASPSP,
+// with its own API keys and configuration.
+type Tenant struct {
+	Id   string `bson:"id" json:"id"`
+	Name string `bson:"name" json:"name"`
+	// ApiKeyHashes are the hex encoded SHA-256 hashes of the API keys, the keys themselves are not stored.
+	ApiKeyHashes []string `bson:"api_key_hashes" json:"-"`
+	// AspspCountries restricts the returned scopes, empty means the countries of the deployment.
+	AspspCountries []string `bson:"aspsp_countries" json:"aspsp_countries,omitempty"`
+	// Policy is the name of the verification policy, empty means the policy of the deployment.
+	Policy    string    `bson:"policy" json:"policy,omitempty"`
+	IsActive  bool      `bson:"is_active" json:"is_active"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// TenantUsage counts the requests of a tenant on a single day (UTC).
+type TenantUsage struct {
+	TenantId string `bson:"tenant_id" json:"tenant_id"`
+	Day      string `bson:"day" json:"day"` // YYYY-MM-DD
+	Requests int64  `bson:"requests" json:"requests"`
+}
+
+// Evidence is what a verification was based on, kept to reconstruct
+// the decision when it is disputed.
+type Evidence struct {
+	Id       string `bson:"id" json:"id"`
+	TenantId string `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	// Certificate and SuppliedChain are the certificates as submitted.
+	Certificate   string   `bson:"certificate" json:"certificate"`
+	SuppliedChain []string `bson:"supplied_chain,omitempty" json:"supplied_chain,omitempty"`
+	// Chain is the verified chain in PEM, leaf first, including downloaded intermediates.
+	Chain []string `bson:"chain,omitempty" json:"chain,omitempty"`
+	// OcspResponse is the DER encoded OCSP response the revocation check was based on.
+	OcspResponse []byte `bson:"ocsp_response,omitempty" json:"ocsp_response,omitempty"`
+	// Registry is the registry record of the TPP at verification time.
+	Registry *TppResponse `bson:"registry,omitempty" json:"registry,omitempty"`
+	// Policy and Result are JSON documents, kept as they were when the result was issued.
+	Policy    json.RawMessage `bson:"policy" json:"policy"`
+	Result    json.RawMessage `bson:"result" json:"result"`
+	CreatedAt time.Time       `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time       `bson:"expires_at" json:"expires_at"`
+}
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+type CallbackStatus string
+
+const (
+	CallbackPending   CallbackStatus = "pending"
+	CallbackDelivered CallbackStatus = "delivered"
+	CallbackFailed    CallbackStatus = "failed"
+)
+
+// Job is an asynchronous verification.
+type Job struct {
+	Id       string    `bson:"id" json:"id"`
+	TenantId string    `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	Status   JobStatus `bson:"status" json:"status"`
+	// Request is the JSON encoded verification request.
+	Request json.RawMessage `bson:"request" json:"-"`
+	// Result is the JSON encoded verification response of a succeeded job.
+	Result   json.RawMessage `bson:"result,omitempty" json:"result,omitempty"`
+	Error    string          `bson:"error,omitempty" json:"error,omitempty"`
+	Attempts int             `bson:"attempts" json:"attempts"`
+	// CallbackUrl receives the finished job, CallbackStatus is set once the job is finished.
+	CallbackUrl      string         `bson:"callback_url,omitempty" json:"callback_url,omitempty"`
+	CallbackStatus   CallbackStatus `bson:"callback_status,omitempty" json:"callback_status,omitempty"`
+	CallbackAttempts int            `bson:"callback_attempts,omitempty" json:"callback_attempts,omitempty"`
+	// LeasedUntil is the earliest time the job may be claimed: the end of the lease
+	// of the instance working on it, or the time of the next attempt.
+	LeasedUntil time.Time `bson:"leased_until" json:"-"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+	// ExpiresAt is set when the job is finished.
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
 }
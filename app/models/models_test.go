@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuthorizationStatus(t *testing.T) {
+	date := func(s string) *time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			t.Fatalf("Couldn't parse date %s: %v", s, err)
+		}
+		return &d
+	}
+	at := *date("2025-06-01")
+	tests := []struct {
+		name         string
+		authorizedAt *time.Time
+		withdrawnAt  *time.Time
+		want         AuthorizationStatus
+	}{
+		{"Authorized", date("2020-01-01"), nil, AuthorizationStatusAuthorized},
+		{"Zero withdrawal date", date("2020-01-01"), &time.Time{}, AuthorizationStatusAuthorized},
+		{"Withdrawn", date("2020-01-01"), date("2024-01-01"), AuthorizationStatusWithdrawn},
+		{"Withdrawal in the future", date("2020-01-01"), date("2026-01-01"), AuthorizationStatusAuthorized},
+		{"Re-authorized", date("2025-01-01"), date("2024-01-01"), AuthorizationStatusAuthorized},
+		{"Not yet authorized", date("2026-01-01"), nil, AuthorizationStatusNotAuthorized},
+		{"No authorization date", nil, nil, AuthorizationStatusNotAuthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpp := TPP{AuthorizedAt: tt.authorizedAt, WithdrawnAt: tt.withdrawnAt}
+			if got := tpp.AuthorizationStatus(at); got != tt.want {
+				t.Errorf("AuthorizationStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
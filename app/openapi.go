@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+)
+
+// openapiSpec is the OpenAPI document of the v2 API. Requests to /v2 are validated against it.
+//
+//go:embed openapi.yaml
+var openapiSpec []byte
+
+// loadOpenAPI parses and validates the document and returns it with a router of its operations.
+func loadOpenAPI() (*openapi3.T, routers.Router, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openapiSpec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to route OpenAPI document: %w", err)
+	}
+	return doc, router, nil
+}
+
+// validateRequest answers requests that do not match the OpenAPI document with a
+// validation problem listing every invalid parameter and body member. Authentication
+// is left to authMiddleware.
+func validateRequest(router routers.Router) gin.HandlerFunc {
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			writeProblem(c, newProblem(problemNotFound, ""))
+			return
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			p := newProblem(problemValidation, "The request does not match the OpenAPI document.")
+			p.Errors = invalidParams(err)
+			writeProblem(c, p)
+			return
+		}
+		c.Next()
+	}
+}
+
+// invalidParams flattens the errors of openapi3filter.ValidateRequest. A MultiError is
+// only expanded where it is returned: RequestError unwraps to the errors of its value.
+func invalidParams(err error) []invalidParam {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var params []invalidParam
+		for _, err := range multi {
+			params = append(params, invalidParams(err)...)
+		}
+		return params
+	}
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return []invalidParam{{Detail: err.Error()}}
+	}
+	param := invalidParam{Detail: reqErr.Reason}
+	if reqErr.Parameter != nil {
+		param.Parameter = reqErr.Parameter.Name
+	}
+	errs := []error{reqErr.Err}
+	if multi, ok := reqErr.Err.(openapi3.MultiError); ok {
+		errs = multi
+	}
+	var params []invalidParam
+	for _, err := range errs {
+		params = append(params, schemaParam(param, err))
+	}
+	return params
+}
+
+// schemaParam adds the reason and location of a schema error to param.
+func schemaParam(param invalidParam, err error) invalidParam {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		param.Detail = schemaErr.Reason
+		if param.Parameter == "" {
+			param.Pointer = pointer(schemaErr.JSONPointer())
+		}
+	} else if param.Detail == "" && err != nil {
+		param.Detail = err.Error()
+	}
+	return param
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// pointer formats the path of a schema error as an RFC 6901 JSON pointer.
+func pointer(path []string) string {
+	var b strings.Builder
+	for _, token := range path {
+		b.WriteString("/" + jsonPointerEscaper.Replace(token))
+	}
+	return b.String()
+}
+
+// openapiHandler serves the OpenAPI document as YAML, or as JSON if asJSON is true.
+func openapiHandler(doc *openapi3.T, asJSON bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if asJSON {
+			c.JSON(http.StatusOK, doc)
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml", openapiSpec)
+	}
+}
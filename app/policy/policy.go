@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// Enforcement defines whether a failed check invalidates the verification.
+type Enforcement string
+
+const (
+	Mandatory Enforcement = "mandatory"
+	Warning   Enforcement = "warning"
+)
+
+// RevocationMode defines how a revocation status that could not be obtained is treated.
+// A revoked certificate is rejected in both modes.
+type RevocationMode string
+
+const (
+	// RevocationStrict rejects certificates whose revocation status is unknown.
+	RevocationStrict RevocationMode = "strict"
+	// RevocationSoft only warns when the OCSP responder is missing or unavailable.
+	RevocationSoft RevocationMode = "soft"
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Policy declares the acceptance rules of a deployment.
+type Policy struct {
+	Name string `bson:"name" json:"name" yaml:"name"`
+	// Checks sets the enforcement of individual checks. Checks not listed are mandatory,
+	// except for the subject name and country checks, which are warnings.
+	Checks map[models.CheckName]Enforcement `bson:"checks,omitempty" json:"checks,omitempty" yaml:"checks,omitempty"`
+	// AllowedUsages restricts the accepted certificate usages, empty means QWAC and QSEAL.
+	AllowedUsages []models.CertUsage `bson:"allowed_usages,omitempty" json:"allowed_usages,omitempty" yaml:"allowed_usages,omitempty"`
+	// AllowedRoles requires the certificate to carry at least one of the PSD2 roles, empty means any.
+	AllowedRoles []models.ObRole `bson:"allowed_roles,omitempty" json:"allowed_roles,omitempty" yaml:"allowed_roles,omitempty"`
+	// Revocation defaults to strict.
+	Revocation RevocationMode `bson:"revocation,omitempty" json:"revocation,omitempty" yaml:"revocation,omitempty"`
+	// MaxCertAge limits the time since the certificate was issued, e.g. "8760h". Empty means no limit.
+	MaxCertAge string `bson:"max_cert_age,omitempty" json:"max_cert_age,omitempty" yaml:"max_cert_age,omitempty"`
+	// AllowedQtspCountries restricts the countries of the issuing QTSP, empty means any.
+	AllowedQtspCountries []string `bson:"allowed_qtsp_countries,omitempty" json:"allowed_qtsp_countries,omitempty" yaml:"allowed_qtsp_countries,omitempty"`
+
+	maxCertAge time.Duration
+}
+
+// Default returns the built-in policy: every check except for the subject checks
+// is mandatory and the revocation status must be known.
+func Default() *Policy {
+	return &Policy{
+		Name:       "default",
+		Revocation: RevocationStrict,
+	}
+}
+
+// Load reads a policy from a YAML or JSON file, depending on its extension.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, p)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, p)
+	default:
+		return nil, fmt.Errorf("%w: unsupported policy file extension %q", ErrInvalidPolicy, filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+var knownChecks = []models.CheckName{
+	models.CheckParse, models.CheckUsage, models.CheckSandbox, models.CheckRegistry,
+	models.CheckChain, models.CheckRevocation, models.CheckScope,
+	models.CheckNcaOrganization, models.CheckNcaAuthority, models.CheckNcaCountry,
+	models.CheckSubjectName, models.CheckSubjectCountry,
+	models.CheckRole, models.CheckCertAge, models.CheckQtspCountry,
+	models.CheckPairOrganization, models.CheckPairNca, models.CheckPairTpp,
+}
+
+// Validate checks the policy and fills in the defaults.
+func (p *Policy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPolicy)
+	}
+	for name, enforcement := range p.Checks {
+		if !slices.Contains(knownChecks, name) {
+			return fmt.Errorf("%w: unknown check %q", ErrInvalidPolicy, name)
+		}
+		if enforcement != Mandatory && enforcement != Warning {
+			return fmt.Errorf("%w: unknown enforcement %q of check %q", ErrInvalidPolicy, enforcement, name)
+		}
+	}
+	for _, usage := range p.AllowedUsages {
+		if usage != models.QWAC && usage != models.QSEAL {
+			return fmt.Errorf("%w: unknown usage %q", ErrInvalidPolicy, usage)
+		}
+	}
+	for _, role := range p.AllowedRoles {
+		if !slices.Contains(models.ObRoles, role) {
+			return fmt.Errorf("%w: unknown role %q", ErrInvalidPolicy, role)
+		}
+	}
+	switch p.Revocation {
+	case "":
+		p.Revocation = RevocationStrict
+	case RevocationStrict, RevocationSoft:
+	default:
+		return fmt.Errorf("%w: unknown revocation mode %q", ErrInvalidPolicy, p.Revocation)
+	}
+	if p.MaxCertAge != "" {
+		d, err := time.ParseDuration(p.MaxCertAge)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%w: invalid max_cert_age %q", ErrInvalidPolicy, p.MaxCertAge)
+		}
+		p.maxCertAge = d
+	}
+	for i, country := range p.AllowedQtspCountries {
+		p.AllowedQtspCountries[i] = strings.ToUpper(strings.TrimSpace(country))
+	}
+	return nil
+}
+
+// warningChecks are not mandatory unless the policy says otherwise,
+// as registry names are often abbreviated or translated.
+var warningChecks = []models.CheckName{models.CheckSubjectName, models.CheckSubjectCountry}
+
+// Mandatory reports whether a failure of the check invalidates the verification.
+func (p *Policy) Mandatory(name models.CheckName) bool {
+	if enforcement, ok := p.Checks[name]; ok {
+		return enforcement == Mandatory
+	}
+	return !slices.Contains(warningChecks, name)
+}
+
+// UsageAllowed reports whether certificates of the given usage are accepted.
+func (p *Policy) UsageAllowed(usage models.CertUsage) bool {
+	if len(p.AllowedUsages) == 0 {
+		return usage == models.QWAC || usage == models.QSEAL
+	}
+	return slices.Contains(p.AllowedUsages, usage)
+}
+
+// CertAgeLimit returns the maximum time since the certificate was issued, zero means no limit.
+func (p *Policy) CertAgeLimit() time.Duration {
+	return p.maxCertAge
+}
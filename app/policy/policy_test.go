@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+func TestLoad_Examples(t *testing.T) {
+	paths, err := filepath.Glob("../../policies/*")
+	if err != nil {
+		t.Fatalf("Failed to list policies: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("Expected example policies")
+	}
+	for _, path := range paths {
+		p, err := Load(path)
+		if err != nil {
+			t.Errorf("Expected %s to be valid, got %v", path, err)
+			continue
+		}
+		if p.Name == "" {
+			t.Errorf("Expected %s to have a name", path)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		valid   bool
+	}{
+		{"YAML", "policy.yaml", "name: test\nchecks:\n  sandbox: warning\nmax_cert_age: 24h\n", true},
+		{"JSON", "policy.json", `{"name": "test", "allowed_roles": ["PSP_AI"]}`, true},
+		{"Missing name", "policy.yaml", "revocation: soft\n", false},
+		{"Pair check", "policy.yaml", "name: test\nchecks:\n  pair_nca: warning\n", true},
+		{"Unknown check", "policy.yaml", "name: test\nchecks:\n  colour: warning\n", false},
+		{"Unknown enforcement", "policy.yaml", "name: test\nchecks:\n  sandbox: optional\n", false},
+		{"Unknown usage", "policy.yaml", "name: test\nallowed_usages: [QSIGN]\n", false},
+		{"Unknown role", "policy.yaml", "name: test\nallowed_roles: [PSP_XX]\n", false},
+		{"Unknown revocation mode", "policy.yaml", "name: test\nrevocation: lenient\n", false},
+		{"Invalid max age", "policy.yaml", "name: test\nmax_cert_age: 1 year\n", false},
+		{"Unsupported extension", "policy.toml", "name = \"test\"\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("Failed to write policy: %v", err)
+			}
+			_, err := Load(path)
+			if tt.valid && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("Expected ErrInvalidPolicy, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := &Policy{
+		Name:       "test",
+		Checks:     map[models.CheckName]Enforcement{models.CheckSandbox: Warning, models.CheckSubjectName: Mandatory},
+		MaxCertAge: "48h",
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Revocation != RevocationStrict {
+		t.Errorf("Expected strict revocation by default, got %s", p.Revocation)
+	}
+	if p.CertAgeLimit() != 48*time.Hour {
+		t.Errorf("Expected max certificate age of 48h, got %s", p.CertAgeLimit())
+	}
+	mandatory := map[models.CheckName]bool{
+		models.CheckChain:          true,
+		models.CheckSandbox:        false,
+		models.CheckSubjectName:    true,
+		models.CheckSubjectCountry: false,
+	}
+	for name, expected := range mandatory {
+		if p.Mandatory(name) != expected {
+			t.Errorf("Expected %s mandatory %v, got %v", name, expected, p.Mandatory(name))
+		}
+	}
+	if !Default().UsageAllowed(models.QWAC) || Default().UsageAllowed(models.UNKNOWN) {
+		t.Error("Expected the default policy to allow QWAC and QSEAL only")
+	}
+}
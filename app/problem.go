@@ -0,0 +1,119 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+// problemContentType is the media type of RFC 9457 problem details.
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix is the prefix of the type URIs. The URIs are part of the v2 API
+// and must not change; GET on a type URI describes the type.
+const problemTypePrefix = "/v2/problems/"
+
+type problemType struct {
+	slug   string
+	title  string
+	status int
+}
+
+func (t problemType) uri() string {
+	return problemTypePrefix + t.slug
+}
+
+var (
+	problemValidation          = problemType{"validation-error", "The request is not valid.", http.StatusBadRequest}
+	problemInvalidCertificate  = problemType{"invalid-certificate", "The certificate could not be parsed.", http.StatusUnprocessableEntity}
+	problemReceiptsDisabled    = problemType{"receipts-disabled", "Receipts are not enabled.", http.StatusBadRequest}
+	problemUnauthorized        = problemType{"unauthorized", "Invalid or missing credentials.", http.StatusUnauthorized}
+	problemNotFound            = problemType{"not-found", "The resource does not exist.", http.StatusNotFound}
+	problemTppNotFound         = problemType{"tpp-not-found", "TPP not found.", http.StatusNotFound}
+	problemAmbiguousTppId      = problemType{"ambiguous-tpp-id", "The identifier matches several TPPs.", http.StatusConflict}
+	problemRegistryUnavailable = problemType{"registry-unavailable", "The registry could not be queried.", http.StatusServiceUnavailable}
+	problemInternal            = problemType{"internal-error", "The request could not be processed.", http.StatusInternalServerError}
+)
+
+var problemTypes = []problemType{
+	problemValidation, problemInvalidCertificate, problemReceiptsDisabled, problemUnauthorized,
+	problemNotFound, problemTppNotFound, problemAmbiguousTppId, problemRegistryUnavailable, problemInternal,
+}
+
+// problem is an RFC 9457 problem details object.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors lists the invalid parts of the request of a validation error.
+	Errors []invalidParam `json:"errors,omitempty"`
+}
+
+// invalidParam is an invalid part of a request, either a body member identified by
+// its JSON pointer or a parameter identified by its name.
+type invalidParam struct {
+	Detail    string `json:"detail"`
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+func newProblem(t problemType, detail string) *problem {
+	return &problem{Type: t.uri(), Title: t.title, Status: t.status, Detail: detail}
+}
+
+// writeProblem answers the request with a problem of the given type.
+func writeProblem(c *gin.Context, p *problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// errorProblem maps the errors of the verify package to problems. Errors of requests
+// that passed validation but are still invalid, e.g. a malformed cursor, are validation errors.
+func errorProblem(err error) *problem {
+	switch {
+	case errors.Is(err, verify.ErrInvalidCertificate):
+		return newProblem(problemInvalidCertificate, "Invalid certificate format.")
+	case errors.Is(err, verify.ErrNoCertificate):
+		return newProblem(problemInvalidCertificate, "No valid certificate found.")
+	case errors.Is(err, verify.ErrCertificateParse):
+		return newProblem(problemInvalidCertificate, "Failed to parse certificate.")
+	case errors.Is(err, verify.ErrReceiptsDisabled):
+		return newProblem(problemReceiptsDisabled, "")
+	case errors.Is(err, db.ErrTppNotFound):
+		return newProblem(problemTppNotFound, "")
+	case errors.Is(err, verify.ErrAmbiguousTppId):
+		return newProblem(problemAmbiguousTppId, err.Error())
+	case errors.Is(err, verify.ErrTppLookup):
+		return newProblem(problemRegistryUnavailable, "Failed to retrieve TPP information.")
+	case errors.Is(err, verify.ErrInvalidTppSearch),
+		errors.Is(err, verify.ErrEmptyBatch), errors.Is(err, verify.ErrBatchTooLarge),
+		errors.Is(err, verify.ErrNoTppIds), errors.Is(err, verify.ErrTooManyTppIds):
+		return newProblem(problemValidation, err.Error())
+	default:
+		return newProblem(problemInternal, "")
+	}
+}
+
+// problemTypeHandler describes the problem type of a type URI.
+func problemTypeHandler(c *gin.Context) {
+	for _, t := range problemTypes {
+		if t.slug == c.Param("type") {
+			c.JSON(http.StatusOK, gin.H{
+				"type":   t.uri(),
+				"title":  t.title,
+				"status": t.status,
+			})
+			return
+		}
+	}
+	writeProblem(c, newProblem(problemNotFound, "Unknown problem type."))
+}
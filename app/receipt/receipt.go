@@ -0,0 +1,370 @@
+// Package receipt signs verification results as compact JWS (RFC 7515) and publishes
+// the verification keys as a JWK Set (RFC 7517), so that receipts can be checked offline.
+package receipt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported receipt key")
+	ErrInvalidReceipt = errors.New("invalid receipt")
+	ErrUnknownKey     = errors.New("unknown receipt key")
+)
+
+// JWK is the public part of a signing key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is the document served at /keys.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// receiptType is the typ header of receipts, so that they are not confused with other JWS of the same key.
+const receiptType = "tpp-verification-receipt+jws"
+
+// Signer signs receipts with the server key. It also verifies receipts signed
+// with the current key and with any previous keys added after a rotation.
+type Signer struct {
+	key  crypto.Signer
+	alg  string
+	kid  string
+	keys map[string]verificationKey
+	jwks JWKS
+}
+
+type verificationKey struct {
+	alg string
+	pub crypto.PublicKey
+}
+
+// NewSigner creates a signer for an ECDSA P-256/P-384, RSA or Ed25519 key.
+// The key ID is the RFC 7638 thumbprint of the public key.
+func NewSigner(key crypto.Signer) (*Signer, error) {
+	s := &Signer{key: key, keys: make(map[string]verificationKey)}
+	jwk, err := s.AddVerificationKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	s.alg, s.kid = jwk.Alg, jwk.Kid
+	return s, nil
+}
+
+// LoadSigner reads a PEM encoded private key (PKCS #8, SEC 1 or PKCS #1).
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigner(key)
+}
+
+// LoadVerificationKeys adds the PEM encoded public keys or certificates of the file,
+// typically the keys receipts were signed with before a key rotation.
+func (s *Signer) LoadVerificationKeys(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		var pub crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var crt *x509.Certificate
+			crt, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				pub = crt.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUnsupportedKey, err)
+		}
+		if _, err := s.AddVerificationKey(pub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrUnsupportedKey)
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedKey, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+	return signer, nil
+}
+
+// AddVerificationKey accepts receipts signed with the key and publishes it in the JWKS.
+func (s *Signer) AddVerificationKey(pub crypto.PublicKey) (JWK, error) {
+	jwk, err := publicJWK(pub)
+	if err != nil {
+		return JWK{}, err
+	}
+	if _, ok := s.keys[jwk.Kid]; !ok {
+		s.keys[jwk.Kid] = verificationKey{alg: jwk.Alg, pub: pub}
+		s.jwks.Keys = append(s.jwks.Keys, jwk)
+	}
+	return jwk, nil
+}
+
+// KeyId returns the ID of the signing key.
+func (s *Signer) KeyId() string {
+	return s.kid
+}
+
+// JWKS returns the keys receipts can be verified with.
+func (s *Signer) JWKS() JWKS {
+	return s.jwks
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func publicJWK(pub crypto.PublicKey) (JWK, error) {
+	var jwk JWK
+	// Members in lexicographic order, as required for the RFC 7638 thumbprint
+	var thumbprint string
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk = JWK{Kty: "EC", X: b64(k.X.FillBytes(make([]byte, size))), Y: b64(k.Y.FillBytes(make([]byte, size)))}
+		switch k.Curve {
+		case elliptic.P256():
+			jwk.Crv, jwk.Alg = "P-256", "ES256"
+		case elliptic.P384():
+			jwk.Crv, jwk.Alg = "P-384", "ES384"
+		default:
+			return JWK{}, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, k.Curve.Params().Name)
+		}
+		thumbprint = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, jwk.Crv, jwk.X, jwk.Y)
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return JWK{}, fmt.Errorf("%w: RSA keys must have at least 2048 bits", ErrUnsupportedKey)
+		}
+		jwk = JWK{Kty: "RSA", Alg: "RS256", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}
+		thumbprint = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case ed25519.PublicKey:
+		jwk = JWK{Kty: "OKP", Crv: "Ed25519", Alg: "EdDSA", X: b64(k)}
+		thumbprint = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":%q}`, jwk.X)
+	default:
+		return JWK{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+	}
+	sum := sha256.Sum256([]byte(thumbprint))
+	jwk.Kid = b64(sum[:])
+	jwk.Use = "sig"
+	return jwk, nil
+}
+
+// Sign returns the compact JWS of the JSON encoded claims.
+func (s *Signer) Sign(claims any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return s.sign(receiptType, payload)
+}
+
+// SignDetached returns the JWS of the payload with a detached payload (RFC 7515, appendix F),
+// e.g. to sign the body of a request. The typ header tells the use of the signature.
+func (s *Signer) SignDetached(typ string, payload []byte) (string, error) {
+	token, err := s.sign(typ, payload)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(token, ".")
+	return parts[0] + ".." + parts[2], nil
+}
+
+func (s *Signer) sign(typ string, payload []byte) (string, error) {
+	h, err := json.Marshal(header{Alg: s.alg, Kid: s.kid, Typ: typ})
+	if err != nil {
+		return "", err
+	}
+	input := b64(h) + "." + b64(payload)
+	var sig []byte
+	switch s.alg {
+	case "EdDSA":
+		sig, err = s.key.Sign(rand.Reader, []byte(input), crypto.Hash(0))
+	case "RS256":
+		digest := sha256.Sum256([]byte(input))
+		sig, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case "ES256", "ES384":
+		sig, err = s.signECDSA(input)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign receipt: %w", err)
+	}
+	return input + "." + b64(sig), nil
+}
+
+// signECDSA converts the ASN.1 signature of the key to the fixed-size R || S form of JWS.
+func (s *Signer) signECDSA(input string) ([]byte, error) {
+	digest := hashInput(s.alg, input)
+	der, err := s.key.Sign(rand.Reader, digest, hashOf(s.alg))
+	if err != nil {
+		return nil, err
+	}
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	}
+	size := (s.key.Public().(*ecdsa.PublicKey).Curve.Params().BitSize + 7) / 8
+	out := make([]byte, 2*size)
+	sig.R.FillBytes(out[:size])
+	sig.S.FillBytes(out[size:])
+	return out, nil
+}
+
+func hashOf(alg string) crypto.Hash {
+	if alg == "ES384" {
+		return crypto.SHA384
+	}
+	return crypto.SHA256
+}
+
+func hashInput(alg string, input string) []byte {
+	if alg == "ES384" {
+		sum := sha512.Sum384([]byte(input))
+		return sum[:]
+	}
+	sum := sha256.Sum256([]byte(input))
+	return sum[:]
+}
+
+// Verify checks the signature of the receipt against the known keys and decodes its claims.
+// It returns the ID of the key the receipt was signed with.
+func (s *Signer) Verify(token string, claims any) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: not a compact JWS", ErrInvalidReceipt)
+	}
+	kid, err := s.verify(parts, receiptType)
+	if err != nil {
+		return "", err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidReceipt, err)
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidReceipt, err)
+	}
+	return kid, nil
+}
+
+// VerifyDetached checks a signature made with SignDetached over the payload.
+func (s *Signer) VerifyDetached(token string, typ string, payload []byte) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return "", fmt.Errorf("%w: not a JWS with a detached payload", ErrInvalidReceipt)
+	}
+	parts[1] = b64(payload)
+	return s.verify(parts, typ)
+}
+
+func (s *Signer) verify(parts []string, typ string) (string, error) {
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidReceipt, err)
+	}
+	var h header
+	if err := json.Unmarshal(rawHeader, &h); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidReceipt, err)
+	}
+	if h.Typ != typ {
+		return "", fmt.Errorf("%w: unexpected type %q", ErrInvalidReceipt, h.Typ)
+	}
+	key, ok := s.keys[h.Kid]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, h.Kid)
+	}
+	// The algorithm is bound to the key, the header cannot downgrade it
+	if h.Alg != key.alg {
+		return "", fmt.Errorf("%w: algorithm %s does not match the key", ErrInvalidReceipt, h.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidReceipt, err)
+	}
+	if !verifySignature(key, parts[0]+"."+parts[1], sig) {
+		return "", fmt.Errorf("%w: signature mismatch", ErrInvalidReceipt)
+	}
+	return h.Kid, nil
+}
+
+func verifySignature(key verificationKey, input string, sig []byte) bool {
+	switch pub := key.pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, []byte(input), sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256([]byte(input))
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(pub, hashInput(key.alg, input), r, s)
+	}
+	return false
+}
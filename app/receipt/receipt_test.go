@@ -0,0 +1,186 @@
+package receipt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testClaims struct {
+	Valid  bool   `json:"valid"`
+	Policy string `json:"policy"`
+}
+
+func newKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return map[string]crypto.Signer{"ES256": p256, "ES384": p384, "RS256": rsaKey, "EdDSA": edKey}
+}
+
+func TestSignVerify(t *testing.T) {
+	for alg, key := range newKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			signer, err := NewSigner(key)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			token, err := signer.Sign(testClaims{Valid: true, Policy: "default"})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var claims testClaims
+			kid, err := signer.Verify(token, &claims)
+			if err != nil {
+				t.Fatalf("Expected a valid receipt, got %v", err)
+			}
+			if kid != signer.KeyId() || !claims.Valid || claims.Policy != "default" {
+				t.Errorf("Expected the signed claims, got %s %+v", kid, claims)
+			}
+			jwks := signer.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Alg != alg || jwks.Keys[0].Kid != kid {
+				t.Errorf("Expected a single %s key %s, got %+v", alg, kid, jwks)
+			}
+
+			// Replace the payload, keeping the header and signature
+			parts := strings.Split(token, ".")
+			forged, err := signer.Sign(testClaims{Valid: false, Policy: "default"})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			parts[1] = strings.Split(forged, ".")[1]
+			if _, err := signer.Verify(strings.Join(parts, "."), &claims); !errors.Is(err, ErrInvalidReceipt) {
+				t.Errorf("Expected a tampered receipt to be rejected, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSignDetached(t *testing.T) {
+	signer, err := NewSigner(newKeys(t)["ES256"])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body := []byte(`{"id":"job-1","status":"succeeded"}`)
+	token, err := signer.SignDetached("callback+jws", body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if parts := strings.Split(token, "."); len(parts) != 3 || parts[1] != "" {
+		t.Fatalf("Expected a detached payload, got %s", token)
+	}
+	if kid, err := signer.VerifyDetached(token, "callback+jws", body); err != nil || kid != signer.KeyId() {
+		t.Errorf("Expected a valid signature, got %s %v", kid, err)
+	}
+	if _, err := signer.VerifyDetached(token, "callback+jws", []byte(`{"id":"job-1","status":"failed"}`)); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("Expected a modified payload to be rejected, got %v", err)
+	}
+	if _, err := signer.VerifyDetached(token, receiptType, body); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("Expected a signature of another type to be rejected, got %v", err)
+	}
+	var claims testClaims
+	if _, err := signer.Verify(token, &claims); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("Expected a detached signature not to be accepted as a receipt, got %v", err)
+	}
+}
+
+func TestVerify_KeyRotation(t *testing.T) {
+	keys := newKeys(t)
+	previous, err := NewSigner(keys["ES256"])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	token, err := previous.Sign(testClaims{Valid: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	current, err := NewSigner(keys["EdDSA"])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var claims testClaims
+	if _, err := current.Verify(token, &claims); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Expected an unknown key, got %v", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(keys["ES256"].Public())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "previous.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	if err := current.LoadVerificationKeys(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if kid, err := current.Verify(token, &claims); err != nil || kid != previous.KeyId() {
+		t.Errorf("Expected the receipt of the previous key to be valid, got %s %v", kid, err)
+	}
+	if len(current.JWKS().Keys) != 2 {
+		t.Errorf("Expected both keys to be published, got %+v", current.JWKS())
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tests := []struct {
+		name  string
+		block *pem.Block
+		err   error
+	}{
+		{"PKCS #8", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, nil},
+		{"SEC 1", &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}, nil},
+		{"Garbage", &pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")}, ErrUnsupportedKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "key.pem")
+			if err := os.WriteFile(path, pem.EncodeToMemory(tt.block), 0o600); err != nil {
+				t.Fatalf("Failed to write key: %v", err)
+			}
+			signer, err := LoadSigner(path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && signer.JWKS().Keys[0].Alg != "ES256" {
+				t.Errorf("Expected an ES256 key, got %+v", signer.JWKS())
+			}
+		})
+	}
+}
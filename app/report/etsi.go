@@ -0,0 +1,301 @@
+// Package report renders verification results as ETSI TS 119 102-2 validation reports.
+package report
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+const Namespace = "http://uri.etsi.org/19102/v1.2.1#"
+
+// Main indications of ETSI EN 319 102-1.
+const (
+	TotalPassed   = "urn:etsi:019102:mainindication:total-passed"
+	TotalFailed   = "urn:etsi:019102:mainindication:total-failed"
+	Indeterminate = "urn:etsi:019102:mainindication:indeterminate"
+)
+
+// Sub-indications of ETSI EN 319 102-1 used by the verifier.
+const (
+	SubRevoked                 = "urn:etsi:019102:subindication:REVOKED"
+	SubNotYetValid             = "urn:etsi:019102:subindication:NOT_YET_VALID"
+	SubOutOfBoundsNoPoe        = "urn:etsi:019102:subindication:OUT_OF_BOUNDS_NO_POE"
+	SubNoCertificateChainFound = "urn:etsi:019102:subindication:NO_CERTIFICATE_CHAIN_FOUND"
+	SubCertificateChainFailure = "urn:etsi:019102:subindication:CERTIFICATE_CHAIN_GENERAL_FAILURE"
+	SubTryLater                = "urn:etsi:019102:subindication:TRY_LATER"
+	SubSigConstraintsFailure   = "urn:etsi:019102:subindication:SIG_CONSTRAINTS_FAILURE"
+	SubChainConstraintsFailure = "urn:etsi:019102:subindication:CHAIN_CONSTRAINTS_FAILURE"
+)
+
+const (
+	objectTypeCertificate    = "urn:etsi:019102:validationObject:certificate"
+	objectTypeOcspResponse   = "urn:etsi:019102:validationObject:OCSPResponse"
+	constraintStatusApplied  = "urn:etsi:019102:constraintStatus:applied"
+	constraintStatusDisabled = "urn:etsi:019102:constraintStatus:disabled"
+	// Checks of the verifier are reported as constraints under its own URN
+	constraintIdentifierPrefix = "urn:tppverifier:constraint:"
+)
+
+type ValidationReport struct {
+	XMLName                    xml.Name                   `xml:"ValidationReport"`
+	Xmlns                      string                     `xml:"xmlns,attr"`
+	SignatureValidationReport  SignatureValidationReport  `xml:"SignatureValidationReport"`
+	SignatureValidationObjects SignatureValidationObjects `xml:"SignatureValidationObjects"`
+	SignatureValidator         SignatureValidator         `xml:"SignatureValidator"`
+}
+
+type SignatureValidationReport struct {
+	ValidationConstraintsEvaluationReport ValidationConstraintsEvaluationReport `xml:"ValidationConstraintsEvaluationReport"`
+	ValidationTimeInfo                    ValidationTimeInfo                    `xml:"ValidationTimeInfo"`
+	SignerInformation                     *SignerInformation                    `xml:"SignerInformation,omitempty"`
+	SignatureValidationStatus             ValidationStatus                      `xml:"SignatureValidationStatus"`
+}
+
+type ValidationConstraintsEvaluationReport struct {
+	ValidationConstraint []ValidationConstraint `xml:"ValidationConstraint"`
+}
+
+type ValidationConstraint struct {
+	ValidationConstraintIdentifier string            `xml:"ValidationConstraintIdentifier"`
+	ValidationConstraintParameter  string            `xml:"ValidationConstraintParameter,omitempty"`
+	ConstraintStatus               ConstraintStatus  `xml:"ConstraintStatus"`
+	ValidationStatus               *ValidationStatus `xml:"ValidationStatus,omitempty"`
+}
+
+type ConstraintStatus struct {
+	Status string `xml:"Status"`
+}
+
+type ValidationTimeInfo struct {
+	ValidationTime time.Time `xml:"ValidationTime"`
+}
+
+type SignerInformation struct {
+	SignerCertificate VOReference `xml:"SignerCertificate"`
+	Signer            string      `xml:"Signer,omitempty"`
+}
+
+type VOReference struct {
+	VOReference string `xml:"VOReference,attr"`
+}
+
+type ValidationStatus struct {
+	MainIndication                 string                          `xml:"MainIndication"`
+	SubIndication                  string                          `xml:"SubIndication,omitempty"`
+	AssociatedValidationReportData *AssociatedValidationReportData `xml:"AssociatedValidationReportData,omitempty"`
+}
+
+type AssociatedValidationReportData struct {
+	CertificateChain            *CertificateChain            `xml:"CertificateChain,omitempty"`
+	RevocationStatusInformation *RevocationStatusInformation `xml:"RevocationStatusInformation,omitempty"`
+	TrustAnchor                 *VOReference                 `xml:"TrustAnchor,omitempty"`
+}
+
+type CertificateChain struct {
+	SigningCertificate      VOReference   `xml:"SigningCertificate"`
+	IntermediateCertificate []VOReference `xml:"IntermediateCertificate"`
+	TrustAnchor             *VOReference  `xml:"TrustAnchor,omitempty"`
+}
+
+type RevocationStatusInformation struct {
+	ValidationObjectId string      `xml:"ValidationObjectId"`
+	RevocationTime     *time.Time  `xml:"RevocationTime,omitempty"`
+	RevocationReason   string      `xml:"RevocationReason,omitempty"`
+	RevocationObject   VOReference `xml:"RevocationObject"`
+}
+
+type SignatureValidationObjects struct {
+	ValidationObject []ValidationObject `xml:"ValidationObject"`
+}
+
+type ValidationObject struct {
+	Id                             string                         `xml:"id,attr"`
+	ObjectType                     string                         `xml:"ObjectType"`
+	ValidationObjectRepresentation ValidationObjectRepresentation `xml:"ValidationObjectRepresentation"`
+}
+
+type ValidationObjectRepresentation struct {
+	Base64 string `xml:"base64"`
+}
+
+type SignatureValidator struct {
+	DigitalId DigitalId `xml:"DigitalId"`
+}
+
+type DigitalId struct {
+	Other string `xml:"Other"`
+}
+
+// objectId identifies a validation object by the SHA-256 of its DER encoding.
+func objectId(prefix string, der []byte) string {
+	sum := sha256.Sum256(der)
+	return prefix + "-" + strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// New derives the report from the checks and artifacts of the result. The certificate
+// takes the place of the signer, as the verifier validates certificates rather than signatures.
+func New(res *verify.VerifyResponse, validator string) *ValidationReport {
+	return build(res, res.Artifacts(), validator)
+}
+
+func build(res *verify.VerifyResponse, artifacts verify.Artifacts, validator string) *ValidationReport {
+	report := &ValidationReport{
+		Xmlns: Namespace,
+		SignatureValidationReport: SignatureValidationReport{
+			ValidationTimeInfo: ValidationTimeInfo{ValidationTime: res.VerifiedAt.UTC()},
+		},
+		SignatureValidator: SignatureValidator{DigitalId: DigitalId{Other: validator}},
+	}
+	svr := &report.SignatureValidationReport
+	objects := &report.SignatureValidationObjects.ValidationObject
+
+	var data AssociatedValidationReportData
+	certs := artifacts.Chain
+	if len(certs) == 0 && artifacts.Certificate != nil {
+		certs = []*x509.Certificate{artifacts.Certificate}
+	}
+	for i, crt := range certs {
+		ref := VOReference{VOReference: objectId("C", crt.Raw)}
+		*objects = append(*objects, ValidationObject{
+			Id:                             ref.VOReference,
+			ObjectType:                     objectTypeCertificate,
+			ValidationObjectRepresentation: ValidationObjectRepresentation{Base64: base64.StdEncoding.EncodeToString(crt.Raw)},
+		})
+		switch {
+		case i == 0:
+			data.CertificateChain = &CertificateChain{SigningCertificate: ref}
+			svr.SignerInformation = &SignerInformation{SignerCertificate: ref, Signer: crt.Subject.String()}
+		case i == len(certs)-1 && artifacts.Chain != nil:
+			data.CertificateChain.TrustAnchor = &ref
+			data.TrustAnchor = &ref
+		default:
+			data.CertificateChain.IntermediateCertificate = append(data.CertificateChain.IntermediateCertificate, ref)
+		}
+	}
+	if artifacts.OcspResponse != nil && data.CertificateChain != nil {
+		ref := VOReference{VOReference: objectId("R", artifacts.OcspResponse)}
+		*objects = append(*objects, ValidationObject{
+			Id:                             ref.VOReference,
+			ObjectType:                     objectTypeOcspResponse,
+			ValidationObjectRepresentation: ValidationObjectRepresentation{Base64: base64.StdEncoding.EncodeToString(artifacts.OcspResponse)},
+		})
+		info := &RevocationStatusInformation{
+			ValidationObjectId: data.CertificateChain.SigningCertificate.VOReference,
+			RevocationObject:   ref,
+		}
+		if ocspResponse, err := ocsp.ParseResponse(artifacts.OcspResponse, nil); err == nil && ocspResponse.Status == ocsp.Revoked {
+			revokedAt := ocspResponse.RevokedAt.UTC()
+			info.RevocationTime = &revokedAt
+			info.RevocationReason = revocationReason(ocspResponse.RevocationReason)
+		}
+		data.RevocationStatusInformation = info
+	}
+
+	for _, check := range res.Checks {
+		constraint := ValidationConstraint{
+			ValidationConstraintIdentifier: constraintIdentifierPrefix + string(check.Name),
+			ValidationConstraintParameter:  check.Details,
+			ConstraintStatus:               ConstraintStatus{Status: constraintStatusApplied},
+		}
+		if check.Status == models.CheckStatusSkipped {
+			constraint.ConstraintStatus.Status = constraintStatusDisabled
+		} else {
+			main, sub := checkIndication(check)
+			constraint.ValidationStatus = &ValidationStatus{MainIndication: main, SubIndication: sub}
+		}
+		svr.ValidationConstraintsEvaluationReport.ValidationConstraint = append(svr.ValidationConstraintsEvaluationReport.ValidationConstraint, constraint)
+	}
+
+	main, sub := indication(res, artifacts)
+	svr.SignatureValidationStatus = ValidationStatus{MainIndication: main, SubIndication: sub}
+	if data.CertificateChain != nil {
+		svr.SignatureValidationStatus.AssociatedValidationReportData = &data
+	}
+	return report
+}
+
+// indication returns the overall indication. Problems of the certificate itself take
+// precedence over failed constraints, in the order of EN 319 102-1.
+func indication(res *verify.VerifyResponse, artifacts verify.Artifacts) (string, string) {
+	if res.Valid {
+		return TotalPassed, ""
+	}
+	if check := findFailed(res.Checks, models.CheckRevocation); check != nil && check.Code == models.CodeRevoked {
+		return TotalFailed, SubRevoked
+	}
+	if crt := artifacts.Certificate; crt != nil {
+		if res.VerifiedAt.Before(crt.NotBefore) {
+			return Indeterminate, SubNotYetValid
+		}
+		if res.VerifiedAt.After(crt.NotAfter) {
+			return Indeterminate, SubOutOfBoundsNoPoe
+		}
+	}
+	for _, name := range []models.CheckName{models.CheckChain, models.CheckRevocation} {
+		if check := findFailed(res.Checks, name); check != nil {
+			return checkIndication(*check)
+		}
+	}
+	return Indeterminate, SubSigConstraintsFailure
+}
+
+func findFailed(checks []models.Check, name models.CheckName) *models.Check {
+	for i := range checks {
+		if checks[i].Name == name && (checks[i].Status == models.CheckStatusFail || checks[i].Status == models.CheckStatusError) {
+			return &checks[i]
+		}
+	}
+	return nil
+}
+
+// checkIndication maps the outcome of a single check to an indication.
+func checkIndication(check models.Check) (string, string) {
+	switch check.Status {
+	case models.CheckStatusPass, models.CheckStatusWarning:
+		return TotalPassed, ""
+	}
+	switch check.Code {
+	case models.CodeRevoked:
+		return TotalFailed, SubRevoked
+	case models.CodeChainUntrusted:
+		return Indeterminate, SubNoCertificateChainFound
+	case models.CodeChainUnavailable, models.CodeOcspUnavailable:
+		return Indeterminate, SubTryLater
+	case models.CodeOcspMissing, models.CodeOcspInvalidResponse:
+		return Indeterminate, SubCertificateChainFailure
+	case models.CodeUsageUnknown, models.CodeUsageMismatch, models.CodeUsageNotAllowed, models.CodeSandbox:
+		return Indeterminate, SubChainConstraintsFailure
+	}
+	return Indeterminate, SubSigConstraintsFailure
+}
+
+// revocationReason returns the RFC 5280 name of the CRL reason code.
+func revocationReason(code int) string {
+	reasons := []string{
+		"unspecified", "keyCompromise", "cACompromise", "affiliationChanged", "superseded",
+		"cessationOfOperation", "certificateHold", "", "removeFromCRL", "privilegeWithdrawn", "aACompromise",
+	}
+	if code < 0 || code >= len(reasons) || reasons[code] == "" {
+		return "unspecified"
+	}
+	return reasons[code]
+}
+
+// Marshal returns the report as an XML document.
+func (r *ValidationReport) Marshal() ([]byte, error) {
+	out, err := xml.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,152 @@
+package report
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+func readCert(t *testing.T, name string) *x509.Certificate {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "chains", "production", name))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	block, _ := pem.Decode(data)
+	crt, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", name, err)
+	}
+	return crt
+}
+
+func readKey(t *testing.T, name string) crypto.Signer {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "chains", "production", name))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	block, _ := pem.Decode(data)
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", name, err)
+	}
+	return key.(crypto.Signer)
+}
+
+func mustParse(t *testing.T, der []byte) *x509.Certificate {
+	t.Helper()
+	crt, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return crt
+}
+
+func TestNew(t *testing.T) {
+	leaf, intermediate, root := readCert(t, "leaf.pem"), readCert(t, "intermediate.pem"), readCert(t, "ca.pem")
+	at := leaf.NotBefore.Add(24 * time.Hour)
+	ocspResponse := func(status int) []byte {
+		der, err := ocsp.CreateResponse(intermediate, intermediate, ocsp.Response{
+			Status:           status,
+			SerialNumber:     leaf.SerialNumber,
+			ThisUpdate:       at,
+			NextUpdate:       at.Add(time.Hour),
+			RevokedAt:        at.Add(-time.Hour),
+			RevocationReason: ocsp.KeyCompromise,
+		}, readKey(t, "intermediate.key"))
+		if err != nil {
+			t.Fatalf("Failed to create OCSP response: %v", err)
+		}
+		return der
+	}
+	chain := []*x509.Certificate{leaf, intermediate, root}
+	passed := []models.Check{
+		{Name: models.CheckChain, Status: models.CheckStatusPass, Code: models.CodeChainTrusted},
+		{Name: models.CheckRevocation, Status: models.CheckStatusPass, Code: models.CodeNotRevoked},
+	}
+	tests := []struct {
+		name      string
+		valid     bool
+		checks    []models.Check
+		artifacts verify.Artifacts
+		at        time.Time
+		main, sub string
+		objects   int
+		revokedAt bool
+	}{
+		{"Passed", true, passed, verify.Artifacts{Certificate: leaf, Chain: chain, OcspResponse: ocspResponse(ocsp.Good)}, at, TotalPassed, "", 4, false},
+		{"Revoked", false, []models.Check{
+			{Name: models.CheckChain, Status: models.CheckStatusPass, Code: models.CodeChainTrusted},
+			{Name: models.CheckRevocation, Status: models.CheckStatusFail, Code: models.CodeRevoked},
+		}, verify.Artifacts{Certificate: leaf, Chain: chain, OcspResponse: ocspResponse(ocsp.Revoked)}, at, TotalFailed, SubRevoked, 4, true},
+		{"Untrusted", false, []models.Check{
+			{Name: models.CheckChain, Status: models.CheckStatusFail, Code: models.CodeChainUntrusted},
+			{Name: models.CheckRevocation, Status: models.CheckStatusSkipped, Code: models.CodeSkipped},
+		}, verify.Artifacts{Certificate: leaf}, at, Indeterminate, SubNoCertificateChainFound, 1, false},
+		{"OCSP unavailable", false, []models.Check{
+			{Name: models.CheckChain, Status: models.CheckStatusPass, Code: models.CodeChainTrusted},
+			{Name: models.CheckRevocation, Status: models.CheckStatusError, Code: models.CodeOcspUnavailable},
+		}, verify.Artifacts{Certificate: leaf, Chain: chain}, at, Indeterminate, SubTryLater, 3, false},
+		{"Expired", false, []models.Check{
+			{Name: models.CheckChain, Status: models.CheckStatusFail, Code: models.CodeChainUntrusted},
+		}, verify.Artifacts{Certificate: leaf}, leaf.NotAfter.Add(time.Hour), Indeterminate, SubOutOfBoundsNoPoe, 1, false},
+		{"Registry", false, append(passed, models.Check{Name: models.CheckRegistry, Status: models.CheckStatusFail, Code: models.CodeTppNotFound}),
+			verify.Artifacts{Certificate: leaf, Chain: chain, OcspResponse: ocspResponse(ocsp.Good)}, at, Indeterminate, SubSigConstraintsFailure, 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &verify.VerifyResponse{Valid: tt.valid, Checks: tt.checks, VerifiedAt: tt.at}
+			report := build(res, tt.artifacts, "tppVerifier")
+			status := report.SignatureValidationReport.SignatureValidationStatus
+			if status.MainIndication != tt.main || status.SubIndication != tt.sub {
+				t.Errorf("Expected %s %s, got %s %s", tt.main, tt.sub, status.MainIndication, status.SubIndication)
+			}
+			if n := len(report.SignatureValidationObjects.ValidationObject); n != tt.objects {
+				t.Errorf("Expected %d validation objects, got %d", tt.objects, n)
+			}
+			signer := report.SignatureValidationReport.SignerInformation
+			if signer == nil || signer.SignerCertificate.VOReference != report.SignatureValidationObjects.ValidationObject[0].Id {
+				t.Errorf("Expected the leaf as the signer certificate, got %+v", signer)
+			}
+			if len(report.SignatureValidationReport.ValidationConstraintsEvaluationReport.ValidationConstraint) != len(tt.checks) {
+				t.Errorf("Expected a constraint per check, got %+v", report.SignatureValidationReport.ValidationConstraintsEvaluationReport)
+			}
+			data := status.AssociatedValidationReportData
+			if tt.artifacts.Chain != nil && (data == nil || data.TrustAnchor == nil || len(data.CertificateChain.IntermediateCertificate) != 1) {
+				t.Errorf("Expected the chain with its trust anchor, got %+v", data)
+			}
+			if tt.revokedAt != (data != nil && data.RevocationStatusInformation != nil && data.RevocationStatusInformation.RevocationTime != nil) {
+				t.Errorf("Expected revocation time %v, got %+v", tt.revokedAt, data)
+			}
+
+			out, err := report.Marshal()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !strings.HasPrefix(string(out), xml.Header) || !strings.Contains(string(out), `<ValidationReport xmlns="`+Namespace+`">`) {
+				t.Errorf("Expected an XML document in the TS 119 102-2 namespace, got %s", out)
+			}
+			var parsed ValidationReport
+			if err := xml.Unmarshal(out, &parsed); err != nil {
+				t.Fatalf("Expected the report to parse, got %v", err)
+			}
+			der, err := base64.StdEncoding.DecodeString(parsed.SignatureValidationObjects.ValidationObject[0].ValidationObjectRepresentation.Base64)
+			if err != nil || !leaf.Equal(mustParse(t, der)) {
+				t.Errorf("Expected the base64 encoded leaf certificate, got %v", err)
+			}
+		})
+	}
+}
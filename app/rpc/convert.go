@@ -0,0 +1,179 @@
+package rpc
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/rpc/pb"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+// verifyRequest converts a request. As in the v2 HTTP API, a certificate the registry
+// grants no scopes fails the scope check instead of the call.
+func verifyRequest(req *pb.VerifyRequest) verify.VerifyRequest {
+	r := verify.VerifyRequest{
+		Cert:            req.GetCert(),
+		Chain:           req.GetChain(),
+		RegistryCheck:   req.RegistryCheck,
+		AspspCountry:    req.GetAspspCountry(),
+		NoCache:         req.GetNoCache(),
+		ExpectedUsage:   models.CertUsage(req.GetExpectedUsage()),
+		Receipt:         req.GetReceipt(),
+		NoScopesAsCheck: true,
+	}
+	if req.At != nil {
+		at := req.At.AsTime()
+		r.At = &at
+	}
+	return r
+}
+
+func verifyResponse(res *verify.VerifyResponse) *pb.VerifyResponse {
+	if res == nil {
+		return nil
+	}
+	out := &pb.VerifyResponse{
+		Id:         res.Id,
+		Cert:       certificateResponse(res.Certificate),
+		Tpp:        tppResponse(res.TPP),
+		Valid:      res.Valid,
+		Scopes:     make(map[string]*pb.Services, len(res.Scopes)),
+		Reason:     res.Reason,
+		Checks:     make([]*pb.Check, len(res.Checks)),
+		VerifiedAt: timestamp(res.VerifiedAt),
+		Cached:     res.Cached,
+		Policy:     res.Policy,
+		DataVersions: &pb.DataVersions{
+			Roots:    res.DataVersions.Roots,
+			Registry: optionalTimestamp(res.DataVersions.Registry),
+		},
+		Receipt: res.Receipt,
+	}
+	for country, services := range res.Scopes {
+		out.Scopes[country] = &pb.Services{Services: services}
+	}
+	for i, check := range res.Checks {
+		out.Checks[i] = &pb.Check{
+			Name:    string(check.Name),
+			Status:  string(check.Status),
+			Code:    string(check.Code),
+			Details: check.Details,
+			Score:   check.Score,
+		}
+	}
+	return out
+}
+
+func certificateResponse(crt *models.CertificateResponse) *pb.CertificateResponse {
+	if crt == nil {
+		return nil
+	}
+	out := &pb.CertificateResponse{
+		Sha256:       crt.Sha256,
+		Expired:      crt.Expired,
+		Scopes:       make([]string, len(crt.Scopes)),
+		SerialNumber: crt.SerialNumber,
+		Issuer:       name(crt.Issuer),
+		Subject:      name(crt.Subject),
+		NotBefore:    timestamp(crt.ValidFrom),
+		NotAfter:     timestamp(crt.ValidUntil),
+		Usage:        string(crt.Usage),
+	}
+	for i, scope := range crt.Scopes {
+		out.Scopes[i] = string(scope)
+	}
+	return out
+}
+
+// name converts the attributes of an issuer or subject, see cert.CertificateResponseAt.
+func name(attrs map[string]any) *pb.Name {
+	strs := func(key string) []string {
+		v, _ := attrs[key].([]string)
+		return v
+	}
+	str := func(key string) string {
+		v, _ := attrs[key].(string)
+		return v
+	}
+	return &pb.Name{
+		Country:                strs("country"),
+		Organization:           strs("organization"),
+		OrganizationalUnit:     strs("organizational_unit"),
+		Locality:               strs("locality"),
+		Province:               strs("province"),
+		Street:                 strs("street"),
+		PostalCode:             strs("postal_code"),
+		CommonName:             str("common_name"),
+		SerialNumber:           str("serial_number"),
+		OrganizationIdentifier: str("organization_identifier"),
+	}
+}
+
+func tppResponse(tpp *models.TppResponse) *pb.TppResponse {
+	if tpp == nil {
+		return nil
+	}
+	return &pb.TppResponse{
+		Id:                  tpp.Id,
+		NameLatin:           tpp.NameLatin,
+		NameNative:          tpp.NameNative,
+		Authority:           tpp.Authority,
+		Services:            services(tpp.Services),
+		Country:             tpp.Country,
+		AuthorizationStatus: string(tpp.AuthorizationStatus),
+		AuthorizedAt:        optionalTimestamp(tpp.AuthorizedAt),
+		WithdrawnAt:         optionalTimestamp(tpp.WithdrawnAt),
+		UpdatedAt:           optionalTimestamp(tpp.UpdatedAt),
+	}
+}
+
+func tppRecord(tpp *verify.TppRecord) *pb.TppRecord {
+	if tpp == nil {
+		return nil
+	}
+	return &pb.TppRecord{
+		Id:                  tpp.Id,
+		ObId:                tpp.OBID,
+		NameLatin:           tpp.NameLatin,
+		NameNative:          tpp.NameNative,
+		Authority:           tpp.Authority,
+		Country:             tpp.Country,
+		Services:            services(tpp.Services),
+		Type:                tpp.Type,
+		AuthorizedAt:        optionalTimestamp(tpp.AuthorizedAt),
+		WithdrawnAt:         optionalTimestamp(tpp.WithdrawnAt),
+		CreatedAt:           timestamp(tpp.CreatedAt),
+		UpdatedAt:           timestamp(tpp.UpdatedAt),
+		Registry:            tpp.Registry,
+		AuthorizationStatus: string(tpp.AuthorizationStatus),
+	}
+}
+
+func services(m map[string][]models.Service) map[string]*pb.Services {
+	out := make(map[string]*pb.Services, len(m))
+	for country, services := range m {
+		s := &pb.Services{Services: make([]string, len(services))}
+		for i, service := range services {
+			s.Services[i] = string(service)
+		}
+		out[country] = s
+	}
+	return out
+}
+
+// timestamp converts a time, leaving the zero time unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamp(*t)
+}
@@ -0,0 +1,1573 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: verifier.proto
+
+// The messages mirror the JSON of the HTTP API: VerifyResponse, CertificateResponse and
+// TppResponse have the fields of their JSON counterparts, with times as timestamps.
+// Codes and statuses are strings as in JSON, so that new check codes need no new version.
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type VerifyRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The certificate in PEM or base64 DER, or a PEM or PKCS7 bundle with its intermediates.
+	Cert  string   `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
+	Chain []string `protobuf:"bytes,2,rep,name=chain,proto3" json:"chain,omitempty"`
+	// Defaults to true.
+	RegistryCheck *bool                  `protobuf:"varint,3,opt,name=registry_check,json=registryCheck,proto3,oneof" json:"registry_check,omitempty"`
+	AspspCountry  string                 `protobuf:"bytes,4,opt,name=aspsp_country,json=aspspCountry,proto3" json:"aspsp_country,omitempty"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=at,proto3" json:"at,omitempty"`
+	NoCache       bool                   `protobuf:"varint,6,opt,name=no_cache,json=noCache,proto3" json:"no_cache,omitempty"`
+	// QWAC or QSEAL.
+	ExpectedUsage string `protobuf:"bytes,7,opt,name=expected_usage,json=expectedUsage,proto3" json:"expected_usage,omitempty"`
+	Receipt       bool   `protobuf:"varint,8,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyRequest) Reset() {
+	*x = VerifyRequest{}
+	mi := &file_verifier_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRequest) ProtoMessage() {}
+
+func (x *VerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *VerifyRequest) GetCert() string {
+	if x != nil {
+		return x.Cert
+	}
+	return ""
+}
+
+func (x *VerifyRequest) GetChain() []string {
+	if x != nil {
+		return x.Chain
+	}
+	return nil
+}
+
+func (x *VerifyRequest) GetRegistryCheck() bool {
+	if x != nil && x.RegistryCheck != nil {
+		return *x.RegistryCheck
+	}
+	return false
+}
+
+func (x *VerifyRequest) GetAspspCountry() string {
+	if x != nil {
+		return x.AspspCountry
+	}
+	return ""
+}
+
+func (x *VerifyRequest) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *VerifyRequest) GetNoCache() bool {
+	if x != nil {
+		return x.NoCache
+	}
+	return false
+}
+
+func (x *VerifyRequest) GetExpectedUsage() string {
+	if x != nil {
+		return x.ExpectedUsage
+	}
+	return ""
+}
+
+func (x *VerifyRequest) GetReceipt() bool {
+	if x != nil {
+		return x.Receipt
+	}
+	return false
+}
+
+type VerifyResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Cert  *CertificateResponse   `protobuf:"bytes,2,opt,name=cert,proto3" json:"cert,omitempty"`
+	Tpp   *TppResponse           `protobuf:"bytes,3,opt,name=tpp,proto3" json:"tpp,omitempty"`
+	// A certificate the registry grants no scopes is not an error but fails the scope check with NO_SCOPES.
+	Valid bool `protobuf:"varint,4,opt,name=valid,proto3" json:"valid,omitempty"`
+	// Maps the countries to the services the TPP may use there.
+	Scopes        map[string]*Services   `protobuf:"bytes,5,rep,name=scopes,proto3" json:"scopes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Checks        []*Check               `protobuf:"bytes,7,rep,name=checks,proto3" json:"checks,omitempty"`
+	VerifiedAt    *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=verified_at,json=verifiedAt,proto3" json:"verified_at,omitempty"`
+	Cached        bool                   `protobuf:"varint,9,opt,name=cached,proto3" json:"cached,omitempty"`
+	Policy        string                 `protobuf:"bytes,10,opt,name=policy,proto3" json:"policy,omitempty"`
+	DataVersions  *DataVersions          `protobuf:"bytes,11,opt,name=data_versions,json=dataVersions,proto3" json:"data_versions,omitempty"`
+	Receipt       string                 `protobuf:"bytes,12,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyResponse) Reset() {
+	*x = VerifyResponse{}
+	mi := &file_verifier_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyResponse) ProtoMessage() {}
+
+func (x *VerifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyResponse.ProtoReflect.Descriptor instead.
+func (*VerifyResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VerifyResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *VerifyResponse) GetCert() *CertificateResponse {
+	if x != nil {
+		return x.Cert
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetTpp() *TppResponse {
+	if x != nil {
+		return x.Tpp
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *VerifyResponse) GetScopes() map[string]*Services {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *VerifyResponse) GetChecks() []*Check {
+	if x != nil {
+		return x.Checks
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetVerifiedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.VerifiedAt
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *VerifyResponse) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *VerifyResponse) GetDataVersions() *DataVersions {
+	if x != nil {
+		return x.DataVersions
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetReceipt() string {
+	if x != nil {
+		return x.Receipt
+	}
+	return ""
+}
+
+type CertificateResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Sha256       string                 `protobuf:"bytes,1,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Expired      bool                   `protobuf:"varint,2,opt,name=expired,proto3" json:"expired,omitempty"`
+	Scopes       []string               `protobuf:"bytes,3,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	SerialNumber string                 `protobuf:"bytes,4,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	Issuer       *Name                  `protobuf:"bytes,5,opt,name=issuer,proto3" json:"issuer,omitempty"`
+	Subject      *Name                  `protobuf:"bytes,6,opt,name=subject,proto3" json:"subject,omitempty"`
+	NotBefore    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"`
+	NotAfter     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"`
+	// QWAC, QSEAL or UNKNOWN.
+	Usage         string `protobuf:"bytes,9,opt,name=usage,proto3" json:"usage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CertificateResponse) Reset() {
+	*x = CertificateResponse{}
+	mi := &file_verifier_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CertificateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CertificateResponse) ProtoMessage() {}
+
+func (x *CertificateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CertificateResponse.ProtoReflect.Descriptor instead.
+func (*CertificateResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CertificateResponse) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *CertificateResponse) GetExpired() bool {
+	if x != nil {
+		return x.Expired
+	}
+	return false
+}
+
+func (x *CertificateResponse) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *CertificateResponse) GetSerialNumber() string {
+	if x != nil {
+		return x.SerialNumber
+	}
+	return ""
+}
+
+func (x *CertificateResponse) GetIssuer() *Name {
+	if x != nil {
+		return x.Issuer
+	}
+	return nil
+}
+
+func (x *CertificateResponse) GetSubject() *Name {
+	if x != nil {
+		return x.Subject
+	}
+	return nil
+}
+
+func (x *CertificateResponse) GetNotBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NotBefore
+	}
+	return nil
+}
+
+func (x *CertificateResponse) GetNotAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NotAfter
+	}
+	return nil
+}
+
+func (x *CertificateResponse) GetUsage() string {
+	if x != nil {
+		return x.Usage
+	}
+	return ""
+}
+
+// Name holds the attributes of a certificate issuer or subject.
+type Name struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Country                []string               `protobuf:"bytes,1,rep,name=country,proto3" json:"country,omitempty"`
+	Organization           []string               `protobuf:"bytes,2,rep,name=organization,proto3" json:"organization,omitempty"`
+	OrganizationalUnit     []string               `protobuf:"bytes,3,rep,name=organizational_unit,json=organizationalUnit,proto3" json:"organizational_unit,omitempty"`
+	Locality               []string               `protobuf:"bytes,4,rep,name=locality,proto3" json:"locality,omitempty"`
+	Province               []string               `protobuf:"bytes,5,rep,name=province,proto3" json:"province,omitempty"`
+	Street                 []string               `protobuf:"bytes,6,rep,name=street,proto3" json:"street,omitempty"`
+	PostalCode             []string               `protobuf:"bytes,7,rep,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	CommonName             string                 `protobuf:"bytes,8,opt,name=common_name,json=commonName,proto3" json:"common_name,omitempty"`
+	SerialNumber           string                 `protobuf:"bytes,9,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	OrganizationIdentifier string                 `protobuf:"bytes,10,opt,name=organization_identifier,json=organizationIdentifier,proto3" json:"organization_identifier,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *Name) Reset() {
+	*x = Name{}
+	mi := &file_verifier_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Name) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Name) ProtoMessage() {}
+
+func (x *Name) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Name.ProtoReflect.Descriptor instead.
+func (*Name) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Name) GetCountry() []string {
+	if x != nil {
+		return x.Country
+	}
+	return nil
+}
+
+func (x *Name) GetOrganization() []string {
+	if x != nil {
+		return x.Organization
+	}
+	return nil
+}
+
+func (x *Name) GetOrganizationalUnit() []string {
+	if x != nil {
+		return x.OrganizationalUnit
+	}
+	return nil
+}
+
+func (x *Name) GetLocality() []string {
+	if x != nil {
+		return x.Locality
+	}
+	return nil
+}
+
+func (x *Name) GetProvince() []string {
+	if x != nil {
+		return x.Province
+	}
+	return nil
+}
+
+func (x *Name) GetStreet() []string {
+	if x != nil {
+		return x.Street
+	}
+	return nil
+}
+
+func (x *Name) GetPostalCode() []string {
+	if x != nil {
+		return x.PostalCode
+	}
+	return nil
+}
+
+func (x *Name) GetCommonName() string {
+	if x != nil {
+		return x.CommonName
+	}
+	return ""
+}
+
+func (x *Name) GetSerialNumber() string {
+	if x != nil {
+		return x.SerialNumber
+	}
+	return ""
+}
+
+func (x *Name) GetOrganizationIdentifier() string {
+	if x != nil {
+		return x.OrganizationIdentifier
+	}
+	return ""
+}
+
+type TppResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	NameLatin  string                 `protobuf:"bytes,2,opt,name=name_latin,json=nameLatin,proto3" json:"name_latin,omitempty"`
+	NameNative string                 `protobuf:"bytes,3,opt,name=name_native,json=nameNative,proto3" json:"name_native,omitempty"`
+	Authority  string                 `protobuf:"bytes,4,opt,name=authority,proto3" json:"authority,omitempty"`
+	Services   map[string]*Services   `protobuf:"bytes,5,rep,name=services,proto3" json:"services,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Country    string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	// AUTHORIZED, WITHDRAWN or NOT_YET_AUTHORIZED.
+	AuthorizationStatus string                 `protobuf:"bytes,7,opt,name=authorization_status,json=authorizationStatus,proto3" json:"authorization_status,omitempty"`
+	AuthorizedAt        *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=authorized_at,json=authorizedAt,proto3" json:"authorized_at,omitempty"`
+	WithdrawnAt         *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=withdrawn_at,json=withdrawnAt,proto3" json:"withdrawn_at,omitempty"`
+	UpdatedAt           *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *TppResponse) Reset() {
+	*x = TppResponse{}
+	mi := &file_verifier_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TppResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TppResponse) ProtoMessage() {}
+
+func (x *TppResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TppResponse.ProtoReflect.Descriptor instead.
+func (*TppResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TppResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TppResponse) GetNameLatin() string {
+	if x != nil {
+		return x.NameLatin
+	}
+	return ""
+}
+
+func (x *TppResponse) GetNameNative() string {
+	if x != nil {
+		return x.NameNative
+	}
+	return ""
+}
+
+func (x *TppResponse) GetAuthority() string {
+	if x != nil {
+		return x.Authority
+	}
+	return ""
+}
+
+func (x *TppResponse) GetServices() map[string]*Services {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+func (x *TppResponse) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *TppResponse) GetAuthorizationStatus() string {
+	if x != nil {
+		return x.AuthorizationStatus
+	}
+	return ""
+}
+
+func (x *TppResponse) GetAuthorizedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AuthorizedAt
+	}
+	return nil
+}
+
+func (x *TppResponse) GetWithdrawnAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.WithdrawnAt
+	}
+	return nil
+}
+
+func (x *TppResponse) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type Services struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// AIS or PIS.
+	Services      []string `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Services) Reset() {
+	*x = Services{}
+	mi := &file_verifier_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Services) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Services) ProtoMessage() {}
+
+func (x *Services) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Services.ProtoReflect.Descriptor instead.
+func (*Services) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Services) GetServices() []string {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+type Check struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// pass, fail, warning, skipped or error.
+	Status        string   `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Code          string   `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	Details       string   `protobuf:"bytes,4,opt,name=details,proto3" json:"details,omitempty"`
+	Score         *float64 `protobuf:"fixed64,5,opt,name=score,proto3,oneof" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Check) Reset() {
+	*x = Check{}
+	mi := &file_verifier_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Check) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Check) ProtoMessage() {}
+
+func (x *Check) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Check.ProtoReflect.Descriptor instead.
+func (*Check) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Check) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Check) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Check) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Check) GetDetails() string {
+	if x != nil {
+		return x.Details
+	}
+	return ""
+}
+
+func (x *Check) GetScore() float64 {
+	if x != nil && x.Score != nil {
+		return *x.Score
+	}
+	return 0
+}
+
+type DataVersions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Roots         string                 `protobuf:"bytes,1,opt,name=roots,proto3" json:"roots,omitempty"`
+	Registry      *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=registry,proto3" json:"registry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DataVersions) Reset() {
+	*x = DataVersions{}
+	mi := &file_verifier_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DataVersions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DataVersions) ProtoMessage() {}
+
+func (x *DataVersions) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DataVersions.ProtoReflect.Descriptor instead.
+func (*DataVersions) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DataVersions) GetRoots() string {
+	if x != nil {
+		return x.Roots
+	}
+	return ""
+}
+
+func (x *DataVersions) GetRegistry() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Registry
+	}
+	return nil
+}
+
+// Error is the error of a batch item or an identifier.
+type Error struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The google.rpc.Code the error would have as the error of a call.
+	Code          int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_verifier_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Error) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type BatchVerifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*VerifyRequest       `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchVerifyRequest) Reset() {
+	*x = BatchVerifyRequest{}
+	mi := &file_verifier_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchVerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchVerifyRequest) ProtoMessage() {}
+
+func (x *BatchVerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchVerifyRequest.ProtoReflect.Descriptor instead.
+func (*BatchVerifyRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BatchVerifyRequest) GetItems() []*VerifyRequest {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type BatchVerifyItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Result        *VerifyResponse        `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	Error         *Error                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchVerifyItem) Reset() {
+	*x = BatchVerifyItem{}
+	mi := &file_verifier_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchVerifyItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchVerifyItem) ProtoMessage() {}
+
+func (x *BatchVerifyItem) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchVerifyItem.ProtoReflect.Descriptor instead.
+func (*BatchVerifyItem) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BatchVerifyItem) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BatchVerifyItem) GetResult() *VerifyResponse {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *BatchVerifyItem) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type BatchVerifyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*BatchVerifyItem     `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchVerifyResponse) Reset() {
+	*x = BatchVerifyResponse{}
+	mi := &file_verifier_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchVerifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchVerifyResponse) ProtoMessage() {}
+
+func (x *BatchVerifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchVerifyResponse.ProtoReflect.Descriptor instead.
+func (*BatchVerifyResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BatchVerifyResponse) GetResults() []*BatchVerifyItem {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type LookupTppRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupTppRequest) Reset() {
+	*x = LookupTppRequest{}
+	mi := &file_verifier_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupTppRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupTppRequest) ProtoMessage() {}
+
+func (x *LookupTppRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupTppRequest.ProtoReflect.Descriptor instead.
+func (*LookupTppRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *LookupTppRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// TppRecord is the registry record of a TPP with its authorization status at lookup time.
+type TppRecord struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The EBA entity code.
+	Id                  string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ObId                string                 `protobuf:"bytes,2,opt,name=ob_id,json=obId,proto3" json:"ob_id,omitempty"`
+	NameLatin           string                 `protobuf:"bytes,3,opt,name=name_latin,json=nameLatin,proto3" json:"name_latin,omitempty"`
+	NameNative          string                 `protobuf:"bytes,4,opt,name=name_native,json=nameNative,proto3" json:"name_native,omitempty"`
+	Authority           string                 `protobuf:"bytes,5,opt,name=authority,proto3" json:"authority,omitempty"`
+	Country             string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	Services            map[string]*Services   `protobuf:"bytes,7,rep,name=services,proto3" json:"services,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Type                string                 `protobuf:"bytes,8,opt,name=type,proto3" json:"type,omitempty"`
+	AuthorizedAt        *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=authorized_at,json=authorizedAt,proto3" json:"authorized_at,omitempty"`
+	WithdrawnAt         *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=withdrawn_at,json=withdrawnAt,proto3" json:"withdrawn_at,omitempty"`
+	CreatedAt           *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt           *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Registry            string                 `protobuf:"bytes,13,opt,name=registry,proto3" json:"registry,omitempty"`
+	AuthorizationStatus string                 `protobuf:"bytes,14,opt,name=authorization_status,json=authorizationStatus,proto3" json:"authorization_status,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *TppRecord) Reset() {
+	*x = TppRecord{}
+	mi := &file_verifier_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TppRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TppRecord) ProtoMessage() {}
+
+func (x *TppRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TppRecord.ProtoReflect.Descriptor instead.
+func (*TppRecord) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TppRecord) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TppRecord) GetObId() string {
+	if x != nil {
+		return x.ObId
+	}
+	return ""
+}
+
+func (x *TppRecord) GetNameLatin() string {
+	if x != nil {
+		return x.NameLatin
+	}
+	return ""
+}
+
+func (x *TppRecord) GetNameNative() string {
+	if x != nil {
+		return x.NameNative
+	}
+	return ""
+}
+
+func (x *TppRecord) GetAuthority() string {
+	if x != nil {
+		return x.Authority
+	}
+	return ""
+}
+
+func (x *TppRecord) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *TppRecord) GetServices() map[string]*Services {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+func (x *TppRecord) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TppRecord) GetAuthorizedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AuthorizedAt
+	}
+	return nil
+}
+
+func (x *TppRecord) GetWithdrawnAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.WithdrawnAt
+	}
+	return nil
+}
+
+func (x *TppRecord) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *TppRecord) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *TppRecord) GetRegistry() string {
+	if x != nil {
+		return x.Registry
+	}
+	return ""
+}
+
+func (x *TppRecord) GetAuthorizationStatus() string {
+	if x != nil {
+		return x.AuthorizationStatus
+	}
+	return ""
+}
+
+type LookupTppsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupTppsRequest) Reset() {
+	*x = LookupTppsRequest{}
+	mi := &file_verifier_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupTppsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupTppsRequest) ProtoMessage() {}
+
+func (x *LookupTppsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupTppsRequest.ProtoReflect.Descriptor instead.
+func (*LookupTppsRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *LookupTppsRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type LookupTppItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Tpp           *TppRecord             `protobuf:"bytes,2,opt,name=tpp,proto3" json:"tpp,omitempty"`
+	Error         *Error                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupTppItem) Reset() {
+	*x = LookupTppItem{}
+	mi := &file_verifier_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupTppItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupTppItem) ProtoMessage() {}
+
+func (x *LookupTppItem) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupTppItem.ProtoReflect.Descriptor instead.
+func (*LookupTppItem) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *LookupTppItem) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LookupTppItem) GetTpp() *TppRecord {
+	if x != nil {
+		return x.Tpp
+	}
+	return nil
+}
+
+func (x *LookupTppItem) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type LookupTppsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*LookupTppItem       `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupTppsResponse) Reset() {
+	*x = LookupTppsResponse{}
+	mi := &file_verifier_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupTppsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupTppsResponse) ProtoMessage() {}
+
+func (x *LookupTppsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupTppsResponse.ProtoReflect.Descriptor instead.
+func (*LookupTppsResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *LookupTppsResponse) GetResults() []*LookupTppItem {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_verifier_proto protoreflect.FileDescriptor
+
+const file_verifier_proto_rawDesc = "" +
+	"\n" +
+	"\x0everifier.proto\x12\x0etppverifier.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa5\x02\n" +
+	"\rVerifyRequest\x12\x12\n" +
+	"\x04cert\x18\x01 \x01(\tR\x04cert\x12\x14\n" +
+	"\x05chain\x18\x02 \x03(\tR\x05chain\x12*\n" +
+	"\x0eregistry_check\x18\x03 \x01(\bH\x00R\rregistryCheck\x88\x01\x01\x12#\n" +
+	"\raspsp_country\x18\x04 \x01(\tR\faspspCountry\x12*\n" +
+	"\x02at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\x12\x19\n" +
+	"\bno_cache\x18\x06 \x01(\bR\anoCache\x12%\n" +
+	"\x0eexpected_usage\x18\a \x01(\tR\rexpectedUsage\x12\x18\n" +
+	"\areceipt\x18\b \x01(\bR\areceiptB\x11\n" +
+	"\x0f_registry_check\"\xc8\x04\n" +
+	"\x0eVerifyResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x127\n" +
+	"\x04cert\x18\x02 \x01(\v2#.tppverifier.v1.CertificateResponseR\x04cert\x12-\n" +
+	"\x03tpp\x18\x03 \x01(\v2\x1b.tppverifier.v1.TppResponseR\x03tpp\x12\x14\n" +
+	"\x05valid\x18\x04 \x01(\bR\x05valid\x12B\n" +
+	"\x06scopes\x18\x05 \x03(\v2*.tppverifier.v1.VerifyResponse.ScopesEntryR\x06scopes\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12-\n" +
+	"\x06checks\x18\a \x03(\v2\x15.tppverifier.v1.CheckR\x06checks\x12;\n" +
+	"\vverified_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"verifiedAt\x12\x16\n" +
+	"\x06cached\x18\t \x01(\bR\x06cached\x12\x16\n" +
+	"\x06policy\x18\n" +
+	" \x01(\tR\x06policy\x12A\n" +
+	"\rdata_versions\x18\v \x01(\v2\x1c.tppverifier.v1.DataVersionsR\fdataVersions\x12\x18\n" +
+	"\areceipt\x18\f \x01(\tR\areceipt\x1aS\n" +
+	"\vScopesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x05value\x18\x02 \x01(\v2\x18.tppverifier.v1.ServicesR\x05value:\x028\x01\"\xec\x02\n" +
+	"\x13CertificateResponse\x12\x16\n" +
+	"\x06sha256\x18\x01 \x01(\tR\x06sha256\x12\x18\n" +
+	"\aexpired\x18\x02 \x01(\bR\aexpired\x12\x16\n" +
+	"\x06scopes\x18\x03 \x03(\tR\x06scopes\x12#\n" +
+	"\rserial_number\x18\x04 \x01(\tR\fserialNumber\x12,\n" +
+	"\x06issuer\x18\x05 \x01(\v2\x14.tppverifier.v1.NameR\x06issuer\x12.\n" +
+	"\asubject\x18\x06 \x01(\v2\x14.tppverifier.v1.NameR\asubject\x129\n" +
+	"\n" +
+	"not_before\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tnotBefore\x127\n" +
+	"\tnot_after\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\bnotAfter\x12\x14\n" +
+	"\x05usage\x18\t \x01(\tR\x05usage\"\xe5\x02\n" +
+	"\x04Name\x12\x18\n" +
+	"\acountry\x18\x01 \x03(\tR\acountry\x12\"\n" +
+	"\forganization\x18\x02 \x03(\tR\forganization\x12/\n" +
+	"\x13organizational_unit\x18\x03 \x03(\tR\x12organizationalUnit\x12\x1a\n" +
+	"\blocality\x18\x04 \x03(\tR\blocality\x12\x1a\n" +
+	"\bprovince\x18\x05 \x03(\tR\bprovince\x12\x16\n" +
+	"\x06street\x18\x06 \x03(\tR\x06street\x12\x1f\n" +
+	"\vpostal_code\x18\a \x03(\tR\n" +
+	"postalCode\x12\x1f\n" +
+	"\vcommon_name\x18\b \x01(\tR\n" +
+	"commonName\x12#\n" +
+	"\rserial_number\x18\t \x01(\tR\fserialNumber\x127\n" +
+	"\x17organization_identifier\x18\n" +
+	" \x01(\tR\x16organizationIdentifier\"\xa1\x04\n" +
+	"\vTppResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"name_latin\x18\x02 \x01(\tR\tnameLatin\x12\x1f\n" +
+	"\vname_native\x18\x03 \x01(\tR\n" +
+	"nameNative\x12\x1c\n" +
+	"\tauthority\x18\x04 \x01(\tR\tauthority\x12E\n" +
+	"\bservices\x18\x05 \x03(\v2).tppverifier.v1.TppResponse.ServicesEntryR\bservices\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x121\n" +
+	"\x14authorization_status\x18\a \x01(\tR\x13authorizationStatus\x12?\n" +
+	"\rauthorized_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\fauthorizedAt\x12=\n" +
+	"\fwithdrawn_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\vwithdrawnAt\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x1aU\n" +
+	"\rServicesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x05value\x18\x02 \x01(\v2\x18.tppverifier.v1.ServicesR\x05value:\x028\x01\"&\n" +
+	"\bServices\x12\x1a\n" +
+	"\bservices\x18\x01 \x03(\tR\bservices\"\x86\x01\n" +
+	"\x05Check\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x12\x18\n" +
+	"\adetails\x18\x04 \x01(\tR\adetails\x12\x19\n" +
+	"\x05score\x18\x05 \x01(\x01H\x00R\x05score\x88\x01\x01B\b\n" +
+	"\x06_score\"\\\n" +
+	"\fDataVersions\x12\x14\n" +
+	"\x05roots\x18\x01 \x01(\tR\x05roots\x126\n" +
+	"\bregistry\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\bregistry\"5\n" +
+	"\x05Error\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"I\n" +
+	"\x12BatchVerifyRequest\x123\n" +
+	"\x05items\x18\x01 \x03(\v2\x1d.tppverifier.v1.VerifyRequestR\x05items\"\x8c\x01\n" +
+	"\x0fBatchVerifyItem\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x126\n" +
+	"\x06result\x18\x02 \x01(\v2\x1e.tppverifier.v1.VerifyResponseR\x06result\x12+\n" +
+	"\x05error\x18\x03 \x01(\v2\x15.tppverifier.v1.ErrorR\x05error\"P\n" +
+	"\x13BatchVerifyResponse\x129\n" +
+	"\aresults\x18\x01 \x03(\v2\x1f.tppverifier.v1.BatchVerifyItemR\aresults\"\"\n" +
+	"\x10LookupTppRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x9d\x05\n" +
+	"\tTppRecord\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x13\n" +
+	"\x05ob_id\x18\x02 \x01(\tR\x04obId\x12\x1d\n" +
+	"\n" +
+	"name_latin\x18\x03 \x01(\tR\tnameLatin\x12\x1f\n" +
+	"\vname_native\x18\x04 \x01(\tR\n" +
+	"nameNative\x12\x1c\n" +
+	"\tauthority\x18\x05 \x01(\tR\tauthority\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x12C\n" +
+	"\bservices\x18\a \x03(\v2'.tppverifier.v1.TppRecord.ServicesEntryR\bservices\x12\x12\n" +
+	"\x04type\x18\b \x01(\tR\x04type\x12?\n" +
+	"\rauthorized_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\fauthorizedAt\x12=\n" +
+	"\fwithdrawn_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\vwithdrawnAt\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x1a\n" +
+	"\bregistry\x18\r \x01(\tR\bregistry\x121\n" +
+	"\x14authorization_status\x18\x0e \x01(\tR\x13authorizationStatus\x1aU\n" +
+	"\rServicesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x05value\x18\x02 \x01(\v2\x18.tppverifier.v1.ServicesR\x05value:\x028\x01\"%\n" +
+	"\x11LookupTppsRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"y\n" +
+	"\rLookupTppItem\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12+\n" +
+	"\x03tpp\x18\x02 \x01(\v2\x19.tppverifier.v1.TppRecordR\x03tpp\x12+\n" +
+	"\x05error\x18\x03 \x01(\v2\x15.tppverifier.v1.ErrorR\x05error\"M\n" +
+	"\x12LookupTppsResponse\x127\n" +
+	"\aresults\x18\x01 \x03(\v2\x1d.tppverifier.v1.LookupTppItemR\aresults2\xcd\x02\n" +
+	"\vTppVerifier\x12G\n" +
+	"\x06Verify\x12\x1d.tppverifier.v1.VerifyRequest\x1a\x1e.tppverifier.v1.VerifyResponse\x12V\n" +
+	"\vVerifyBatch\x12\".tppverifier.v1.BatchVerifyRequest\x1a#.tppverifier.v1.BatchVerifyResponse\x12H\n" +
+	"\tLookupTpp\x12 .tppverifier.v1.LookupTppRequest\x1a\x19.tppverifier.v1.TppRecord\x12S\n" +
+	"\n" +
+	"LookupTpps\x12!.tppverifier.v1.LookupTppsRequest\x1a\".tppverifier.v1.LookupTppsResponseB+Z)github.com/botsman/tppVerifier/app/rpc/pbb\x06proto3"
+
+var (
+	file_verifier_proto_rawDescOnce sync.Once
+	file_verifier_proto_rawDescData []byte
+)
+
+func file_verifier_proto_rawDescGZIP() []byte {
+	file_verifier_proto_rawDescOnce.Do(func() {
+		file_verifier_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_verifier_proto_rawDesc), len(file_verifier_proto_rawDesc)))
+	})
+	return file_verifier_proto_rawDescData
+}
+
+var file_verifier_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_verifier_proto_goTypes = []any{
+	(*VerifyRequest)(nil),         // 0: tppverifier.v1.VerifyRequest
+	(*VerifyResponse)(nil),        // 1: tppverifier.v1.VerifyResponse
+	(*CertificateResponse)(nil),   // 2: tppverifier.v1.CertificateResponse
+	(*Name)(nil),                  // 3: tppverifier.v1.Name
+	(*TppResponse)(nil),           // 4: tppverifier.v1.TppResponse
+	(*Services)(nil),              // 5: tppverifier.v1.Services
+	(*Check)(nil),                 // 6: tppverifier.v1.Check
+	(*DataVersions)(nil),          // 7: tppverifier.v1.DataVersions
+	(*Error)(nil),                 // 8: tppverifier.v1.Error
+	(*BatchVerifyRequest)(nil),    // 9: tppverifier.v1.BatchVerifyRequest
+	(*BatchVerifyItem)(nil),       // 10: tppverifier.v1.BatchVerifyItem
+	(*BatchVerifyResponse)(nil),   // 11: tppverifier.v1.BatchVerifyResponse
+	(*LookupTppRequest)(nil),      // 12: tppverifier.v1.LookupTppRequest
+	(*TppRecord)(nil),             // 13: tppverifier.v1.TppRecord
+	(*LookupTppsRequest)(nil),     // 14: tppverifier.v1.LookupTppsRequest
+	(*LookupTppItem)(nil),         // 15: tppverifier.v1.LookupTppItem
+	(*LookupTppsResponse)(nil),    // 16: tppverifier.v1.LookupTppsResponse
+	nil,                           // 17: tppverifier.v1.VerifyResponse.ScopesEntry
+	nil,                           // 18: tppverifier.v1.TppResponse.ServicesEntry
+	nil,                           // 19: tppverifier.v1.TppRecord.ServicesEntry
+	(*timestamppb.Timestamp)(nil), // 20: google.protobuf.Timestamp
+}
+var file_verifier_proto_depIdxs = []int32{
+	20, // 0: tppverifier.v1.VerifyRequest.at:type_name -> google.protobuf.Timestamp
+	2,  // 1: tppverifier.v1.VerifyResponse.cert:type_name -> tppverifier.v1.CertificateResponse
+	4,  // 2: tppverifier.v1.VerifyResponse.tpp:type_name -> tppverifier.v1.TppResponse
+	17, // 3: tppverifier.v1.VerifyResponse.scopes:type_name -> tppverifier.v1.VerifyResponse.ScopesEntry
+	6,  // 4: tppverifier.v1.VerifyResponse.checks:type_name -> tppverifier.v1.Check
+	20, // 5: tppverifier.v1.VerifyResponse.verified_at:type_name -> google.protobuf.Timestamp
+	7,  // 6: tppverifier.v1.VerifyResponse.data_versions:type_name -> tppverifier.v1.DataVersions
+	3,  // 7: tppverifier.v1.CertificateResponse.issuer:type_name -> tppverifier.v1.Name
+	3,  // 8: tppverifier.v1.CertificateResponse.subject:type_name -> tppverifier.v1.Name
+	20, // 9: tppverifier.v1.CertificateResponse.not_before:type_name -> google.protobuf.Timestamp
+	20, // 10: tppverifier.v1.CertificateResponse.not_after:type_name -> google.protobuf.Timestamp
+	18, // 11: tppverifier.v1.TppResponse.services:type_name -> tppverifier.v1.TppResponse.ServicesEntry
+	20, // 12: tppverifier.v1.TppResponse.authorized_at:type_name -> google.protobuf.Timestamp
+	20, // 13: tppverifier.v1.TppResponse.withdrawn_at:type_name -> google.protobuf.Timestamp
+	20, // 14: tppverifier.v1.TppResponse.updated_at:type_name -> google.protobuf.Timestamp
+	20, // 15: tppverifier.v1.DataVersions.registry:type_name -> google.protobuf.Timestamp
+	0,  // 16: tppverifier.v1.BatchVerifyRequest.items:type_name -> tppverifier.v1.VerifyRequest
+	1,  // 17: tppverifier.v1.BatchVerifyItem.result:type_name -> tppverifier.v1.VerifyResponse
+	8,  // 18: tppverifier.v1.BatchVerifyItem.error:type_name -> tppverifier.v1.Error
+	10, // 19: tppverifier.v1.BatchVerifyResponse.results:type_name -> tppverifier.v1.BatchVerifyItem
+	19, // 20: tppverifier.v1.TppRecord.services:type_name -> tppverifier.v1.TppRecord.ServicesEntry
+	20, // 21: tppverifier.v1.TppRecord.authorized_at:type_name -> google.protobuf.Timestamp
+	20, // 22: tppverifier.v1.TppRecord.withdrawn_at:type_name -> google.protobuf.Timestamp
+	20, // 23: tppverifier.v1.TppRecord.created_at:type_name -> google.protobuf.Timestamp
+	20, // 24: tppverifier.v1.TppRecord.updated_at:type_name -> google.protobuf.Timestamp
+	13, // 25: tppverifier.v1.LookupTppItem.tpp:type_name -> tppverifier.v1.TppRecord
+	8,  // 26: tppverifier.v1.LookupTppItem.error:type_name -> tppverifier.v1.Error
+	15, // 27: tppverifier.v1.LookupTppsResponse.results:type_name -> tppverifier.v1.LookupTppItem
+	5,  // 28: tppverifier.v1.VerifyResponse.ScopesEntry.value:type_name -> tppverifier.v1.Services
+	5,  // 29: tppverifier.v1.TppResponse.ServicesEntry.value:type_name -> tppverifier.v1.Services
+	5,  // 30: tppverifier.v1.TppRecord.ServicesEntry.value:type_name -> tppverifier.v1.Services
+	0,  // 31: tppverifier.v1.TppVerifier.Verify:input_type -> tppverifier.v1.VerifyRequest
+	9,  // 32: tppverifier.v1.TppVerifier.VerifyBatch:input_type -> tppverifier.v1.BatchVerifyRequest
+	12, // 33: tppverifier.v1.TppVerifier.LookupTpp:input_type -> tppverifier.v1.LookupTppRequest
+	14, // 34: tppverifier.v1.TppVerifier.LookupTpps:input_type -> tppverifier.v1.LookupTppsRequest
+	1,  // 35: tppverifier.v1.TppVerifier.Verify:output_type -> tppverifier.v1.VerifyResponse
+	11, // 36: tppverifier.v1.TppVerifier.VerifyBatch:output_type -> tppverifier.v1.BatchVerifyResponse
+	13, // 37: tppverifier.v1.TppVerifier.LookupTpp:output_type -> tppverifier.v1.TppRecord
+	16, // 38: tppverifier.v1.TppVerifier.LookupTpps:output_type -> tppverifier.v1.LookupTppsResponse
+	35, // [35:39] is the sub-list for method output_type
+	31, // [31:35] is the sub-list for method input_type
+	31, // [31:31] is the sub-list for extension type_name
+	31, // [31:31] is the sub-list for extension extendee
+	0,  // [0:31] is the sub-list for field type_name
+}
+
+func init() { file_verifier_proto_init() }
+func file_verifier_proto_init() {
+	if File_verifier_proto != nil {
+		return
+	}
+	file_verifier_proto_msgTypes[0].OneofWrappers = []any{}
+	file_verifier_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_verifier_proto_rawDesc), len(file_verifier_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_verifier_proto_goTypes,
+		DependencyIndexes: file_verifier_proto_depIdxs,
+		MessageInfos:      file_verifier_proto_msgTypes,
+	}.Build()
+	File_verifier_proto = out.File
+	file_verifier_proto_goTypes = nil
+	file_verifier_proto_depIdxs = nil
+}
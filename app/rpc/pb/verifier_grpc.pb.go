@@ -0,0 +1,257 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: verifier.proto
+
+// The messages mirror the JSON of the HTTP API: VerifyResponse, CertificateResponse and
+// TppResponse have the fields of their JSON counterparts, with times as timestamps.
+// Codes and statuses are strings as in JSON, so that new check codes need no new version.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TppVerifier_Verify_FullMethodName      = "/tppverifier.v1.TppVerifier/Verify"
+	TppVerifier_VerifyBatch_FullMethodName = "/tppverifier.v1.TppVerifier/VerifyBatch"
+	TppVerifier_LookupTpp_FullMethodName   = "/tppverifier.v1.TppVerifier/LookupTpp"
+	TppVerifier_LookupTpps_FullMethodName  = "/tppverifier.v1.TppVerifier/LookupTpps"
+)
+
+// TppVerifierClient is the client API for TppVerifier service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TppVerifier verifies TPP certificates and looks up the registry. Calls are authenticated
+// with the API key or shared header of the HTTP API, sent as metadata. The standard
+// grpc.health.v1.Health service is served alongside and needs no authentication.
+type TppVerifierClient interface {
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+	// VerifyBatch verifies up to 1000 certificates. An item that cannot be verified
+	// has an error in place of its result.
+	VerifyBatch(ctx context.Context, in *BatchVerifyRequest, opts ...grpc.CallOption) (*BatchVerifyResponse, error)
+	// LookupTpp returns the registry record of a TPP by its OB ID, EBA entity code or national reference code.
+	LookupTpp(ctx context.Context, in *LookupTppRequest, opts ...grpc.CallOption) (*TppRecord, error)
+	// LookupTpps looks up up to 1000 identifiers. An identifier that is not found has an
+	// error in place of its record.
+	LookupTpps(ctx context.Context, in *LookupTppsRequest, opts ...grpc.CallOption) (*LookupTppsResponse, error)
+}
+
+type tppVerifierClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTppVerifierClient(cc grpc.ClientConnInterface) TppVerifierClient {
+	return &tppVerifierClient{cc}
+}
+
+func (c *tppVerifierClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VerifyResponse)
+	err := c.cc.Invoke(ctx, TppVerifier_Verify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tppVerifierClient) VerifyBatch(ctx context.Context, in *BatchVerifyRequest, opts ...grpc.CallOption) (*BatchVerifyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchVerifyResponse)
+	err := c.cc.Invoke(ctx, TppVerifier_VerifyBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tppVerifierClient) LookupTpp(ctx context.Context, in *LookupTppRequest, opts ...grpc.CallOption) (*TppRecord, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TppRecord)
+	err := c.cc.Invoke(ctx, TppVerifier_LookupTpp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tppVerifierClient) LookupTpps(ctx context.Context, in *LookupTppsRequest, opts ...grpc.CallOption) (*LookupTppsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupTppsResponse)
+	err := c.cc.Invoke(ctx, TppVerifier_LookupTpps_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TppVerifierServer is the server API for TppVerifier service.
+// All implementations must embed UnimplementedTppVerifierServer
+// for forward compatibility.
+//
+// TppVerifier verifies TPP certificates and looks up the registry. Calls are authenticated
+// with the API key or shared header of the HTTP API, sent as metadata. The standard
+// grpc.health.v1.Health service is served alongside and needs no authentication.
+type TppVerifierServer interface {
+	Verify(context.Context, *VerifyRequest) (*VerifyResponse, error)
+	// VerifyBatch verifies up to 1000 certificates. An item that cannot be verified
+	// has an error in place of its result.
+	VerifyBatch(context.Context, *BatchVerifyRequest) (*BatchVerifyResponse, error)
+	// LookupTpp returns the registry record of a TPP by its OB ID, EBA entity code or national reference code.
+	LookupTpp(context.Context, *LookupTppRequest) (*TppRecord, error)
+	// LookupTpps looks up up to 1000 identifiers. An identifier that is not found has an
+	// error in place of its record.
+	LookupTpps(context.Context, *LookupTppsRequest) (*LookupTppsResponse, error)
+	mustEmbedUnimplementedTppVerifierServer()
+}
+
+// UnimplementedTppVerifierServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTppVerifierServer struct{}
+
+func (UnimplementedTppVerifierServer) Verify(context.Context, *VerifyRequest) (*VerifyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Verify not implemented")
+}
+func (UnimplementedTppVerifierServer) VerifyBatch(context.Context, *BatchVerifyRequest) (*BatchVerifyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method VerifyBatch not implemented")
+}
+func (UnimplementedTppVerifierServer) LookupTpp(context.Context, *LookupTppRequest) (*TppRecord, error) {
+	return nil, status.Error(codes.Unimplemented, "method LookupTpp not implemented")
+}
+func (UnimplementedTppVerifierServer) LookupTpps(context.Context, *LookupTppsRequest) (*LookupTppsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method LookupTpps not implemented")
+}
+func (UnimplementedTppVerifierServer) mustEmbedUnimplementedTppVerifierServer() {}
+func (UnimplementedTppVerifierServer) testEmbeddedByValue()                     {}
+
+// UnsafeTppVerifierServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TppVerifierServer will
+// result in compilation errors.
+type UnsafeTppVerifierServer interface {
+	mustEmbedUnimplementedTppVerifierServer()
+}
+
+func RegisterTppVerifierServer(s grpc.ServiceRegistrar, srv TppVerifierServer) {
+	// If the following call panics, it indicates UnimplementedTppVerifierServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TppVerifier_ServiceDesc, srv)
+}
+
+func _TppVerifier_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TppVerifierServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TppVerifier_Verify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TppVerifierServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TppVerifier_VerifyBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchVerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TppVerifierServer).VerifyBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TppVerifier_VerifyBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TppVerifierServer).VerifyBatch(ctx, req.(*BatchVerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TppVerifier_LookupTpp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupTppRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TppVerifierServer).LookupTpp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TppVerifier_LookupTpp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TppVerifierServer).LookupTpp(ctx, req.(*LookupTppRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TppVerifier_LookupTpps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupTppsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TppVerifierServer).LookupTpps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TppVerifier_LookupTpps_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TppVerifierServer).LookupTpps(ctx, req.(*LookupTppsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TppVerifier_ServiceDesc is the grpc.ServiceDesc for TppVerifier service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TppVerifier_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tppverifier.v1.TppVerifier",
+	HandlerType: (*TppVerifierServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Verify",
+			Handler:    _TppVerifier_Verify_Handler,
+		},
+		{
+			MethodName: "VerifyBatch",
+			Handler:    _TppVerifier_VerifyBatch_Handler,
+		},
+		{
+			MethodName: "LookupTpp",
+			Handler:    _TppVerifier_LookupTpp_Handler,
+		},
+		{
+			MethodName: "LookupTpps",
+			Handler:    _TppVerifier_LookupTpps_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "verifier.proto",
+}
@@ -0,0 +1,210 @@
+// Package rpc serves the verification service over gRPC, e.g. for API gateways that
+// speak gRPC. It shares the VerifySvc and the authentication of the HTTP API.
+package rpc
+
+//go:generate protoc -I pb --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative pb/verifier.proto
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/rpc/pb"
+	"github.com/botsman/tppVerifier/app/tenant"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+const defaultApiKeyHeader = "X-API-Key"
+
+// maxMessageSize allows batches of verify.MaxBatchSize certificates with their chains.
+const maxMessageSize = 32 << 20
+
+// Service is the part of VerifySvc served over gRPC.
+type Service interface {
+	verify.Verifier
+	verify.BatchVerifier
+	verify.TppLookup
+}
+
+var _ Service = (*verify.VerifySvc)(nil)
+
+type server struct {
+	pb.UnimplementedTppVerifierServer
+	svc Service
+}
+
+// NewServer creates the gRPC server. Calls are authenticated as requests to /tpp, with the
+// API_KEY_HEADER, AUTH_HEADER_NAME and AUTH_HEADER_VALUE settings applied to the metadata.
+// The health service needs no authentication.
+func NewServer(svc Service, auth *tenant.Authenticator) *grpc.Server {
+	headerName := os.Getenv("AUTH_HEADER_NAME")
+	headerValue := os.Getenv("AUTH_HEADER_VALUE")
+	if (headerName == "") != (headerValue == "") {
+		panic("AUTH_HEADER_NAME and AUTH_HEADER_VALUE must be set together")
+	}
+	if headerName == "" && auth == nil {
+		panic("AUTH_HEADER_NAME and AUTH_HEADER_VALUE must be set if tenants are not enabled")
+	}
+	apiKeyHeader := os.Getenv("API_KEY_HEADER")
+	if apiKeyHeader == "" {
+		apiKeyHeader = defaultApiKeyHeader
+	}
+
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoveryInterceptor, authInterceptor(auth, apiKeyHeader, headerName, headerValue)),
+		grpc.MaxRecvMsgSize(maxMessageSize),
+		grpc.MaxSendMsgSize(maxMessageSize),
+	)
+	pb.RegisterTppVerifierServer(s, &server{svc: svc})
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	return s
+}
+
+// recoveryInterceptor answers a call that panics with an internal error, as gin.Recovery
+// does for the HTTP API, so that the panic does not stop the server.
+func recoveryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			res, err = nil, status.Error(codes.Internal, "Failed to process the request.")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// authInterceptor accepts a tenant API key or the shared header in the metadata, as
+// authMiddleware of the HTTP API does. Calls made with an API key are verified with the
+// configuration of the tenant and counted.
+func authInterceptor(auth *tenant.Authenticator, apiKeyHeader, headerName, headerValue string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		if apiKey := first(md.Get(apiKeyHeader)); auth != nil && apiKey != "" {
+			t, err := auth.Authenticate(ctx, apiKey)
+			if errors.Is(err, tenant.ErrUnauthorized) {
+				return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+			}
+			if err != nil {
+				log.Printf("Failed to authenticate API key: %v", err)
+				return nil, status.Error(codes.Internal, "Failed to authenticate API key.")
+			}
+			auth.Record(t.Id)
+			return handler(verify.ContextWithTenant(ctx, t), req)
+		}
+		if headerName == "" || first(md.Get(headerName)) != headerValue {
+			return nil, status.Error(codes.Unauthenticated, "Invalid or missing header")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// errorCode maps the errors of the verify package to status codes.
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, verify.ErrInvalidCertificate),
+		errors.Is(err, verify.ErrNoCertificate),
+		errors.Is(err, verify.ErrCertificateParse),
+		errors.Is(err, verify.ErrAmbiguousTppId),
+		errors.Is(err, verify.ErrEmptyBatch),
+		errors.Is(err, verify.ErrBatchTooLarge),
+		errors.Is(err, verify.ErrNoTppIds),
+		errors.Is(err, verify.ErrTooManyTppIds):
+		return codes.InvalidArgument
+	case errors.Is(err, verify.ErrReceiptsDisabled):
+		return codes.FailedPrecondition
+	case errors.Is(err, db.ErrTppNotFound):
+		return codes.NotFound
+	case errors.Is(err, verify.ErrTppLookup):
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// statusError returns the status of an error. Repository and internal errors are not
+// detailed to clients.
+func statusError(err error) error {
+	switch code := errorCode(err); code {
+	case codes.Internal:
+		return status.Error(code, "Failed to process the request.")
+	case codes.Unavailable:
+		return status.Error(code, verify.ErrTppLookup.Error())
+	default:
+		return status.Error(code, err.Error())
+	}
+}
+
+func (s *server) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.VerifyResponse, error) {
+	res, err := s.svc.Verify(ctx, verifyRequest(req))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return verifyResponse(res), nil
+}
+
+func (s *server) VerifyBatch(ctx context.Context, req *pb.BatchVerifyRequest) (*pb.BatchVerifyResponse, error) {
+	batch := verify.BatchVerifyRequest{Items: make([]verify.VerifyRequest, len(req.GetItems()))}
+	for i, item := range req.GetItems() {
+		batch.Items[i] = verifyRequest(item)
+	}
+	res, err := s.svc.VerifyBatch(ctx, batch)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	out := &pb.BatchVerifyResponse{Results: make([]*pb.BatchVerifyItem, len(res.Results))}
+	for i, item := range res.Results {
+		out.Results[i] = &pb.BatchVerifyItem{Index: int32(item.Index), Result: verifyResponse(item.Result)}
+		if err := item.Err(); err != nil {
+			out.Results[i].Error = itemError(err)
+		}
+	}
+	return out, nil
+}
+
+func (s *server) LookupTpp(ctx context.Context, req *pb.LookupTppRequest) (*pb.TppRecord, error) {
+	tpp, err := s.svc.LookupTpp(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return tppRecord(tpp), nil
+}
+
+func (s *server) LookupTpps(ctx context.Context, req *pb.LookupTppsRequest) (*pb.LookupTppsResponse, error) {
+	res, err := s.svc.LookupTpps(ctx, verify.TppLookupRequest{Ids: req.GetIds()})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	out := &pb.LookupTppsResponse{Results: make([]*pb.LookupTppItem, len(res.Results))}
+	for i, item := range res.Results {
+		out.Results[i] = &pb.LookupTppItem{Id: item.Id, Tpp: tppRecord(item.TPP)}
+		if err := item.Err(); err != nil {
+			out.Results[i].Error = itemError(err)
+		}
+	}
+	return out, nil
+}
+
+func itemError(err error) *pb.Error {
+	st := status.Convert(statusError(err))
+	return &pb.Error{Code: int32(st.Code()), Message: st.Message()}
+}
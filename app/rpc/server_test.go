@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/rpc/pb"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+var notBefore = time.Date(2025, 8, 17, 10, 13, 53, 0, time.UTC)
+
+type stubService struct {
+	req verify.VerifyRequest
+}
+
+func (s *stubService) Verify(ctx context.Context, req verify.VerifyRequest) (*verify.VerifyResponse, error) {
+	s.req = req
+	switch req.Cert {
+	case "invalid":
+		return nil, verify.ErrInvalidCertificate
+	case "broken":
+		return nil, fmt.Errorf("%w: connection refused", verify.ErrTppLookup)
+	case "panic":
+		panic("unexpected certificate")
+	}
+	return &verify.VerifyResponse{
+		Valid:  true,
+		Scopes: map[string][]string{"FI": {"AIS", "PIS"}},
+		Certificate: &models.CertificateResponse{
+			Scopes:     []models.Scope{models.ScopeAIS},
+			Subject:    map[string]any{"country": []string{"FI"}, "organization_identifier": "PSDFI-FINFSA-01110279"},
+			ValidFrom:  notBefore,
+			ValidUntil: notBefore.AddDate(10, 0, 0),
+			Usage:      models.QSEAL,
+		},
+		TPP:    &models.TppResponse{Id: "PSDFI-FINFSA-01110279", Services: map[string][]models.Service{"FI": {models.AISP}}},
+		Checks: []models.Check{{Name: models.CheckScope, Status: models.CheckStatusPass, Code: models.CodeScopesGranted}},
+	}, nil
+}
+
+func (s *stubService) VerifyBatch(ctx context.Context, req verify.BatchVerifyRequest) (*verify.BatchVerifyResponse, error) {
+	if len(req.Items) == 0 {
+		return nil, verify.ErrEmptyBatch
+	}
+	return &verify.BatchVerifyResponse{Results: []verify.BatchVerifyItem{{Index: 0, Result: &verify.VerifyResponse{Valid: true}}}}, nil
+}
+
+func (s *stubService) LookupTpp(ctx context.Context, id string) (*verify.TppRecord, error) {
+	if id != "PSDFI-FINFSA-01110279" {
+		return nil, db.ErrTppNotFound
+	}
+	return &verify.TppRecord{TPP: &models.TPP{OBID: id}, AuthorizationStatus: models.AuthorizationStatusAuthorized}, nil
+}
+
+func (s *stubService) LookupTpps(ctx context.Context, req verify.TppLookupRequest) (*verify.TppLookupResponse, error) {
+	return &verify.TppLookupResponse{}, nil
+}
+
+// dial starts a server on an in-memory listener and returns a connection to it.
+func dial(t *testing.T, svc Service) *grpc.ClientConn {
+	t.Helper()
+	t.Setenv("AUTH_HEADER_NAME", "X-Auth")
+	t.Setenv("AUTH_HEADER_VALUE", "secret")
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(svc, nil)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServer(t *testing.T) {
+	svc := &stubService{}
+	conn := dial(t, svc)
+	client := pb.NewTppVerifierClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-auth", "secret")
+
+	res, err := client.Verify(ctx, &pb.VerifyRequest{Cert: "abc", At: timestamppb.New(notBefore)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !svc.req.NoScopesAsCheck || svc.req.At == nil || !svc.req.At.Equal(notBefore) {
+		t.Errorf("Expected the request to be converted, got %+v", svc.req)
+	}
+	if !res.Valid || len(res.Scopes["FI"].GetServices()) != 2 || len(res.Checks) != 1 {
+		t.Errorf("Expected the result to be converted, got %v", res)
+	}
+	if got := res.Cert.NotBefore.AsTime(); !got.Equal(notBefore) {
+		t.Errorf("Expected not before %v, got %v", notBefore, got)
+	}
+	if got := res.Cert.Subject.GetOrganizationIdentifier(); got != "PSDFI-FINFSA-01110279" {
+		t.Errorf("Expected the organization identifier, got %q", got)
+	}
+	if res.Tpp.GetServices()["FI"].GetServices()[0] != "AIS" || res.Tpp.AuthorizedAt != nil {
+		t.Errorf("Expected the TPP to be converted, got %v", res.Tpp)
+	}
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+		want codes.Code
+	}{
+		{"Invalid certificate", func(ctx context.Context) error {
+			_, err := client.Verify(ctx, &pb.VerifyRequest{Cert: "invalid"})
+			return err
+		}, codes.InvalidArgument},
+		{"Registry unavailable", func(ctx context.Context) error {
+			_, err := client.Verify(ctx, &pb.VerifyRequest{Cert: "broken"})
+			return err
+		}, codes.Unavailable},
+		{"Panic", func(ctx context.Context) error {
+			_, err := client.Verify(ctx, &pb.VerifyRequest{Cert: "panic"})
+			return err
+		}, codes.Internal},
+		{"Served after a panic", func(ctx context.Context) error {
+			_, err := client.Verify(ctx, &pb.VerifyRequest{Cert: "abc"})
+			return err
+		}, codes.OK},
+		{"Batch", func(ctx context.Context) error {
+			_, err := client.VerifyBatch(ctx, &pb.BatchVerifyRequest{Items: []*pb.VerifyRequest{{Cert: "abc"}}})
+			return err
+		}, codes.OK},
+		{"Empty batch", func(ctx context.Context) error {
+			_, err := client.VerifyBatch(ctx, &pb.BatchVerifyRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"Lookup", func(ctx context.Context) error {
+			_, err := client.LookupTpp(ctx, &pb.LookupTppRequest{Id: "PSDFI-FINFSA-01110279"})
+			return err
+		}, codes.OK},
+		{"Lookup unknown", func(ctx context.Context) error {
+			_, err := client.LookupTpp(ctx, &pb.LookupTppRequest{Id: "PSDFI-FINFSA-99999999"})
+			return err
+		}, codes.NotFound},
+		{"Missing header", func(context.Context) error {
+			_, err := client.Verify(context.Background(), &pb.VerifyRequest{Cert: "abc"})
+			return err
+		}, codes.Unauthenticated},
+		{"Health without header", func(context.Context) error {
+			_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			return err
+		}, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call(ctx)); got != tt.want {
+				t.Errorf("Expected code %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
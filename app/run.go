@@ -1,11 +1,16 @@
 package app
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/botsman/tppVerifier/app/jobs"
+	"github.com/botsman/tppVerifier/app/tenant"
 	"github.com/botsman/tppVerifier/app/verify"
 )
 
@@ -13,25 +18,119 @@ type HttpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-func SetupRouter(vs *verify.VerifySvc) *gin.Engine {
+const defaultApiKeyHeader = "X-API-Key"
+
+// SetupRouter creates the router. Requests to /tpp and /v2/tpp are authenticated with tenant
+// API keys if auth is not nil, and with the AUTH_HEADER_NAME/AUTH_HEADER_VALUE pair if it is set.
+// The job endpoints are only served if q is not nil.
+func SetupRouter(vs *verify.VerifySvc, auth *tenant.Authenticator, q *jobs.Queue) *gin.Engine {
 	r := gin.Default()
 	headerName := os.Getenv("AUTH_HEADER_NAME")
 	headerValue := os.Getenv("AUTH_HEADER_VALUE")
-	if headerName == "" || headerValue == "" {
-		panic("AUTH_HEADER_NAME and AUTH_HEADER_VALUE must be set")
+	if (headerName == "") != (headerValue == "") {
+		panic("AUTH_HEADER_NAME and AUTH_HEADER_VALUE must be set together")
+	}
+	if headerName == "" && auth == nil {
+		panic("AUTH_HEADER_NAME and AUTH_HEADER_VALUE must be set if tenants are not enabled")
+	}
+	apiKeyHeader := os.Getenv("API_KEY_HEADER")
+	if apiKeyHeader == "" {
+		apiKeyHeader = defaultApiKeyHeader
 	}
 
+	authenticate := authMiddleware(auth, apiKeyHeader, headerName, headerValue, denyJSON)
 	tppGroup := r.Group("/tpp")
-	tppGroup.Use(func(c *gin.Context) {
-		if c.GetHeader(headerName) != headerValue {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid or missing header"})
-			return
-		}
-		c.Next()
-	})
-	tppGroup.POST("/verify", vs.Verify)
+	tppGroup.Use(authenticate)
+	tppGroup.POST("/verify", verifyHandler(vs))
+	tppGroup.POST("/verify/batch", batchVerifyHandler(vs))
+	tppGroup.POST("/verify/pair", pairVerifyHandler(vs))
+	if q != nil {
+		tppGroup.POST("/verify/jobs", submitJobHandler(q))
+		tppGroup.GET("/verify/jobs/:id", jobHandler(q))
+	}
+	tppGroup.GET("", tppSearchHandler(vs))
+	tppGroup.POST("/lookup", tppLookupHandler(vs))
+	tppGroup.GET("/changes", tppChangesHandler(vs))
+	if auth != nil {
+		tppGroup.GET("/usage", usageHandler(auth))
+	}
+	tppGroup.GET("/:id", tppHandler(vs))
+	r.GET("/verifications/:id", authenticate, evidenceHandler(vs))
+	// Receipts are checked with public keys only, so these endpoints need no authentication
+	r.GET("/keys", keysHandler(vs))
+	r.POST("/receipts/verify", receiptVerifyHandler(vs))
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
+
+	doc, router, err := loadOpenAPI()
+	if err != nil {
+		panic(err)
+	}
+	v2 := r.Group("/v2")
+	v2.GET("/openapi.yaml", openapiHandler(doc, false))
+	v2.GET("/openapi.json", openapiHandler(doc, true))
+	v2.GET("/problems/:type", problemTypeHandler)
+	v2Tpp := v2.Group("/tpp")
+	v2Tpp.Use(authMiddleware(auth, apiKeyHeader, headerName, headerValue, denyProblem), validateRequest(router))
+	v2Tpp.POST("/verify", v2VerifyHandler(vs))
+	v2Tpp.POST("/verify/batch", v2BatchVerifyHandler(vs))
+	v2Tpp.POST("/verify/pair", v2PairVerifyHandler(vs))
+	v2Tpp.GET("", v2TppSearchHandler(vs))
+	v2Tpp.POST("/lookup", v2TppLookupHandler(vs))
+	v2Tpp.GET("/changes", v2TppChangesHandler(vs))
+	v2Tpp.GET("/:id", v2TppHandler(vs))
+	r.NoRoute(func(c *gin.Context) {
+		// Outside of v2, gin answers with its default 404
+		if strings.HasPrefix(c.Request.URL.Path, "/v2/") {
+			writeProblem(c, newProblem(problemNotFound, ""))
+		}
+	})
 	return r
 }
+
+// authMiddleware accepts a tenant API key or the shared header. Requests made with
+// an API key are verified with the configuration of the tenant and counted.
+// Other requests are answered with deny.
+func authMiddleware(auth *tenant.Authenticator, apiKeyHeader, headerName, headerValue string, deny denyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey := c.GetHeader(apiKeyHeader); auth != nil && apiKey != "" {
+			t, err := auth.Authenticate(c.Request.Context(), apiKey)
+			if errors.Is(err, tenant.ErrUnauthorized) {
+				deny(c, http.StatusForbidden, "Invalid API key")
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to authenticate API key: %v", err)
+				deny(c, http.StatusInternalServerError, "Failed to authenticate API key.")
+				return
+			}
+			auth.Record(t.Id)
+			c.Request = c.Request.WithContext(verify.ContextWithTenant(c.Request.Context(), t))
+			c.Next()
+			return
+		}
+		if headerName == "" || c.GetHeader(headerName) != headerValue {
+			deny(c, http.StatusForbidden, "Invalid or missing header")
+			return
+		}
+		c.Next()
+	}
+}
+
+// denyFunc aborts a request that failed authentication.
+type denyFunc func(c *gin.Context, status int, msg string)
+
+// denyJSON answers with the v1 error object.
+func denyJSON(c *gin.Context, status int, msg string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": msg})
+}
+
+// denyProblem answers with a problem. Invalid credentials are 401 in v2, not 403 as in v1.
+func denyProblem(c *gin.Context, status int, msg string) {
+	if status == http.StatusForbidden {
+		writeProblem(c, newProblem(problemUnauthorized, msg))
+		return
+	}
+	writeProblem(c, newProblem(problemInternal, msg))
+}
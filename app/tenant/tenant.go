@@ -0,0 +1,184 @@
+// Package tenant authenticates API keys against the tenants of the repository
+// and counts the requests of each tenant.
+package tenant
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/clock"
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+// ErrUnauthorized is returned when the API key does not belong to an active tenant.
+var ErrUnauthorized = errors.New("invalid API key")
+
+// defaultCacheTTL bounds how long a revoked key or a changed tenant keeps being served
+// from memory, while sparing the repository a lookup on every request.
+const defaultCacheTTL = time.Minute
+
+const dayFormat = "2006-01-02"
+
+type cachedTenant struct {
+	tenant  *verify.Tenant // nil for unknown keys
+	expires time.Time
+}
+
+type usageKey struct {
+	tenantId string
+	day      string
+}
+
+type Authenticator struct {
+	repo  db.TenantRepository
+	clock clock.Clock
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	tenants map[string]cachedTenant
+	usage   map[usageKey]int64
+}
+
+func NewAuthenticator(repo db.TenantRepository) *Authenticator {
+	return &Authenticator{
+		repo:    repo,
+		clock:   clock.System(),
+		ttl:     defaultCacheTTL,
+		tenants: make(map[string]cachedTenant),
+		usage:   make(map[usageKey]int64),
+	}
+}
+
+// SetCacheTTL sets how long tenants are cached by API key, zero disables the cache.
+func (a *Authenticator) SetCacheTTL(ttl time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ttl = ttl
+	a.tenants = make(map[string]cachedTenant)
+}
+
+// SetClock sets the clock used for the cache and the usage days.
+func (a *Authenticator) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// HashApiKey returns the hex encoded SHA-256 hash under which the API key is stored.
+func HashApiKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate returns the tenant owning the API key, with its policy resolved.
+func (a *Authenticator) Authenticate(ctx context.Context, apiKey string) (*verify.Tenant, error) {
+	if apiKey == "" {
+		return nil, ErrUnauthorized
+	}
+	hash := HashApiKey(apiKey)
+	now := a.clock.Now()
+	a.mu.Lock()
+	cached, ok := a.tenants[hash]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		if cached.tenant == nil {
+			return nil, ErrUnauthorized
+		}
+		return cached.tenant, nil
+	}
+
+	t, err := a.lookup(ctx, hash)
+	if err != nil && !errors.Is(err, ErrUnauthorized) {
+		return nil, err
+	}
+	a.mu.Lock()
+	if a.ttl > 0 {
+		a.tenants[hash] = cachedTenant{tenant: t, expires: now.Add(a.ttl)}
+	}
+	a.mu.Unlock()
+	return t, err
+}
+
+func (a *Authenticator) lookup(ctx context.Context, hash string) (*verify.Tenant, error) {
+	record, err := a.repo.GetTenantByApiKey(ctx, hash)
+	if errors.Is(err, db.ErrTenantNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return a.resolve(ctx, record)
+}
+
+// Tenant returns the active tenant with the given ID, with its policy resolved.
+// It is used for work done on behalf of a tenant outside of its request, and is not cached.
+func (a *Authenticator) Tenant(ctx context.Context, id string) (*verify.Tenant, error) {
+	record, err := a.repo.GetTenant(ctx, id)
+	if errors.Is(err, db.ErrTenantNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return a.resolve(ctx, record)
+}
+
+func (a *Authenticator) resolve(ctx context.Context, record *models.Tenant) (*verify.Tenant, error) {
+	t := &verify.Tenant{Id: record.Id}
+	if len(record.AspspCountries) > 0 {
+		t.AspspCountries = record.AspspCountries
+	}
+	if record.Policy != "" {
+		p, err := a.repo.GetPolicy(ctx, record.Policy)
+		if err != nil {
+			// Falling back to the policy of the deployment could accept certificates the tenant rejects
+			return nil, fmt.Errorf("failed to get policy %q of tenant %s: %w", record.Policy, record.Id, err)
+		}
+		t.Policy = p
+	}
+	return t, nil
+}
+
+// Record counts a request of the tenant. Counters are kept in memory until Flush.
+func (a *Authenticator) Record(tenantId string) {
+	key := usageKey{tenantId: tenantId, day: a.clock.Now().UTC().Format(dayFormat)}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.usage[key]++
+}
+
+// Flush adds the counted requests to the repository. Counters that could not
+// be written are kept for the next flush.
+func (a *Authenticator) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	pending := a.usage
+	a.usage = make(map[usageKey]int64)
+	a.mu.Unlock()
+
+	var errs []error
+	for key, requests := range pending {
+		if err := a.repo.AddUsage(ctx, key.tenantId, key.day, requests); err != nil {
+			log.Printf("Failed to store usage of tenant %s: %v", key.tenantId, err)
+			errs = append(errs, err)
+			a.mu.Lock()
+			a.usage[key] += requests
+			a.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Usage returns the daily request counters of the tenant for the last days, including today.
+func (a *Authenticator) Usage(ctx context.Context, tenantId string, days int) ([]models.TenantUsage, error) {
+	if err := a.Flush(ctx); err != nil {
+		return nil, err
+	}
+	since := a.clock.Now().UTC().AddDate(0, 0, 1-days).Format(dayFormat)
+	return a.repo.GetUsage(ctx, tenantId, since)
+}
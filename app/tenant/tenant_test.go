@@ -0,0 +1,188 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/clock"
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+type stubRepo struct {
+	tenants  map[string]*models.Tenant
+	policies map[string]*policy.Policy
+	usage    map[string]int64
+	lookups  int
+	failAdd  bool
+}
+
+func newStubRepo() *stubRepo {
+	return &stubRepo{
+		tenants: map[string]*models.Tenant{
+			HashApiKey("retail-key"):    {Id: "retail", IsActive: true},
+			HashApiKey("corporate-key"): {Id: "corporate", Policy: "corporate", AspspCountries: []string{"FI"}, IsActive: true},
+			HashApiKey("broken-key"):    {Id: "broken", Policy: "missing", IsActive: true},
+		},
+		policies: map[string]*policy.Policy{
+			"corporate": {Name: "corporate", Revocation: policy.RevocationSoft},
+		},
+		usage: map[string]int64{},
+	}
+}
+
+func (r *stubRepo) GetTenantByApiKey(ctx context.Context, keyHash string) (*models.Tenant, error) {
+	r.lookups++
+	if t, ok := r.tenants[keyHash]; ok {
+		return t, nil
+	}
+	return nil, db.ErrTenantNotFound
+}
+
+func (r *stubRepo) GetTenant(ctx context.Context, id string) (*models.Tenant, error) {
+	for _, t := range r.tenants {
+		if t.Id == id {
+			return t, nil
+		}
+	}
+	return nil, db.ErrTenantNotFound
+}
+
+func (r *stubRepo) GetPolicy(ctx context.Context, name string) (*policy.Policy, error) {
+	if p, ok := r.policies[name]; ok {
+		return p, nil
+	}
+	return nil, db.ErrPolicyNotFound
+}
+
+func (r *stubRepo) AddUsage(ctx context.Context, tenantId string, day string, requests int64) error {
+	if r.failAdd {
+		return errors.New("database unavailable")
+	}
+	r.usage[tenantId+"/"+day] += requests
+	return nil
+}
+
+func (r *stubRepo) GetUsage(ctx context.Context, tenantId string, since string) ([]models.TenantUsage, error) {
+	var usage []models.TenantUsage
+	for key, requests := range r.usage {
+		if id, day, _ := strings.Cut(key, "/"); id == tenantId && day >= since {
+			usage = append(usage, models.TenantUsage{TenantId: id, Day: day, Requests: requests})
+		}
+	}
+	return usage, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name      string
+		apiKey    string
+		tenantId  string
+		policy    string
+		countries []string
+		err       error
+	}{
+		{"Default configuration", "retail-key", "retail", "", nil, nil},
+		{"Tenant configuration", "corporate-key", "corporate", "corporate", []string{"FI"}, nil},
+		{"Unknown key", "other-key", "", "", nil, ErrUnauthorized},
+		{"Empty key", "", "", "", nil, ErrUnauthorized},
+		{"Missing policy", "broken-key", "", "", nil, db.ErrPolicyNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthenticator(newStubRepo())
+			tenant, err := auth.Authenticate(context.Background(), tt.apiKey)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tenant.Id != tt.tenantId {
+				t.Errorf("Expected tenant %s, got %s", tt.tenantId, tenant.Id)
+			}
+			if tt.policy == "" && tenant.Policy != nil {
+				t.Errorf("Expected the policy of the deployment, got %s", tenant.Policy.Name)
+			}
+			if tt.policy != "" && (tenant.Policy == nil || tenant.Policy.Name != tt.policy) {
+				t.Errorf("Expected policy %s, got %+v", tt.policy, tenant.Policy)
+			}
+			if len(tenant.AspspCountries) != len(tt.countries) {
+				t.Errorf("Expected countries %v, got %v", tt.countries, tenant.AspspCountries)
+			}
+		})
+	}
+}
+
+func TestTenant(t *testing.T) {
+	repo := newStubRepo()
+	auth := NewAuthenticator(repo)
+	tenant, err := auth.Tenant(context.Background(), "corporate")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tenant.Policy == nil || tenant.Policy.Name != "corporate" {
+		t.Errorf("Expected policy corporate, got %+v", tenant.Policy)
+	}
+	if _, err := auth.Tenant(context.Background(), "other"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected error %v, got %v", ErrUnauthorized, err)
+	}
+	if repo.lookups != 0 {
+		t.Errorf("Expected no API key lookups, got %d", repo.lookups)
+	}
+}
+
+func TestAuthenticate_Cache(t *testing.T) {
+	repo := newStubRepo()
+	auth := NewAuthenticator(repo)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	auth.SetClock(clock.Fixed(now))
+	for _, key := range []string{"retail-key", "retail-key", "other-key", "other-key"} {
+		_, _ = auth.Authenticate(context.Background(), key)
+	}
+	if repo.lookups != 2 {
+		t.Errorf("Expected 2 lookups, got %d", repo.lookups)
+	}
+	auth.SetClock(clock.Fixed(now.Add(defaultCacheTTL)))
+	if _, err := auth.Authenticate(context.Background(), "retail-key"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if repo.lookups != 3 {
+		t.Errorf("Expected the expired tenant to be looked up again, got %d lookups", repo.lookups)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	repo := newStubRepo()
+	auth := NewAuthenticator(repo)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	auth.SetClock(clock.Fixed(now))
+	auth.Record("retail")
+	auth.Record("retail")
+	auth.Record("corporate")
+
+	repo.failAdd = true
+	if err := auth.Flush(context.Background()); err == nil {
+		t.Fatal("Expected a flush error")
+	}
+	repo.failAdd = false
+	auth.Record("retail")
+
+	usage, err := auth.Usage(context.Background(), "retail", 7)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(usage) != 1 || usage[0].Day != "2026-10-01" || usage[0].Requests != 3 {
+		t.Errorf("Expected 3 requests on 2026-10-01, got %+v", usage)
+	}
+	if repo.usage["corporate/2026-10-01"] != 1 {
+		t.Errorf("Expected 1 request of corporate, got %d", repo.usage["corporate/2026-10-01"])
+	}
+}
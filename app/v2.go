@@ -0,0 +1,218 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+// The v2 API differs from v1 in its errors, which are problem details, in the validity of
+// certificates, which is returned as RFC 3339 times, and in certificates the registry grants
+// no scopes, which fail the scope check instead of the request. Requests are validated
+// against the OpenAPI document before they reach these handlers.
+
+// v2Certificate returns the validity of the certificate as times; the fields shadow
+// the time.String values of the embedded response.
+type v2Certificate struct {
+	*models.CertificateResponse
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+type v2VerifyResponse struct {
+	*verify.VerifyResponse
+	Certificate *v2Certificate `json:"cert"`
+}
+
+func newV2VerifyResponse(res *verify.VerifyResponse) *v2VerifyResponse {
+	if res == nil {
+		return nil
+	}
+	v2 := &v2VerifyResponse{VerifyResponse: res}
+	if crt := res.Certificate; crt != nil {
+		v2.Certificate = &v2Certificate{CertificateResponse: crt, NotBefore: crt.ValidFrom, NotAfter: crt.ValidUntil}
+	}
+	return v2
+}
+
+// v2VerifyHandler verifies a certificate. The ETSI validation report is only available in v1.
+func v2VerifyHandler(v verify.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req verify.VerifyRequest
+		if err := c.BindJSON(&req); err != nil {
+			writeProblem(c, newProblem(problemValidation, "Invalid request format."))
+			return
+		}
+		req.NoScopesAsCheck = true
+		res, err := v.Verify(c.Request.Context(), req)
+		if err != nil {
+			writeProblem(c, errorProblem(err))
+			return
+		}
+		c.JSON(http.StatusOK, newV2VerifyResponse(res))
+	}
+}
+
+type v2BatchVerifyItem struct {
+	Index  int               `json:"index"`
+	Result *v2VerifyResponse `json:"result,omitempty"`
+	Error  *problem          `json:"error,omitempty"`
+}
+
+type v2BatchVerifyResponse struct {
+	Results []v2BatchVerifyItem `json:"results"`
+}
+
+func v2BatchVerifyHandler(v verify.BatchVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req verify.BatchVerifyRequest
+		if err := c.BindJSON(&req); err != nil {
+			writeProblem(c, newProblem(problemValidation, "Invalid request format."))
+			return
+		}
+		for i := range req.Items {
+			req.Items[i].NoScopesAsCheck = true
+		}
+		res, err := v.VerifyBatch(c.Request.Context(), req)
+		if err != nil {
+			writeProblem(c, errorProblem(err))
+			return
+		}
+		out := v2BatchVerifyResponse{Results: make([]v2BatchVerifyItem, len(res.Results))}
+		for i, item := range res.Results {
+			out.Results[i] = v2BatchVerifyItem{Index: item.Index, Result: newV2VerifyResponse(item.Result)}
+			if err := item.Err(); err != nil {
+				out.Results[i].Error = errorProblem(err)
+			}
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+type v2PairVerifyResponse struct {
+	*verify.PairVerifyResponse
+	QWAC   *v2VerifyResponse `json:"qwac"`
+	QSealC *v2VerifyResponse `json:"qsealc"`
+}
+
+func v2PairVerifyHandler(v verify.PairVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req verify.PairVerifyRequest
+		if err := c.BindJSON(&req); err != nil {
+			writeProblem(c, newProblem(problemValidation, "Invalid request format."))
+			return
+		}
+		req.NoScopesAsCheck = true
+		res, err := v.VerifyPair(c.Request.Context(), req)
+		if err != nil {
+			writeProblem(c, errorProblem(err))
+			return
+		}
+		c.JSON(http.StatusOK, v2PairVerifyResponse{
+			PairVerifyResponse: res,
+			QWAC:               newV2VerifyResponse(res.QWAC),
+			QSealC:             newV2VerifyResponse(res.QSealC),
+		})
+	}
+}
+
+func v2TppHandler(v verify.TppLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tpp, err := v.LookupTpp(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			writeProblem(c, errorProblem(err))
+			return
+		}
+		c.JSON(http.StatusOK, tpp)
+	}
+}
+
+type v2TppLookupItem struct {
+	Id    string            `json:"id"`
+	TPP   *verify.TppRecord `json:"tpp,omitempty"`
+	Error *problem          `json:"error,omitempty"`
+}
+
+type v2TppLookupResponse struct {
+	Results []v2TppLookupItem `json:"results"`
+}
+
+func v2TppLookupHandler(v verify.TppLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req verify.TppLookupRequest
+		if err := c.BindJSON(&req); err != nil {
+			writeProblem(c, newProblem(problemValidation, "Invalid request format."))
+			return
+		}
+		res, err := v.LookupTpps(c.Request.Context(), req)
+		if err != nil {
+			writeProblem(c, errorProblem(err))
+			return
+		}
+		out := v2TppLookupResponse{Results: make([]v2TppLookupItem, len(res.Results))}
+		for i, item := range res.Results {
+			out.Results[i] = v2TppLookupItem{Id: item.Id, TPP: item.TPP}
+			if err := item.Err(); err != nil {
+				out.Results[i].Error = errorProblem(err)
+			}
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// v2TppSearchHandler searches the registry. The limit was validated against the OpenAPI document.
+func v2TppSearchHandler(v verify.TppSearcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := verify.TppSearchRequest{
+			Name:              c.Query("name"),
+			Country:           c.Query("country"),
+			Authority:         c.Query("authority"),
+			Type:              c.Query("type"),
+			Service:           c.Query("service"),
+			PassportedCountry: c.Query("passported_country"),
+			Status:            c.Query("status"),
+			Cursor:            c.Query("cursor"),
+			Limit:             queryInt(c, "limit"),
+		}
+		res, err := v.SearchTpps(c.Request.Context(), req)
+		if err != nil {
+			writeProblem(c, errorProblem(err))
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// v2TppChangesHandler returns the registry changes. The limit was validated against the OpenAPI document.
+func v2TppChangesHandler(v verify.TppChangeFeed) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := verify.TppChangesRequest{Cursor: c.Query("cursor"), Limit: queryInt(c, "limit")}
+		if since := c.Query("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				p := newProblem(problemValidation, "Invalid since parameter.")
+				p.Errors = []invalidParam{{Detail: "must be an RFC 3339 time", Parameter: "since"}}
+				writeProblem(c, p)
+				return
+			}
+			req.Since = t
+		}
+		res, err := v.TppChanges(c.Request.Context(), req)
+		if err != nil {
+			writeProblem(c, errorProblem(err))
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// queryInt returns an integer query parameter, 0 if it is not set or not an integer.
+func queryInt(c *gin.Context, name string) int {
+	n, _ := strconv.Atoi(c.Query(name))
+	return n
+}
@@ -0,0 +1,220 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/verify"
+)
+
+type stubBatchVerifier struct{}
+
+func (stubBatchVerifier) VerifyBatch(ctx context.Context, req verify.BatchVerifyRequest) (*verify.BatchVerifyResponse, error) {
+	res := &verify.BatchVerifyResponse{}
+	for i := range req.Items {
+		res.Results = append(res.Results, verify.BatchVerifyItem{Index: i, Result: &verify.VerifyResponse{Valid: req.Items[i].NoScopesAsCheck}})
+	}
+	return res, nil
+}
+
+func newV2TestRouter(t *testing.T, v verify.Verifier) *gin.Engine {
+	t.Helper()
+	_, router, err := loadOpenAPI()
+	if err != nil {
+		t.Fatalf("Failed to load OpenAPI document: %v", err)
+	}
+	r := gin.New()
+	g := r.Group("/v2/tpp", validateRequest(router))
+	g.POST("/verify", v2VerifyHandler(v))
+	g.POST("/verify/batch", v2BatchVerifyHandler(stubBatchVerifier{}))
+	g.GET("", v2TppSearchHandler(stubTppLookup{}))
+	g.POST("/lookup", v2TppLookupHandler(stubTppLookup{}))
+	g.GET("/changes", v2TppChangesHandler(stubTppLookup{}))
+	g.GET("/:id", v2TppHandler(stubTppLookup{}))
+	return r
+}
+
+func TestV2Handlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tooMany := `{"items": [` + strings.Repeat(`{"cert": "abc"},`, verify.MaxBatchSize) + `{"cert": "abc"}]}`
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		verifier *stubVerifier
+		want     int
+		// problem is the type of the expected problem, empty if the request succeeds
+		problem string
+		// invalid is the pointer or parameter of the expected validation error
+		invalid string
+	}{
+		{"Verify", http.MethodPost, "/v2/tpp/verify", `{"cert": "abc"}`, &stubVerifier{res: &verify.VerifyResponse{}}, http.StatusOK, "", ""},
+		{"Verify without certificate", http.MethodPost, "/v2/tpp/verify", `{"chain": []}`, &stubVerifier{}, http.StatusBadRequest, "/v2/problems/validation-error", "/cert"},
+		{"Verify invalid country", http.MethodPost, "/v2/tpp/verify", `{"cert": "abc", "aspsp_country": "Finland"}`, &stubVerifier{}, http.StatusBadRequest, "/v2/problems/validation-error", "/aspsp_country"},
+		{"Verify invalid JSON", http.MethodPost, "/v2/tpp/verify", `{`, &stubVerifier{}, http.StatusBadRequest, "/v2/problems/validation-error", ""},
+		{"Verify invalid certificate", http.MethodPost, "/v2/tpp/verify", `{"cert": "abc"}`, &stubVerifier{err: verify.ErrInvalidCertificate}, http.StatusUnprocessableEntity, "/v2/problems/invalid-certificate", ""},
+		{"Verify TPP lookup", http.MethodPost, "/v2/tpp/verify", `{"cert": "abc"}`, &stubVerifier{err: verify.ErrTppLookup}, http.StatusServiceUnavailable, "/v2/problems/registry-unavailable", ""},
+		{"Verify unknown error", http.MethodPost, "/v2/tpp/verify", `{"cert": "abc"}`, &stubVerifier{err: verify.ErrCertificateVerify}, http.StatusInternalServerError, "/v2/problems/internal-error", ""},
+		{"Batch", http.MethodPost, "/v2/tpp/verify/batch", `{"items": [{"cert": "abc"}]}`, nil, http.StatusOK, "", ""},
+		{"Batch too large", http.MethodPost, "/v2/tpp/verify/batch", tooMany, nil, http.StatusBadRequest, "/v2/problems/validation-error", "/items"},
+		{"Get", http.MethodGet, "/v2/tpp/PSDFI-FINFSA-01110279", "", nil, http.StatusOK, "", ""},
+		{"Get unknown", http.MethodGet, "/v2/tpp/PSDFI-FINFSA-99999999", "", nil, http.StatusNotFound, "/v2/problems/tpp-not-found", ""},
+		{"Get ambiguous", http.MethodGet, "/v2/tpp/44059", "", nil, http.StatusConflict, "/v2/problems/ambiguous-tpp-id", ""},
+		{"Lookup", http.MethodPost, "/v2/tpp/lookup", `{"ids": ["44059"]}`, nil, http.StatusOK, "", ""},
+		{"Lookup without identifiers", http.MethodPost, "/v2/tpp/lookup", `{"ids": []}`, nil, http.StatusBadRequest, "/v2/problems/validation-error", "/ids"},
+		{"Search", http.MethodGet, "/v2/tpp?country=FI&limit=10", "", nil, http.StatusOK, "", ""},
+		{"Search limit too large", http.MethodGet, "/v2/tpp?limit=500", "", nil, http.StatusBadRequest, "/v2/problems/validation-error", "limit"},
+		{"Search unknown type", http.MethodGet, "/v2/tpp?type=BANK", "", nil, http.StatusBadRequest, "/v2/problems/validation-error", "type"},
+		{"Changes", http.MethodGet, "/v2/tpp/changes?since=2026-10-01T00:00:00Z", "", nil, http.StatusOK, "", ""},
+		{"Changes invalid since", http.MethodGet, "/v2/tpp/changes?since=yesterday", "", nil, http.StatusBadRequest, "/v2/problems/validation-error", "since"},
+		{"Changes invalid cursor", http.MethodGet, "/v2/tpp/changes?cursor=invalid", "", nil, http.StatusBadRequest, "/v2/problems/validation-error", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newV2TestRouter(t, tt.verifier)
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("Couldn't create request: %v", err)
+			}
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.want, w.Code, w.Body)
+			}
+			if tt.problem == "" {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != problemContentType {
+				t.Errorf("Expected content type %s, got %s", problemContentType, got)
+			}
+			var p problem
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("Expected a problem, got %s", w.Body)
+			}
+			if p.Type != tt.problem || p.Status != tt.want || p.Title == "" || p.Instance != req.URL.Path {
+				t.Errorf("Expected problem %s with status %d, got %+v", tt.problem, tt.want, p)
+			}
+			if tt.invalid == "" {
+				return
+			}
+			found := false
+			for _, param := range p.Errors {
+				found = found || param.Pointer == tt.invalid || param.Parameter == tt.invalid
+			}
+			if !found {
+				t.Errorf("Expected an error for %s, got %+v", tt.invalid, p.Errors)
+			}
+		})
+	}
+}
+
+func TestV2VerifyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	notBefore := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	verifier := &stubVerifier{res: &verify.VerifyResponse{
+		Valid: true,
+		Certificate: &models.CertificateResponse{
+			NotBefore:  notBefore.String(),
+			NotAfter:   notBefore.AddDate(1, 0, 0).String(),
+			ValidFrom:  notBefore,
+			ValidUntil: notBefore.AddDate(1, 0, 0),
+		},
+	}}
+	r := newV2TestRouter(t, verifier)
+	req, err := http.NewRequest(http.MethodPost, "/v2/tpp/verify", bytes.NewBufferString(`{"cert": "abc"}`))
+	if err != nil {
+		t.Fatalf("Couldn't create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if !verifier.req.NoScopesAsCheck {
+		t.Error("Expected certificates without scopes to fail a check")
+	}
+	var res struct {
+		Valid bool `json:"valid"`
+		Cert  struct {
+			NotBefore string `json:"not_before"`
+			NotAfter  string `json:"not_after"`
+		} `json:"cert"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !res.Valid || res.Cert.NotBefore != "2025-01-02T03:04:05Z" || res.Cert.NotAfter != "2026-01-02T03:04:05Z" {
+		t.Errorf("Expected RFC 3339 validity, got %+v", res)
+	}
+}
+
+func TestSetupRouter_V2(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AUTH_HEADER_NAME", "X-Auth")
+	t.Setenv("AUTH_HEADER_VALUE", "secret")
+	r := SetupRouter(verify.NewVerifySvc(nil, nil), nil, nil)
+	doc, _, err := loadOpenAPI()
+	if err != nil {
+		t.Fatalf("Failed to load OpenAPI document: %v", err)
+	}
+
+	// Every v2 endpoint but the document itself is described by the document
+	for _, route := range r.Routes() {
+		path, ok := strings.CutPrefix(route.Path, "/v2")
+		if !ok || strings.HasPrefix(path, "/openapi.") {
+			continue
+		}
+		if i := strings.Index(path, "/:"); i >= 0 {
+			path = path[:i] + "/{" + path[i+2:] + "}"
+		}
+		item := doc.Paths.Find(path)
+		if item == nil || item.GetOperation(route.Method) == nil {
+			t.Errorf("Expected %s %s in the OpenAPI document", route.Method, route.Path)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		want        int
+		contentType string
+	}{
+		{"Document", "/v2/openapi.yaml", http.StatusOK, "application/yaml"},
+		{"JSON document", "/v2/openapi.json", http.StatusOK, "application/json; charset=utf-8"},
+		{"Problem type", "/v2/problems/validation-error", http.StatusOK, "application/json; charset=utf-8"},
+		{"Unknown problem type", "/v2/problems/unknown", http.StatusNotFound, problemContentType},
+		{"Unauthorized", "/v2/tpp/PSDFI-FINFSA-01110279", http.StatusUnauthorized, problemContentType},
+		{"Unknown path", "/v2/unknown", http.StatusNotFound, problemContentType},
+		{"Unknown v1 path", "/unknown", http.StatusNotFound, "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			if err != nil {
+				t.Fatalf("Couldn't create request: %v", err)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status code %d, got %d", tt.want, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, got)
+			}
+		})
+	}
+}
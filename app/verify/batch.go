@@ -0,0 +1,114 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/botsman/tppVerifier/app/db"
+)
+
+const (
+	defaultBatchWorkers = 8
+	MaxBatchSize        = 1000
+)
+
+var (
+	ErrEmptyBatch    = errors.New("no certificates found in the batch")
+	ErrBatchTooLarge = errors.New("too many certificates in the batch")
+)
+
+// BatchVerifier verifies several certificates in one call.
+type BatchVerifier interface {
+	VerifyBatch(ctx context.Context, req BatchVerifyRequest) (*BatchVerifyResponse, error)
+}
+
+var _ BatchVerifier = (*VerifySvc)(nil)
+
+type BatchVerifyRequest struct {
+	Items []VerifyRequest `json:"items"`
+}
+
+// BatchVerifyItem holds either the result or the error of a single item,
+// so that one bad certificate does not fail the whole batch.
+type BatchVerifyItem struct {
+	Index  int             `json:"index"`
+	Result *VerifyResponse `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	err error
+}
+
+// itemError returns the error of a batch or lookup item as shown to clients. Errors caused
+// by the request are passed through, repository and internal errors are not detailed.
+func itemError(err error) string {
+	switch {
+	case errors.Is(err, ErrTppLookup):
+		return ErrTppLookup.Error()
+	case errors.Is(err, ErrInvalidCertificate), errors.Is(err, ErrNoCertificate),
+		errors.Is(err, ErrCertificateParse), errors.Is(err, ErrNoScopes),
+		errors.Is(err, ErrReceiptsDisabled), errors.Is(err, ErrAmbiguousTppId),
+		errors.Is(err, db.ErrTppNotFound),
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err.Error()
+	default:
+		return "Failed to process the request."
+	}
+}
+
+// Err returns the error of the item, nil if it was verified.
+func (i BatchVerifyItem) Err() error {
+	return i.err
+}
+
+type BatchVerifyResponse struct {
+	Results []BatchVerifyItem `json:"results"`
+}
+
+// SetBatchWorkers sets how many batch items are verified concurrently.
+func (s *VerifySvc) SetBatchWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.batchWorkers = n
+}
+
+func (s *VerifySvc) VerifyBatch(ctx context.Context, req BatchVerifyRequest) (*BatchVerifyResponse, error) {
+	if len(req.Items) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(req.Items) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	// Items of the same issuer share chain downloads and OCSP requests
+	// through s.lookups, and learned intermediates through the pool.
+	results := make([]BatchVerifyItem, len(req.Items))
+	sem := make(chan struct{}, s.batchWorkers)
+	var wg sync.WaitGroup
+	for i, item := range req.Items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = s.verifyBatchItem(ctx, i, item)
+		}()
+	}
+	wg.Wait()
+	return &BatchVerifyResponse{Results: results}, nil
+}
+
+func (s *VerifySvc) verifyBatchItem(ctx context.Context, idx int, req VerifyRequest) BatchVerifyItem {
+	item := BatchVerifyItem{Index: idx}
+	if err := ctx.Err(); err != nil {
+		item.Error, item.err = itemError(err), err
+		return item
+	}
+	res, err := s.Verify(ctx, req)
+	if err != nil {
+		item.Error, item.err = itemError(err), err
+		return item
+	}
+	item.Result = res
+	return item
+}
@@ -0,0 +1,161 @@
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+// maxCacheEntries bounds the cache size, expired entries are evicted when it is reached.
+const maxCacheEntries = 10000
+
+// resultCache keeps verification results keyed by the certificate fingerprint.
+// The zero value is a disabled cache.
+type resultCache struct {
+	mu      sync.Mutex
+	maxTTL  time.Duration
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	res     *VerifyResponse
+	expires time.Time
+}
+
+// SetCacheTTL enables the result cache with the given maximum lifetime.
+// A zero or negative TTL disables it.
+func (s *VerifySvc) SetCacheTTL(ttl time.Duration) {
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+	s.cache.maxTTL = ttl
+	s.cache.entries = nil
+}
+
+func (c *resultCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+func (c *resultCache) get(key string, now time.Time) *VerifyResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	res := *entry.res
+	res.Cached = true
+	return &res
+}
+
+// put stores the result until the given expiry, capped by the maximum TTL.
+func (c *resultCache) put(key string, res *VerifyResponse, now, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxTTL <= 0 {
+		return
+	}
+	if maxExpires := now.Add(c.maxTTL); expires.IsZero() || maxExpires.Before(expires) {
+		expires = maxExpires
+	}
+	if !now.Before(expires) {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+	if len(c.entries) >= maxCacheEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = cacheEntry{res: res, expires: expires}
+}
+
+// registryCurrent reports whether a cached result was computed from the stored registry
+// record of the TPP. Each import of the registry updates the records, so that results
+// are recomputed once the registry was reloaded, e.g. after a TPP was withdrawn or removed.
+func (s *VerifySvc) registryCurrent(ctx context.Context, crt *cert.ParsedCert, req VerifyRequest, cached *VerifyResponse) bool {
+	if !req.registryCheckEnabled() {
+		return true
+	}
+	tpp, err := s.db.GetTpp(ctx, normalizeTppId(crt.CompanyId()))
+	if err != nil && !errors.Is(err, db.ErrTppNotFound) {
+		// The fresh verification reports the repository error
+		return false
+	}
+	var version *time.Time
+	if tpp != nil && !tpp.UpdatedAt.IsZero() {
+		version = &tpp.UpdatedAt
+	}
+	if version == nil || cached.DataVersions.Registry == nil {
+		return version == nil && cached.DataVersions.Registry == nil
+	}
+	return version.Equal(*cached.DataVersions.Registry)
+}
+
+// resultCacheKey combines the certificate fingerprint with the supplied intermediates,
+// the request options, the policy and the allowed countries that change the result.
+// Results are not shared between tenants, as their evidence is only visible to the
+// tenant that caused it.
+func resultCacheKey(ctx context.Context, crt *cert.ParsedCert, intermediates []*cert.ParsedCert, req VerifyRequest, p *policy.Policy, countries []string) string {
+	allowed := "*"
+	if countries != nil {
+		allowed = strings.Join(countries, ",")
+	}
+	tenantId := ""
+	if t := TenantFromContext(ctx); t != nil {
+		tenantId = t.Id
+	}
+	return fmt.Sprintf("%s|%s|%s|%t|%s|%s|%s|%t", crt.Sha256(), chainHash(intermediates), allowed, req.registryCheckEnabled(), req.ExpectedUsage, p.Name, tenantId, req.NoScopesAsCheck)
+}
+
+// chainHash identifies a set of supplied intermediates independently of their order, so that
+// a result of a request without intermediates is not returned to one that supplies them.
+func chainHash(intermediates []*cert.ParsedCert) string {
+	if len(intermediates) == 0 {
+		return ""
+	}
+	fingerprints := make([]string, len(intermediates))
+	for i, intermediate := range intermediates {
+		fingerprints[i] = intermediate.Sha256()
+	}
+	slices.Sort(fingerprints)
+	fingerprints = slices.Compact(fingerprints)
+	sum := sha256.Sum256([]byte(strings.Join(fingerprints, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheExpiry returns until when the result may be reused: no later than the
+// certificate expiry and the OCSP NextUpdate. Results with checks that could not
+// be evaluated (e.g. an unreachable OCSP responder) are not cached.
+func cacheExpiry(crt *cert.ParsedCert, checks certVerifyResponse) (time.Time, bool) {
+	if checks.hasErrors() {
+		return time.Time{}, false
+	}
+	expires := crt.Cert.NotAfter
+	if !checks.ocspNextUpdate.IsZero() && checks.ocspNextUpdate.Before(expires) {
+		expires = checks.ocspNextUpdate
+	}
+	return expires, true
+}
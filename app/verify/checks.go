@@ -0,0 +1,119 @@
+package verify
+
+import (
+	"crypto/x509"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+type certVerifyResponse struct {
+	Valid  bool
+	Reason string
+	Checks []models.Check
+
+	ocspNextUpdate time.Time           // zero if the OCSP status was not obtained
+	chain          []*x509.Certificate // verified chain, leaf first
+	ocspResponse   []byte              // raw OCSP response, nil if not obtained
+}
+
+func (r *certVerifyResponse) pass(name models.CheckName, code models.CheckCode, details string) {
+	r.Checks = append(r.Checks, models.Check{
+		Name:    name,
+		Status:  models.CheckStatusPass,
+		Code:    code,
+		Details: details,
+	})
+}
+
+// fail records a failed check and invalidates the result.
+// The first failure is kept as the human-readable reason.
+func (r *certVerifyResponse) fail(name models.CheckName, status models.CheckStatus, code models.CheckCode, reason string) {
+	r.Valid = false
+	if r.Reason == "" {
+		r.Reason = reason
+	}
+	r.Checks = append(r.Checks, models.Check{
+		Name:    name,
+		Status:  status,
+		Code:    code,
+		Details: reason,
+	})
+}
+
+// withScore attaches a similarity score to the last recorded check.
+func (r *certVerifyResponse) withScore(score float64) {
+	r.Checks[len(r.Checks)-1].Score = &score
+}
+
+// skip records checks that were not evaluated because an earlier check failed.
+func (r *certVerifyResponse) skip(names ...models.CheckName) {
+	for _, name := range names {
+		r.Checks = append(r.Checks, models.Check{
+			Name:   name,
+			Status: models.CheckStatusSkipped,
+			Code:   models.CodeSkipped,
+		})
+	}
+}
+
+// skipWith records a check that was deliberately not evaluated for the given reason code.
+func (r *certVerifyResponse) skipWith(name models.CheckName, code models.CheckCode) {
+	r.Checks = append(r.Checks, models.Check{
+		Name:   name,
+		Status: models.CheckStatusSkipped,
+		Code:   code,
+	})
+}
+
+// merge appends the checks of other, keeping the first failure as the reason.
+func (r *certVerifyResponse) merge(other certVerifyResponse) {
+	if !other.Valid {
+		r.Valid = false
+		if r.Reason == "" {
+			r.Reason = other.Reason
+		}
+	}
+	r.Checks = append(r.Checks, other.Checks...)
+	if !other.ocspNextUpdate.IsZero() {
+		r.ocspNextUpdate = other.ocspNextUpdate
+	}
+	if other.chain != nil {
+		r.chain = other.chain
+	}
+	if other.ocspResponse != nil {
+		r.ocspResponse = other.ocspResponse
+	}
+}
+
+// hasErrors reports whether any check could not be evaluated.
+func (r *certVerifyResponse) hasErrors() bool {
+	for _, check := range r.Checks {
+		if check.Status == models.CheckStatusError {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPolicy downgrades the failures of checks the policy does not make mandatory
+// to warnings and re-evaluates the validity and the reason of the result.
+func (r *certVerifyResponse) applyPolicy(p *policy.Policy) {
+	r.Valid, r.Reason = true, ""
+	for i := range r.Checks {
+		check := &r.Checks[i]
+		if check.Status != models.CheckStatusFail && check.Status != models.CheckStatusError {
+			continue
+		}
+		softRevocation := check.Name == models.CheckRevocation && check.Status == models.CheckStatusError && p.Revocation == policy.RevocationSoft
+		if softRevocation || !p.Mandatory(check.Name) {
+			check.Status = models.CheckStatusWarning
+			continue
+		}
+		r.Valid = false
+		if r.Reason == "" {
+			r.Reason = check.Details
+		}
+	}
+}
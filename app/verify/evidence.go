@@ -0,0 +1,105 @@
+package verify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+var ErrEvidenceDisabled = errors.New("evidence preservation is not enabled")
+
+// EvidenceProvider returns the evidence of earlier verifications.
+type EvidenceProvider interface {
+	GetEvidence(ctx context.Context, id string) (*models.Evidence, error)
+}
+
+var _ EvidenceProvider = (*VerifySvc)(nil)
+
+// SetEvidenceRepository enables evidence preservation. Evidence is kept for the given retention.
+func (s *VerifySvc) SetEvidenceRepository(repo db.EvidenceRepository, retention time.Duration) {
+	s.evidence = repo
+	s.evidenceRetention = retention
+}
+
+// NewId returns a random (version 4) UUID, as used for verification and job IDs.
+func NewId() string {
+	var b [16]byte
+	// crypto/rand.Read does not fail on supported platforms
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// preserveEvidence stores what the result was based on. A failure is logged
+// rather than returned, as the result itself is still correct.
+func (s *VerifySvc) preserveEvidence(ctx context.Context, req VerifyRequest, checks certVerifyResponse, tpp *models.TppResponse, p *policy.Policy, result *VerifyResponse) {
+	if s.evidence == nil {
+		return
+	}
+	now := s.clock.Now()
+	evidence := &models.Evidence{
+		Id:            result.Id,
+		Certificate:   req.Cert,
+		SuppliedChain: req.Chain,
+		OcspResponse:  checks.ocspResponse,
+		Registry:      tpp,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(s.evidenceRetention),
+	}
+	if t := TenantFromContext(ctx); t != nil {
+		evidence.TenantId = t.Id
+	}
+	for _, c := range checks.chain {
+		evidence.Chain = append(evidence.Chain, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})))
+	}
+	var err error
+	if evidence.Policy, err = json.Marshal(p); err != nil {
+		log.Printf("Failed to encode the policy of verification %s: %v", result.Id, err)
+		return
+	}
+	if evidence.Result, err = json.Marshal(result); err != nil {
+		log.Printf("Failed to encode the result of verification %s: %v", result.Id, err)
+		return
+	}
+	if err := s.evidence.AddEvidence(ctx, evidence); err != nil {
+		log.Printf("Failed to store the evidence of verification %s: %v", result.Id, err)
+	}
+}
+
+// GetEvidence returns the evidence of a verification. Tenants only see the evidence
+// of their own verifications.
+func (s *VerifySvc) GetEvidence(ctx context.Context, id string) (*models.Evidence, error) {
+	if s.evidence == nil {
+		return nil, ErrEvidenceDisabled
+	}
+	evidence, err := s.evidence.GetEvidence(ctx, id, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	tenantId := ""
+	if t := TenantFromContext(ctx); t != nil {
+		tenantId = t.Id
+	}
+	if tenantId != "" && evidence.TenantId != tenantId {
+		return nil, db.ErrEvidenceNotFound
+	}
+	return evidence, nil
+}
+
+// PurgeEvidence removes expired evidence.
+func (s *VerifySvc) PurgeEvidence(ctx context.Context) (int64, error) {
+	if s.evidence == nil {
+		return 0, ErrEvidenceDisabled
+	}
+	return s.evidence.DeleteExpiredEvidence(ctx, s.clock.Now())
+}
@@ -0,0 +1,113 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/clock"
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+type memoryEvidence struct {
+	mu       sync.Mutex
+	evidence map[string]*models.Evidence
+}
+
+func (m *memoryEvidence) AddEvidence(ctx context.Context, evidence *models.Evidence) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.evidence == nil {
+		m.evidence = make(map[string]*models.Evidence)
+	}
+	m.evidence[evidence.Id] = evidence
+	return nil
+}
+
+func (m *memoryEvidence) GetEvidence(ctx context.Context, id string, now time.Time) (*models.Evidence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	evidence, ok := m.evidence[id]
+	if !ok || !now.Before(evidence.ExpiresAt) {
+		return nil, db.ErrEvidenceNotFound
+	}
+	return evidence, nil
+}
+
+func (m *memoryEvidence) DeleteExpiredEvidence(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, evidence := range m.evidence {
+		if !before.Before(evidence.ExpiresAt) {
+			delete(m.evidence, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestVerify_Evidence(t *testing.T) {
+	now := time.Now()
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	svc := newProductionSvc(t, NewMockDb(), httpClient)
+	svc.SetClock(clock.Fixed(now))
+	if _, err := svc.GetEvidence(context.Background(), "any"); !errors.Is(err, ErrEvidenceDisabled) {
+		t.Fatalf("Expected evidence to be disabled, got %v", err)
+	}
+
+	repo := &memoryEvidence{}
+	svc.SetEvidenceRepository(repo, 24*time.Hour)
+	ctx := ContextWithTenant(context.Background(), &Tenant{Id: "retail"})
+	leaf := readTestFile(t, "chains/production/leaf.pem")
+	res, err := svc.Verify(ctx, VerifyRequest{Cert: leaf})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Id == "" {
+		t.Fatal("Expected a verification ID")
+	}
+
+	evidence, err := svc.GetEvidence(ctx, res.Id)
+	if err != nil {
+		t.Fatalf("Expected evidence, got %v", err)
+	}
+	if evidence.Certificate != leaf || evidence.TenantId != "retail" {
+		t.Errorf("Expected the submitted certificate of tenant retail, got %+v", evidence)
+	}
+	if len(evidence.Chain) != 3 {
+		t.Errorf("Expected a chain of 3 certificates, got %d", len(evidence.Chain))
+	}
+	if len(evidence.OcspResponse) == 0 {
+		t.Error("Expected the OCSP response")
+	}
+	if evidence.Registry == nil || evidence.Registry.Id != "PSDFIN-FINFSA-12345678" {
+		t.Errorf("Expected the registry record, got %+v", evidence.Registry)
+	}
+	var result VerifyResponse
+	if err := json.Unmarshal(evidence.Result, &result); err != nil || result.Id != res.Id || result.Valid != res.Valid {
+		t.Errorf("Expected the result of verification %s, got %s (%v)", res.Id, evidence.Result, err)
+	}
+	var p struct{ Name string }
+	if err := json.Unmarshal(evidence.Policy, &p); err != nil || p.Name != "default" {
+		t.Errorf("Expected the default policy, got %s (%v)", evidence.Policy, err)
+	}
+
+	other := ContextWithTenant(context.Background(), &Tenant{Id: "corporate"})
+	if _, err := svc.GetEvidence(other, res.Id); !errors.Is(err, db.ErrEvidenceNotFound) {
+		t.Errorf("Expected the evidence to be hidden from other tenants, got %v", err)
+	}
+
+	svc.SetClock(clock.Fixed(now.Add(25 * time.Hour)))
+	if _, err := svc.GetEvidence(ctx, res.Id); !errors.Is(err, db.ErrEvidenceNotFound) {
+		t.Errorf("Expected the evidence to expire, got %v", err)
+	}
+	if n, err := svc.PurgeEvidence(ctx); err != nil || n != 1 {
+		t.Errorf("Expected 1 purged bundle, got %d (%v)", n, err)
+	}
+}
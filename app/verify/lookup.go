@@ -0,0 +1,30 @@
+package verify
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// lookupTimeout bounds a shared AIA or OCSP lookup, which no longer ends with the request
+// that started it.
+const lookupTimeout = 30 * time.Second
+
+// sharedLookup runs fn once for the concurrent callers with the same key. fn does not run
+// with the context of the caller that started it, so that a caller that disconnects or times
+// out does not fail the others; each caller still returns once its own context is done.
+func sharedLookup[T any](ctx context.Context, g *singleflight.Group, key string, fn func(ctx context.Context) (T, error)) (T, bool, error) {
+	ch := g.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lookupTimeout)
+		defer cancel()
+		return fn(ctx)
+	})
+	select {
+	case res := <-ch:
+		return res.Val.(T), res.Shared, res.Err
+	case <-ctx.Done():
+		var zero T
+		return zero, false, ctx.Err()
+	}
+}
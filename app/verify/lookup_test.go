@@ -0,0 +1,58 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+func TestSharedLookup(t *testing.T) {
+	var g singleflight.Group
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	lookup := func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return "response", ctx.Err()
+	}
+
+	// The first caller gives up while the lookup is in progress
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, _, err := sharedLookup(ctx, &g, "ocsp", lookup)
+		first <- err
+	}()
+	<-started
+	type result struct {
+		res    string
+		shared bool
+		err    error
+	}
+	second := make(chan result)
+	go func() {
+		res, shared, err := sharedLookup(context.Background(), &g, "ocsp", lookup)
+		second <- result{res, shared, err}
+	}()
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the first caller to be canceled, got %v", err)
+	}
+
+	// Let the second caller join the lookup before it completes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	got := <-second
+	if got.err != nil || got.res != "response" || !got.shared {
+		t.Errorf("Expected the shared response, got %+v", got)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected a single lookup, got %d", n)
+	}
+}
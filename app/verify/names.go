@@ -0,0 +1,162 @@
+package verify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// nameMatchThreshold is the similarity below which the certificate subject
+// organization is reported as not matching the registry name.
+const nameMatchThreshold = 0.85
+
+// legalForms are removed from organization names before comparing them,
+// as certificates and registries rarely agree on whether and how to spell them.
+var legalForms = map[string]bool{
+	"ab": true, "ad": true, "ag": true, "aps": true, "as": true, "asa": true, "bv": true,
+	"co": true, "company": true, "corp": true, "corporation": true, "cv": true, "doo": true,
+	"eg": true, "ehf": true, "eood": true, "ev": true, "gmbh": true, "hf": true, "inc": true,
+	"kft": true, "kg": true, "ltd": true, "limited": true, "llc": true, "llp": true,
+	"nv": true, "nyrt": true, "oy": true, "oyj": true, "ou": true, "plc": true,
+	"sa": true, "sarl": true, "sas": true, "se": true, "sia": true, "spa": true, "sro": true,
+	"srl": true, "uab": true, "zrt": true, "ooo": true, "ltda": true, "lda": true,
+}
+
+// legalFormPhrases are legal forms spelled as several words, e.g. "Sp. z o.o.".
+var legalFormPhrases = []string{"sp z oo", "spolka z oo"}
+
+// transliterations covers letters that are not decomposed into a base letter and
+// a diacritic, and the Cyrillic and Greek alphabets used in native registry names.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'ø': "o", 'œ': "oe", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sht",
+	'ъ': "a", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi",
+	'ґ': "g", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ђ': "d",
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// normalizeName lower-cases the name, transliterates it to ASCII letters and digits
+// and drops punctuation and legal-form suffixes, e.g. "Bänk-Grüße Oy" becomes "bank grusse".
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		if latin, ok := transliterations[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// diacritic of the preceding letter
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case r == '&':
+			b.WriteString(" and ")
+		case r == '.' || r == '\'':
+			// abbreviations such as "Oy." or "S.A." are joined
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	normalized := strings.Join(strings.Fields(b.String()), " ")
+	for _, phrase := range legalFormPhrases {
+		if trimmed, ok := strings.CutSuffix(normalized, " "+phrase); ok {
+			normalized = trimmed
+		}
+	}
+	words := strings.Fields(normalized)
+	// Only trailing legal forms are removed, so that "AB Bank" keeps its distinctive part
+	for len(words) > 1 && legalForms[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// nameSimilarity returns the similarity of two names between 0 and 1,
+// based on the edit distance of their normalized forms.
+func nameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	ar, br := []rune(a), []rune(b)
+	return 1 - float64(levenshtein(ar, br))/float64(max(len(ar), len(br)))
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// checkSubject compares the subject organization and country of the certificate with
+// the registry record. A mismatch may point at an organization identifier of another TPP;
+// the default policy only reports it as a warning.
+func checkSubject(res *certVerifyResponse, crt *cert.ParsedCert, tpp *models.TppResponse) {
+	if tpp == nil {
+		res.skip(models.CheckSubjectName, models.CheckSubjectCountry)
+		return
+	}
+	organizations := crt.Cert.Subject.Organization
+	if len(organizations) == 0 || (tpp.NameLatin == "" && tpp.NameNative == "") {
+		res.skip(models.CheckSubjectName)
+	} else {
+		// The best match of any subject organization and the Latin or native registry name is used
+		score, registryName := -1.0, ""
+		for _, organization := range organizations {
+			for _, name := range []string{tpp.NameLatin, tpp.NameNative} {
+				if name == "" {
+					continue
+				}
+				if s := nameSimilarity(organization, name); s > score {
+					score, registryName = s, name
+				}
+			}
+		}
+		if score >= nameMatchThreshold {
+			res.pass(models.CheckSubjectName, models.CodeNameMatch, registryName)
+		} else {
+			log.Printf("Subject organization %q does not match the registry name %q (similarity %.2f)", organizations[0], registryName, score)
+			res.fail(models.CheckSubjectName, models.CheckStatusFail, models.CodeNameMismatch,
+				fmt.Sprintf("Subject organization %q does not match the registry name %q", organizations[0], registryName))
+		}
+		res.withScore(score)
+	}
+
+	countries := crt.Cert.Subject.Country
+	if len(countries) == 0 || tpp.Country == "" {
+		res.skip(models.CheckSubjectCountry)
+	} else if normalizeCountry(countries[0]) != normalizeCountry(tpp.Country) {
+		log.Printf("Subject country %s does not match the registry country %s", countries[0], tpp.Country)
+		res.fail(models.CheckSubjectCountry, models.CheckStatusFail, models.CodeSubjectCountryMismatch,
+			fmt.Sprintf("Subject country %q does not match the registry country %q", countries[0], tpp.Country))
+	} else {
+		res.pass(models.CheckSubjectCountry, models.CodeSubjectCountryMatch, tpp.Country)
+	}
+}
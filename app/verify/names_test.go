@@ -0,0 +1,94 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Some Company Name Oy", "some company name"},
+		{"SOME COMPANY NAME OYJ.", "some company name"},
+		{"Bänk-Grüße GmbH", "bank grusse"},
+		{"Płatności Sp. z o.o.", "platnosci"},
+		{"Smith & Sons Ltd", "smith and sons"},
+		{"S.A.", "sa"},
+		{"Тинькофф Банк АО", "tinkoff bank ao"},
+		{"Τράπεζα Πειραιώς Α.Ε.", "trapeza peiraios ae"},
+	}
+	for _, tt := range tests {
+		if got := normalizeName(tt.name); got != tt.expected {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.name, got)
+		}
+	}
+}
+
+func TestCheckSubject(t *testing.T) {
+	certs, err := cert.ParseCerts([]byte(readTestFile(t, "chains/production/leaf.pem")))
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	// The subject of the leaf is O=Some Company Name, C=FI
+	tests := []struct {
+		name    string
+		tpp     *models.TppResponse
+		codes   map[models.CheckName]models.CheckCode
+		minimum float64
+		maximum float64
+	}{
+		{"Exact match", &models.TppResponse{NameLatin: "Some Company Name", Country: "FI"}, map[models.CheckName]models.CheckCode{
+			models.CheckSubjectName:    models.CodeNameMatch,
+			models.CheckSubjectCountry: models.CodeSubjectCountryMatch,
+		}, 1, 1},
+		{"Legal form and case", &models.TppResponse{NameLatin: "SOME COMPANY NAME OY", Country: "FI"}, map[models.CheckName]models.CheckCode{
+			models.CheckSubjectName: models.CodeNameMatch,
+		}, 1, 1},
+		{"Native name", &models.TppResponse{NameLatin: "Other Payments", NameNative: "Somé Compány Namé Oy", Country: "FI"}, map[models.CheckName]models.CheckCode{
+			models.CheckSubjectName: models.CodeNameMatch,
+		}, 1, 1},
+		{"Typo", &models.TppResponse{NameLatin: "Some Compny Name", Country: "FI"}, map[models.CheckName]models.CheckCode{
+			models.CheckSubjectName: models.CodeNameMatch,
+		}, nameMatchThreshold, 1},
+		{"Other organization", &models.TppResponse{NameLatin: "Test TPP", Country: "SE"}, map[models.CheckName]models.CheckCode{
+			models.CheckSubjectName:    models.CodeNameMismatch,
+			models.CheckSubjectCountry: models.CodeSubjectCountryMismatch,
+		}, 0, nameMatchThreshold},
+		{"No registry record", nil, map[models.CheckName]models.CheckCode{
+			models.CheckSubjectName:    models.CodeSkipped,
+			models.CheckSubjectCountry: models.CodeSkipped,
+		}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := certVerifyResponse{Valid: true}
+			checkSubject(&res, certs[0], tt.tpp)
+			res.applyPolicy(policy.Default())
+			if !res.Valid {
+				t.Errorf("Expected subject mismatches not to invalidate the result, got %s", res.Reason)
+			}
+			for name, code := range tt.codes {
+				if check := findCheck(res.Checks, name); check == nil || check.Code != code {
+					t.Errorf("Expected %s check with code %s, got %+v", name, code, check)
+				} else if check.Status == models.CheckStatusFail {
+					t.Errorf("Expected %s check to be a warning, got %+v", name, check)
+				}
+			}
+			check := findCheck(res.Checks, models.CheckSubjectName)
+			if tt.tpp == nil {
+				if check.Score != nil {
+					t.Errorf("Expected no score, got %v", *check.Score)
+				}
+				return
+			}
+			if check.Score == nil || *check.Score < tt.minimum || *check.Score > tt.maximum {
+				t.Errorf("Expected score between %v and %v, got %v", tt.minimum, tt.maximum, check.Score)
+			}
+		})
+	}
+}
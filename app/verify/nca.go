@@ -0,0 +1,124 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// iso3166Alpha3 maps the alpha-3 codes of EEA countries to alpha-2.
+// ETSI TS 119 495 requires alpha-2 codes in the organization identifier,
+// but some issuers use alpha-3 codes (e.g. PSDFIN-FINFSA-...).
+var iso3166Alpha3 = map[string]string{
+	"AUT": "AT", "BEL": "BE", "BGR": "BG", "HRV": "HR", "CYP": "CY", "CZE": "CZ",
+	"DNK": "DK", "EST": "EE", "FIN": "FI", "FRA": "FR", "DEU": "DE", "GRC": "GR",
+	"HUN": "HU", "ISL": "IS", "IRL": "IE", "ITA": "IT", "LVA": "LV", "LIE": "LI",
+	"LTU": "LT", "LUX": "LU", "MLT": "MT", "NLD": "NL", "NOR": "NO", "POL": "PL",
+	"PRT": "PT", "ROU": "RO", "SVK": "SK", "SVN": "SI", "ESP": "ES", "SWE": "SE",
+	"GBR": "GB",
+}
+
+// normalizeCountry returns the alpha-2 code of the country.
+func normalizeCountry(country string) string {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if alpha2, ok := iso3166Alpha3[country]; ok {
+		return alpha2
+	}
+	if country == "EL" {
+		// Greece is EL in EU publications, including the EBA register
+		return "GR"
+	}
+	return country
+}
+
+// normalizeAuthority removes the dashes the registry strips as well, e.g. FIN-FSA becomes FINFSA.
+func normalizeAuthority(authority string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(authority), "-", ""))
+}
+
+// parseOrganizationNca returns the country and the NCA of a PSD2 organization identifier
+// in the format PSD{country}-{authority}-{id}.
+func parseOrganizationNca(organizationId string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(organizationId, "PSD")
+	if !ok {
+		return "", "", false
+	}
+	parts := strings.SplitN(rest, "-", 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return normalizeCountry(parts[0]), normalizeAuthority(parts[1]), true
+}
+
+// checkNca checks that the NCA of the PSD2 QcStatement agrees with the organization
+// identifier and, if available, with the registry record of the TPP.
+// Each mismatch is reported as a separate check.
+func checkNca(res *certVerifyResponse, crt *cert.ParsedCert, tpp *models.TppResponse, registryChecked bool) error {
+	nca, err := crt.NCA()
+	if errors.Is(err, cert.ErrInvalidNcaId) {
+		log.Printf("Invalid QcStatement NCA: %v", err)
+		res.fail(models.CheckNcaOrganization, models.CheckStatusFail, models.CodeNcaInvalid, err.Error())
+		res.skip(models.CheckNcaAuthority, models.CheckNcaCountry)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if nca == nil {
+		// Not a PSD2 certificate, reported by the scope check
+		res.skip(models.CheckNcaOrganization, models.CheckNcaAuthority, models.CheckNcaCountry)
+		return nil
+	}
+	ncaCountry, ncaAuthority, found := strings.Cut(nca.Id, "-")
+	if !found {
+		ncaAuthority = ncaCountry
+	}
+	ncaCountry, ncaAuthority = normalizeCountry(ncaCountry), normalizeAuthority(ncaAuthority)
+
+	orgCountry, orgAuthority, ok := parseOrganizationNca(crt.CompanyId())
+	switch {
+	case !ok:
+		log.Printf("Organization identifier %q is not a PSD2 identifier", crt.CompanyId())
+		res.fail(models.CheckNcaOrganization, models.CheckStatusFail, models.CodeNcaOrganizationMismatch,
+			fmt.Sprintf("Organization identifier %q is not a PSD2 identifier", crt.CompanyId()))
+	case orgCountry != ncaCountry || orgAuthority != ncaAuthority:
+		log.Printf("QcStatement NCA %s does not match the organization identifier %s", nca.Id, crt.CompanyId())
+		res.fail(models.CheckNcaOrganization, models.CheckStatusFail, models.CodeNcaOrganizationMismatch,
+			fmt.Sprintf("QcStatement NCA %q does not match the organization identifier %q", nca.Id, crt.CompanyId()))
+	default:
+		res.pass(models.CheckNcaOrganization, models.CodeNcaOrganizationMatch, nca.Id)
+	}
+
+	if !registryChecked {
+		res.skipWith(models.CheckNcaAuthority, models.CodeRegistryCheckDisabled)
+		res.skipWith(models.CheckNcaCountry, models.CodeRegistryCheckDisabled)
+		return nil
+	}
+	if tpp == nil {
+		res.skip(models.CheckNcaAuthority, models.CheckNcaCountry)
+		return nil
+	}
+	if tpp.Authority == "" {
+		res.skip(models.CheckNcaAuthority)
+	} else if normalizeAuthority(tpp.Authority) != ncaAuthority {
+		log.Printf("QcStatement NCA %s does not match the registry authority %s", nca.Id, tpp.Authority)
+		res.fail(models.CheckNcaAuthority, models.CheckStatusFail, models.CodeNcaAuthorityMismatch,
+			fmt.Sprintf("QcStatement NCA %q does not match the registry authority %q", nca.Id, tpp.Authority))
+	} else {
+		res.pass(models.CheckNcaAuthority, models.CodeNcaAuthorityMatch, tpp.Authority)
+	}
+	if tpp.Country == "" {
+		res.skip(models.CheckNcaCountry)
+	} else if normalizeCountry(tpp.Country) != ncaCountry {
+		log.Printf("QcStatement NCA %s does not match the registry country %s", nca.Id, tpp.Country)
+		res.fail(models.CheckNcaCountry, models.CheckStatusFail, models.CodeNcaCountryMismatch,
+			fmt.Sprintf("QcStatement NCA %q does not match the registry country %q", nca.Id, tpp.Country))
+	} else {
+		res.pass(models.CheckNcaCountry, models.CodeNcaCountryMatch, tpp.Country)
+	}
+	return nil
+}
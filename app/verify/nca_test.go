@@ -0,0 +1,120 @@
+package verify
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+func TestCheckNca(t *testing.T) {
+	certs, err := cert.ParseCerts([]byte(readTestFile(t, "chains/production/leaf.pem")))
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	leaf := certs[0]
+	// Same FI-FINFSA QcStatement, but issued to an organization supervised by BaFin
+	var qcStatements pkix.Extension
+	for _, ext := range leaf.Cert.Extensions {
+		if ext.Id.Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 3}) {
+			qcStatements = ext
+		}
+	}
+	bafin := newOrganizationCert(t, "PSDDE-BAFIN-123456", qcStatements)
+	// The PSD2 QcStatement of a certificate with the given NCA identifier
+	psd2Statement := func(ncaId string) pkix.Extension {
+		psd2, err := asn1.Marshal(cert.PSD2QcType{RolesOfPSP: []cert.Role{}, NCAName: "Finnish Financial Supervisory Authority", NCAId: ncaId})
+		if err != nil {
+			t.Fatalf("Failed to marshal QcStatement: %v", err)
+		}
+		value, err := asn1.Marshal([]cert.QCStatement{{ID: asn1.ObjectIdentifier{0, 4, 0, 19495, 2}, Value: asn1.RawValue{FullBytes: psd2}}})
+		if err != nil {
+			t.Fatalf("Failed to marshal QcStatements: %v", err)
+		}
+		return pkix.Extension{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 3}, Value: value}
+	}
+	tpp := &models.TppResponse{Id: "PSDFIN-FINFSA-12345678", Authority: "FINFSA", Country: "FI"}
+	tests := []struct {
+		name     string
+		crt      *cert.ParsedCert
+		tpp      *models.TppResponse
+		registry bool
+		valid    bool
+		codes    map[models.CheckName]models.CheckCode
+	}{
+		{"Consistent", leaf, tpp, true, true, map[models.CheckName]models.CheckCode{
+			models.CheckNcaOrganization: models.CodeNcaOrganizationMatch,
+			models.CheckNcaAuthority:    models.CodeNcaAuthorityMatch,
+			models.CheckNcaCountry:      models.CodeNcaCountryMatch,
+		}},
+		{"Organization identifier of another NCA", bafin, tpp, true, false, map[models.CheckName]models.CheckCode{
+			models.CheckNcaOrganization: models.CodeNcaOrganizationMismatch,
+			models.CheckNcaAuthority:    models.CodeNcaAuthorityMatch,
+		}},
+		{"Registry authority differs", leaf, &models.TppResponse{Authority: "BAFIN", Country: "FI"}, true, false, map[models.CheckName]models.CheckCode{
+			models.CheckNcaOrganization: models.CodeNcaOrganizationMatch,
+			models.CheckNcaAuthority:    models.CodeNcaAuthorityMismatch,
+			models.CheckNcaCountry:      models.CodeNcaCountryMatch,
+		}},
+		{"Registry country differs", leaf, &models.TppResponse{Authority: "FIN-FSA", Country: "SE"}, true, false, map[models.CheckName]models.CheckCode{
+			models.CheckNcaAuthority: models.CodeNcaAuthorityMatch,
+			models.CheckNcaCountry:   models.CodeNcaCountryMismatch,
+		}},
+		{"Registry check disabled", leaf, nil, false, true, map[models.CheckName]models.CheckCode{
+			models.CheckNcaOrganization: models.CodeNcaOrganizationMatch,
+			models.CheckNcaAuthority:    models.CodeRegistryCheckDisabled,
+			models.CheckNcaCountry:      models.CodeRegistryCheckDisabled,
+		}},
+		{"Empty NCA identifier", newOrganizationCert(t, "PSDFI-FINFSA-12345678", psd2Statement("")), tpp, true, false, map[models.CheckName]models.CheckCode{
+			models.CheckNcaOrganization: models.CodeNcaInvalid,
+			models.CheckNcaAuthority:    models.CodeSkipped,
+		}},
+		{"NCA identifier without country", newOrganizationCert(t, "PSDFI-FINFSA-12345678", psd2Statement("F")), tpp, true, false, map[models.CheckName]models.CheckCode{
+			models.CheckNcaOrganization: models.CodeNcaInvalid,
+			models.CheckNcaCountry:      models.CodeSkipped,
+		}},
+		{"Not a PSD2 certificate", newOrganizationCert(t, "PSDFI-FINFSA-12345678"), tpp, true, true, map[models.CheckName]models.CheckCode{
+			models.CheckNcaOrganization: models.CodeSkipped,
+			models.CheckNcaAuthority:    models.CodeSkipped,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := certVerifyResponse{Valid: true}
+			if err := checkNca(&res, tt.crt, tt.tpp, tt.registry); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v: %s", tt.valid, res.Valid, res.Reason)
+			}
+			for name, code := range tt.codes {
+				if check := findCheck(res.Checks, name); check == nil || check.Code != code {
+					t.Errorf("Expected %s check with code %s, got %+v", name, code, check)
+				}
+			}
+		})
+	}
+}
+
+func TestParseOrganizationNca(t *testing.T) {
+	tests := []struct {
+		id        string
+		country   string
+		authority string
+		ok        bool
+	}{
+		{"PSDFI-FINFSA-12345678", "FI", "FINFSA", true},
+		{"PSDFIN-FINFSA-1234567-8", "FI", "FINFSA", true},
+		{"PSDDE-BAFIN-123456", "DE", "BAFIN", true},
+		{"NTRFI-12345678", "", "", false},
+		{"PSDFI-FINFSA", "", "", false},
+	}
+	for _, tt := range tests {
+		country, authority, ok := parseOrganizationNca(tt.id)
+		if country != tt.country || authority != tt.authority || ok != tt.ok {
+			t.Errorf("Expected %s, %s, %v for %s, got %s, %s, %v", tt.country, tt.authority, tt.ok, tt.id, country, authority, ok)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// PairVerifier verifies the QWAC and QSealC presented by the same TPP.
+type PairVerifier interface {
+	VerifyPair(ctx context.Context, req PairVerifyRequest) (*PairVerifyResponse, error)
+}
+
+var _ PairVerifier = (*VerifySvc)(nil)
+
+// PairCert holds one certificate of a pair and its optional intermediates.
+type PairCert struct {
+	Cert  string   `json:"cert"`
+	Chain []string `json:"chain,omitempty"`
+}
+
+// PairVerifyRequest holds the QWAC presented at the TLS layer and the QSealC
+// used for message signing. The options apply to both certificates.
+type PairVerifyRequest struct {
+	QWAC          PairCert   `json:"qwac"`
+	QSealC        PairCert   `json:"qsealc"`
+	RegistryCheck *bool      `json:"registry_check,omitempty"`
+	AspspCountry  string     `json:"aspsp_country,omitempty"`
+	At            *time.Time `json:"at,omitempty"`
+	NoCache       bool       `json:"no_cache,omitempty"`
+	// NoScopesAsCheck is passed to both verifications, see VerifyRequest.
+	NoScopesAsCheck bool `json:"-"`
+}
+
+func (r PairVerifyRequest) verifyRequest(c PairCert, usage models.CertUsage) VerifyRequest {
+	return VerifyRequest{
+		Cert:            c.Cert,
+		Chain:           c.Chain,
+		RegistryCheck:   r.RegistryCheck,
+		AspspCountry:    r.AspspCountry,
+		At:              r.At,
+		NoCache:         r.NoCache,
+		ExpectedUsage:   usage,
+		NoScopesAsCheck: r.NoScopesAsCheck,
+	}
+}
+
+// PairVerifyResponse combines the results of both certificates. Valid is only
+// true if both certificates are valid and consistent with each other.
+type PairVerifyResponse struct {
+	QWAC       *VerifyResponse `json:"qwac"`
+	QSealC     *VerifyResponse `json:"qsealc"`
+	Valid      bool            `json:"valid"`
+	Reason     string          `json:"reason,omitempty"`
+	Checks     []models.Check  `json:"checks"` // consistency checks between the certificates
+	VerifiedAt time.Time       `json:"verified_at"`
+	Policy     string          `json:"policy"`
+}
+
+func (s *VerifySvc) VerifyPair(ctx context.Context, req PairVerifyRequest) (*PairVerifyResponse, error) {
+	qwacReq := req.verifyRequest(req.QWAC, models.QWAC)
+	qsealReq := req.verifyRequest(req.QSealC, models.QSEAL)
+	qwacCert, _, err := parseRequestCerts(qwacReq)
+	if err != nil {
+		return nil, fmt.Errorf("qwac: %w", err)
+	}
+	qsealCert, _, err := parseRequestCerts(qsealReq)
+	if err != nil {
+		return nil, fmt.Errorf("qsealc: %w", err)
+	}
+
+	var (
+		wg                sync.WaitGroup
+		qwacRes, qsealRes *VerifyResponse
+		qwacErr, qsealErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		qwacRes, qwacErr = s.Verify(ctx, qwacReq)
+	}()
+	go func() {
+		defer wg.Done()
+		qsealRes, qsealErr = s.Verify(ctx, qsealReq)
+	}()
+	wg.Wait()
+	if qwacErr != nil {
+		return nil, fmt.Errorf("qwac: %w", qwacErr)
+	}
+	if qsealErr != nil {
+		return nil, fmt.Errorf("qsealc: %w", qsealErr)
+	}
+
+	checks, err := checkPairConsistency(qwacCert, qsealCert, qwacRes.TPP, qsealRes.TPP)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCertificateParse, err)
+	}
+	p, _ := s.settings(ctx)
+	checks.applyPolicy(p)
+	res := &PairVerifyResponse{
+		QWAC:       qwacRes,
+		QSealC:     qsealRes,
+		Valid:      qwacRes.Valid && qsealRes.Valid && checks.Valid,
+		Checks:     checks.Checks,
+		VerifiedAt: qwacRes.VerifiedAt,
+		Policy:     qwacRes.Policy,
+	}
+	switch {
+	case !qwacRes.Valid:
+		res.Reason = "QWAC: " + qwacRes.Reason
+	case !qsealRes.Valid:
+		res.Reason = "QSealC: " + qsealRes.Reason
+	default:
+		res.Reason = checks.Reason
+	}
+	return res, nil
+}
+
+// checkPairConsistency checks that both certificates were issued to the same
+// organization by the same NCA and map to the same registry TPP.
+func checkPairConsistency(qwac, qseal *cert.ParsedCert, qwacTpp, qsealTpp *models.TppResponse) (certVerifyResponse, error) {
+	res := certVerifyResponse{Valid: true}
+
+	qwacId, qsealId := normalizeTppId(qwac.CompanyId()), normalizeTppId(qseal.CompanyId())
+	if qwacId == "" || qwacId != qsealId {
+		log.Printf("Organization identifiers of the QWAC %q and QSealC %q differ", qwacId, qsealId)
+		res.fail(models.CheckPairOrganization, models.CheckStatusFail, models.CodeOrganizationMismatch,
+			fmt.Sprintf("Organization identifiers differ: QWAC %q, QSealC %q", qwacId, qsealId))
+	} else {
+		res.pass(models.CheckPairOrganization, models.CodeOrganizationMatch, qwacId)
+	}
+
+	qwacNca, err := qwac.NCA()
+	if err != nil {
+		return res, err
+	}
+	qsealNca, err := qseal.NCA()
+	if err != nil {
+		return res, err
+	}
+	switch {
+	case qwacNca == nil && qsealNca == nil:
+		res.skip(models.CheckPairNca)
+	case qwacNca == nil || qsealNca == nil || qwacNca.Id != qsealNca.Id:
+		log.Printf("NCAs of the QWAC %v and QSealC %v differ", qwacNca, qsealNca)
+		res.fail(models.CheckPairNca, models.CheckStatusFail, models.CodeNcaMismatch,
+			fmt.Sprintf("NCAs differ: QWAC %q, QSealC %q", ncaId(qwacNca), ncaId(qsealNca)))
+	default:
+		res.pass(models.CheckPairNca, models.CodeNcaMatch, qwacNca.Id)
+	}
+
+	switch {
+	case qwacTpp == nil || qsealTpp == nil:
+		// Registry check disabled or TPP not found, already reported per certificate
+		res.skip(models.CheckPairTpp)
+	case qwacTpp.Id != qsealTpp.Id:
+		log.Printf("Registry TPPs of the QWAC %s and QSealC %s differ", qwacTpp.Id, qsealTpp.Id)
+		res.fail(models.CheckPairTpp, models.CheckStatusFail, models.CodeTppMismatch,
+			fmt.Sprintf("Registry TPPs differ: QWAC %q, QSealC %q", qwacTpp.Id, qsealTpp.Id))
+	default:
+		res.pass(models.CheckPairTpp, models.CodeTppMatch, qwacTpp.Id)
+	}
+	return res, nil
+}
+
+func ncaId(nca *cert.NCA) string {
+	if nca == nil {
+		return ""
+	}
+	return nca.Id
+}
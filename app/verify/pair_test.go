@@ -0,0 +1,159 @@
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+// newOrganizationCert returns a self-signed certificate with the given organization identifier.
+func newOrganizationCert(t *testing.T, organizationId string, extensions ...pkix.Extension) *cert.ParsedCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "other.example.com",
+			ExtraNames: []pkix.AttributeTypeAndValue{{Type: asn1.ObjectIdentifier{2, 5, 4, 97}, Value: organizationId}},
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+		KeyUsage:  x509.KeyUsageContentCommitment,
+
+		ExtraExtensions: extensions,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	crt, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return &cert.ParsedCert{Cert: crt}
+}
+
+func TestCheckPairConsistency(t *testing.T) {
+	parse := func(relPath string) *cert.ParsedCert {
+		certs, err := cert.ParseCerts([]byte(readTestFile(t, relPath)))
+		if err != nil {
+			t.Fatalf("Failed to parse certificate: %v", err)
+		}
+		return certs[0]
+	}
+	qwac, qseal := parse("chains/production/qwac.pem"), parse("chains/production/leaf.pem")
+	other := newOrganizationCert(t, "PSDDE-BAFIN-999999")
+	tpp := &models.TppResponse{Id: "PSDFIN-FINFSA-12345678"}
+	otherTpp := &models.TppResponse{Id: "PSDDE-BAFIN-999999"}
+	tests := []struct {
+		name     string
+		qseal    *cert.ParsedCert
+		qsealTpp *models.TppResponse
+		valid    bool
+		codes    map[models.CheckName]models.CheckCode
+	}{
+		{"Consistent", qseal, tpp, true, map[models.CheckName]models.CheckCode{
+			models.CheckPairOrganization: models.CodeOrganizationMatch,
+			models.CheckPairNca:          models.CodeNcaMatch,
+			models.CheckPairTpp:          models.CodeTppMatch,
+		}},
+		{"Other organization", other, otherTpp, false, map[models.CheckName]models.CheckCode{
+			models.CheckPairOrganization: models.CodeOrganizationMismatch,
+			models.CheckPairNca:          models.CodeNcaMismatch,
+			models.CheckPairTpp:          models.CodeTppMismatch,
+		}},
+		{"No registry record", qseal, nil, true, map[models.CheckName]models.CheckCode{
+			models.CheckPairOrganization: models.CodeOrganizationMatch,
+			models.CheckPairTpp:          models.CodeSkipped,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := checkPairConsistency(qwac, tt.qseal, tpp, tt.qsealTpp)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v: %s", tt.valid, res.Valid, res.Reason)
+			}
+			for name, code := range tt.codes {
+				if check := findCheck(res.Checks, name); check == nil || check.Code != code {
+					t.Errorf("Expected %s check with code %s, got %+v", name, code, check)
+				}
+			}
+		})
+	}
+
+	res, err := checkPairConsistency(qwac, other, tpp, otherTpp)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	res.applyPolicy(&policy.Policy{Name: "test", Checks: map[models.CheckName]policy.Enforcement{
+		models.CheckPairOrganization: policy.Warning,
+		models.CheckPairNca:          policy.Warning,
+		models.CheckPairTpp:          policy.Warning,
+	}})
+	if !res.Valid {
+		t.Errorf("Expected pair checks downgraded to warnings to be valid, got %s", res.Reason)
+	}
+	if check := findCheck(res.Checks, models.CheckPairTpp); check == nil || check.Status != models.CheckStatusWarning {
+		t.Errorf("Expected a pair_tpp warning, got %+v", check)
+	}
+}
+
+func TestVerifyPair(t *testing.T) {
+	qwac, qseal := readTestFile(t, "chains/production/qwac.pem"), readTestFile(t, "chains/production/leaf.pem")
+	tests := []struct {
+		name   string
+		req    PairVerifyRequest
+		valid  bool
+		reason string
+	}{
+		{"Valid pair", PairVerifyRequest{QWAC: PairCert{Cert: qwac}, QSealC: PairCert{Cert: qseal}}, true, ""},
+		{"Swapped certificates", PairVerifyRequest{QWAC: PairCert{Cert: qseal}, QSealC: PairCert{Cert: qwac}}, false, "QWAC: Certificate usage QSEAL does not match the expected QWAC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := NewMockHttpClient()
+			httpClient.SetChainPath(getTestDataPath("chains/production"))
+			svc := newProductionSvc(t, NewMockDb(), httpClient)
+			res, err := svc.VerifyPair(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v: %s", tt.valid, res.Valid, res.Reason)
+			}
+			if res.Reason != tt.reason {
+				t.Errorf("Expected reason %q, got %q", tt.reason, res.Reason)
+			}
+			if res.QWAC == nil || res.QSealC == nil {
+				t.Fatalf("Expected results for both certificates, got %+v", res)
+			}
+			if !tt.valid {
+				if check := findCheck(res.QWAC.Checks, models.CheckUsage); check == nil || check.Code != models.CodeUsageMismatch {
+					t.Errorf("Expected usage mismatch, got %+v", check)
+				}
+			}
+		})
+	}
+
+	svc := newProductionSvc(t, NewMockDb(), NewMockHttpClient())
+	if _, err := svc.VerifyPair(context.Background(), PairVerifyRequest{QWAC: PairCert{Cert: qwac}}); err == nil {
+		t.Error("Expected an error for a missing QSealC")
+	}
+}
@@ -0,0 +1,62 @@
+package verify
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+// checkPolicy records the checks that only exist if the policy configures them:
+// allowed PSD2 roles, maximum certificate age and allowed QTSP countries.
+func checkPolicy(res *certVerifyResponse, crt *cert.ParsedCert, p *policy.Policy, at time.Time) error {
+	if len(p.AllowedRoles) > 0 {
+		roles, err := crt.Roles()
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(roles, func(role models.ObRole) bool { return slices.Contains(p.AllowedRoles, role) }) {
+			res.pass(models.CheckRole, models.CodeRoleAllowed, joinRoles(roles))
+		} else {
+			log.Printf("Certificate roles %v are not allowed by the policy", roles)
+			res.fail(models.CheckRole, models.CheckStatusFail, models.CodeRoleNotAllowed,
+				fmt.Sprintf("Certificate roles [%s] are not allowed", joinRoles(roles)))
+		}
+	}
+	if limit := p.CertAgeLimit(); limit > 0 {
+		if age := at.Sub(crt.Cert.NotBefore); age > limit {
+			log.Printf("Certificate was issued %s ago, more than the allowed %s", age, limit)
+			res.fail(models.CheckCertAge, models.CheckStatusFail, models.CodeCertTooOld,
+				fmt.Sprintf("Certificate was issued more than %s ago", limit))
+		} else {
+			res.pass(models.CheckCertAge, models.CodeCertAgeValid, "")
+		}
+	}
+	if len(p.AllowedQtspCountries) > 0 {
+		var country string
+		if len(crt.Cert.Issuer.Country) > 0 {
+			country = normalizeCountry(crt.Cert.Issuer.Country[0])
+		}
+		if country != "" && slices.ContainsFunc(p.AllowedQtspCountries, func(allowed string) bool { return normalizeCountry(allowed) == country }) {
+			res.pass(models.CheckQtspCountry, models.CodeQtspCountryAllowed, country)
+		} else {
+			log.Printf("Issuer country %q is not allowed by the policy", country)
+			res.fail(models.CheckQtspCountry, models.CheckStatusFail, models.CodeQtspCountryNotAllowed,
+				fmt.Sprintf("Issuer country %q is not allowed", country))
+		}
+	}
+	return nil
+}
+
+func joinRoles(roles []models.ObRole) string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,100 @@
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/receipt"
+)
+
+// DataVersions identify the reference data a result was computed from,
+// so that a receipt can be traced back to the roots and registry record in use.
+type DataVersions struct {
+	// Roots is a fingerprint of the set of trusted root certificates.
+	Roots string `json:"roots"`
+	// Registry is the last update of the registry record of the TPP.
+	Registry *time.Time `json:"registry,omitempty"`
+}
+
+// ReceiptClaims are the signed contents of a receipt.
+type ReceiptClaims struct {
+	IssuedAt     int64        `json:"iat"`
+	CertSha256   string       `json:"cert_sha256"`
+	Policy       string       `json:"policy"`
+	Tenant       string       `json:"tenant,omitempty"`
+	DataVersions DataVersions `json:"data_versions"`
+	Valid        bool         `json:"valid"`
+	// Result is the response the receipt was issued for, without the receipt itself.
+	Result *VerifyResponse `json:"result"`
+}
+
+// ReceiptVerifier checks receipts and publishes the receipt keys.
+type ReceiptVerifier interface {
+	VerifyReceipt(token string) (*ReceiptClaims, error)
+	ReceiptKeys() (receipt.JWKS, error)
+}
+
+var _ ReceiptVerifier = (*VerifySvc)(nil)
+
+// SetReceiptSigner enables signed receipts.
+func (s *VerifySvc) SetReceiptSigner(signer *receipt.Signer) {
+	s.receipts = signer
+}
+
+// rootsVersion returns a fingerprint of the trusted roots, independent of their order.
+func (s *VerifySvc) rootsVersion() string {
+	s.mu.RLock()
+	hashes := slices.Clone(s.rootHashes)
+	s.mu.RUnlock()
+	slices.Sort(hashes)
+	sum := sha256.Sum256([]byte(strings.Join(hashes, ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// signReceipt returns a copy of the result with its receipt, as the result itself may be cached.
+func (s *VerifySvc) signReceipt(ctx context.Context, res *VerifyResponse) (*VerifyResponse, error) {
+	claims := ReceiptClaims{
+		IssuedAt:     s.clock.Now().Unix(),
+		Policy:       res.Policy,
+		DataVersions: res.DataVersions,
+		Valid:        res.Valid,
+		Result:       res,
+	}
+	if res.Certificate != nil {
+		claims.CertSha256 = res.Certificate.Sha256
+	}
+	if t := TenantFromContext(ctx); t != nil {
+		claims.Tenant = t.Id
+	}
+	token, err := s.receipts.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	signed := *res
+	signed.Receipt = token
+	return &signed, nil
+}
+
+// VerifyReceipt checks the signature of a receipt issued by this service and returns its claims.
+func (s *VerifySvc) VerifyReceipt(token string) (*ReceiptClaims, error) {
+	if s.receipts == nil {
+		return nil, ErrReceiptsDisabled
+	}
+	claims := &ReceiptClaims{}
+	if _, err := s.receipts.Verify(token, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ReceiptKeys returns the JWK Set receipts can be verified with offline.
+func (s *VerifySvc) ReceiptKeys() (receipt.JWKS, error) {
+	if s.receipts == nil {
+		return receipt.JWKS{}, ErrReceiptsDisabled
+	}
+	return s.receipts.JWKS(), nil
+}
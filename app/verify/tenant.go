@@ -0,0 +1,56 @@
+package verify
+
+import (
+	"context"
+	"strings"
+
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+// Tenant holds the configuration of the calling tenant, which overrides the
+// defaults of the service for the requests made with its API keys.
+type Tenant struct {
+	Id string
+	// Policy is nil if the tenant uses the policy of the service.
+	Policy *policy.Policy
+	// AspspCountries is nil if the tenant uses the ASPSP countries of the service.
+	AspspCountries []string
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a context whose verifications use the configuration of the tenant.
+func ContextWithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// TenantFromContext returns the tenant of the context, nil if there is none.
+func TenantFromContext(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(tenantKey{}).(*Tenant)
+	return t
+}
+
+// settings returns the policy and ASPSP countries that apply to the request.
+func (s *VerifySvc) settings(ctx context.Context) (*policy.Policy, []string) {
+	p, countries := s.policy, s.aspspCountries
+	if t := TenantFromContext(ctx); t != nil {
+		if t.Policy != nil {
+			p = t.Policy
+		}
+		if t.AspspCountries != nil {
+			countries = normalizeCountries(t.AspspCountries)
+		}
+	}
+	return p, countries
+}
+
+func normalizeCountries(countries []string) []string {
+	var normalized []string
+	for _, country := range countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if country != "" {
+			normalized = append(normalized, country)
+		}
+	}
+	return normalized
+}
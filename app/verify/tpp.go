@@ -0,0 +1,259 @@
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+var (
+	ErrAmbiguousTppId = errors.New("identifier matches several TPPs")
+	ErrNoTppIds       = errors.New("no TPP identifiers given")
+	ErrTooManyTppIds  = errors.New("too many TPP identifiers")
+	// ErrInvalidTppSearch is returned for unknown filter values, invalid limits and cursors.
+	ErrInvalidTppSearch = errors.New("invalid TPP search")
+)
+
+const (
+	DefaultTppSearchLimit = 50
+	MaxTppSearchLimit     = 200
+)
+
+// TppTypes are the EBA entity types of the registry.
+var TppTypes = []string{"PSD_AISP", "PSD_PI", "PSD_EMI"}
+
+// countryCode matches ISO 3166-1 alpha-2 codes. Repositories use the passported country
+// as a field name, so it must be checked before it reaches them.
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// TppRecord is the registry record of a TPP with its authorization status at lookup time.
+type TppRecord struct {
+	*models.TPP
+	AuthorizationStatus models.AuthorizationStatus `json:"authorization_status"`
+}
+
+// TppLookup returns registry records, independent of certificates.
+type TppLookup interface {
+	LookupTpp(ctx context.Context, id string) (*TppRecord, error)
+	LookupTpps(ctx context.Context, req TppLookupRequest) (*TppLookupResponse, error)
+}
+
+var _ TppLookup = (*VerifySvc)(nil)
+
+type TppLookupRequest struct {
+	Ids []string `json:"ids"`
+}
+
+// TppLookupItem holds either the record or the error of a single identifier.
+type TppLookupItem struct {
+	Id    string     `json:"id"`
+	TPP   *TppRecord `json:"tpp,omitempty"`
+	Error string     `json:"error,omitempty"`
+
+	err error
+}
+
+// Err returns the error of the item, nil if the TPP was found.
+func (i TppLookupItem) Err() error {
+	return i.err
+}
+
+type TppLookupResponse struct {
+	Results []TppLookupItem `json:"results"`
+}
+
+// LookupTpp returns the TPP with the given identifier, which is tried as an OB ID
+// (e.g. PSDFI-FINFSA-0111027-9), an EBA entity code and a national reference code, in this order.
+// A national reference code issued by several authorities fails with ErrAmbiguousTppId.
+func (s *VerifySvc) LookupTpp(ctx context.Context, id string) (*TppRecord, error) {
+	tpp, err := s.findTpp(ctx, strings.TrimSpace(id))
+	if errors.Is(err, ErrTppLookup) {
+		// Repository errors are logged here, clients only get ErrTppLookup
+		log.Printf("Failed to look up TPP %s: %v", id, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TppRecord{TPP: tpp, AuthorizationStatus: tpp.AuthorizationStatus(s.clock.Now())}, nil
+}
+
+func (s *VerifySvc) findTpp(ctx context.Context, id string) (*models.TPP, error) {
+	if id == "" {
+		return nil, db.ErrTppNotFound
+	}
+	lookups := []func() (*models.TPP, error){
+		func() (*models.TPP, error) { return s.db.GetTpp(ctx, normalizeTppId(id)) },
+		func() (*models.TPP, error) { return s.db.GetTppByEntityCode(ctx, id) },
+	}
+	for _, lookup := range lookups {
+		tpp, err := lookup()
+		if err == nil && tpp != nil {
+			return tpp, nil
+		}
+		if err != nil && !errors.Is(err, db.ErrTppNotFound) {
+			return nil, fmt.Errorf("%w: %w", ErrTppLookup, err)
+		}
+	}
+	// National reference codes are part of the OB ID without dashes, see normalizeTppId
+	code := strings.NewReplacer("-", "", " ", "").Replace(id)
+	tpps, err := s.db.GetTppsByNationalReference(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTppLookup, err)
+	}
+	switch len(tpps) {
+	case 0:
+		return nil, db.ErrTppNotFound
+	case 1:
+		return tpps[0], nil
+	}
+	ids := make([]string, len(tpps))
+	for i, tpp := range tpps {
+		ids[i] = tpp.OBID
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAmbiguousTppId, strings.Join(ids, ", "))
+}
+
+// LookupTpps looks up several identifiers. An identifier that is not found does not fail
+// the lookup; its error is returned in place of its record.
+func (s *VerifySvc) LookupTpps(ctx context.Context, req TppLookupRequest) (*TppLookupResponse, error) {
+	if len(req.Ids) == 0 {
+		return nil, ErrNoTppIds
+	}
+	if len(req.Ids) > MaxBatchSize {
+		return nil, ErrTooManyTppIds
+	}
+	res := &TppLookupResponse{Results: make([]TppLookupItem, len(req.Ids))}
+	for i, id := range req.Ids {
+		item := TppLookupItem{Id: id}
+		tpp, err := s.LookupTpp(ctx, id)
+		if err != nil {
+			item.Error, item.err = itemError(err), err
+		} else {
+			item.TPP = tpp
+		}
+		res.Results[i] = item
+	}
+	return res, nil
+}
+
+// TppSearcher searches the registry.
+type TppSearcher interface {
+	SearchTpps(ctx context.Context, req TppSearchRequest) (*TppSearchResponse, error)
+}
+
+var _ TppSearcher = (*VerifySvc)(nil)
+
+// TppSearchRequest holds the filters of a search, see db.TppFilter. Cursor is the NextCursor
+// of the previous page.
+type TppSearchRequest struct {
+	Name              string
+	Country           string
+	Authority         string
+	Type              string
+	Service           string
+	PassportedCountry string
+	Status            string
+	Cursor            string
+	Limit             int
+}
+
+type TppSearchResponse struct {
+	Tpps []*TppRecord `json:"tpps"`
+	// NextCursor is set if there are more results.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchTpps returns a page of the TPPs matching the filters, ordered by OB ID.
+// Codes are matched case-insensitively.
+func (s *VerifySvc) SearchTpps(ctx context.Context, req TppSearchRequest) (*TppSearchResponse, error) {
+	filter, err := s.tppFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	// One more TPP than requested tells if there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	tpps, err := s.db.SearchTpps(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to search TPPs: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrTppLookup, err)
+	}
+	res := &TppSearchResponse{Tpps: make([]*TppRecord, 0, min(len(tpps), limit))}
+	for _, tpp := range tpps[:min(len(tpps), limit)] {
+		res.Tpps = append(res.Tpps, &TppRecord{TPP: tpp, AuthorizationStatus: tpp.AuthorizationStatus(filter.At)})
+	}
+	if len(tpps) > limit {
+		res.NextCursor = encodeCursor(tpps[limit-1].OBID)
+	}
+	return res, nil
+}
+
+func (s *VerifySvc) tppFilter(req TppSearchRequest) (db.TppFilter, error) {
+	var err error
+	filter := db.TppFilter{
+		Name:              strings.TrimSpace(req.Name),
+		Country:           strings.ToUpper(req.Country),
+		Authority:         strings.ToUpper(req.Authority),
+		Type:              strings.ToUpper(req.Type),
+		Service:           models.Service(strings.ToUpper(req.Service)),
+		PassportedCountry: strings.ToUpper(req.PassportedCountry),
+		Status:            models.AuthorizationStatus(strings.ToUpper(req.Status)),
+		At:                s.clock.Now(),
+		Limit:             req.Limit,
+	}
+	if filter.Type != "" && !slices.Contains(TppTypes, filter.Type) {
+		return filter, fmt.Errorf("%w: unknown type %q", ErrInvalidTppSearch, req.Type)
+	}
+	if filter.Service != "" && filter.Service != models.AISP && filter.Service != models.PISP {
+		return filter, fmt.Errorf("%w: unknown service %q", ErrInvalidTppSearch, req.Service)
+	}
+	if filter.PassportedCountry != "" && !countryCode.MatchString(filter.PassportedCountry) {
+		return filter, fmt.Errorf("%w: invalid passported country %q", ErrInvalidTppSearch, req.PassportedCountry)
+	}
+	switch filter.Status {
+	case "", models.AuthorizationStatusAuthorized, models.AuthorizationStatusWithdrawn, models.AuthorizationStatusNotAuthorized:
+	default:
+		return filter, fmt.Errorf("%w: unknown status %q", ErrInvalidTppSearch, req.Status)
+	}
+	filter.Limit, err = pageLimit(req.Limit)
+	if err != nil {
+		return filter, err
+	}
+	filter.After, err = decodeCursor(req.Cursor)
+	return filter, err
+}
+
+// pageLimit returns the page size of a request, the default one if it is not set.
+func pageLimit(limit int) (int, error) {
+	if limit == 0 {
+		return DefaultTppSearchLimit, nil
+	}
+	if limit < 0 || limit > MaxTppSearchLimit {
+		return 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidTppSearch, MaxTppSearchLimit)
+	}
+	return limit, nil
+}
+
+// encodeCursor makes the position of a page opaque to clients.
+func encodeCursor(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(position) == 0 {
+		return "", fmt.Errorf("%w: invalid cursor", ErrInvalidTppSearch)
+	}
+	return string(position), nil
+}
@@ -0,0 +1,64 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// TppChangeFeed returns the changes of the registry recorded by its imports.
+type TppChangeFeed interface {
+	TppChanges(ctx context.Context, req TppChangesRequest) (*TppChangesResponse, error)
+}
+
+var _ TppChangeFeed = (*VerifySvc)(nil)
+
+// TppChangesRequest starts the feed at Since, or resumes it at Cursor, the NextCursor of
+// a previous response. The feed starts at the first recorded change if neither is set.
+type TppChangesRequest struct {
+	Since  time.Time
+	Cursor string
+	Limit  int
+}
+
+type TppChangesResponse struct {
+	Changes []models.TppChange `json:"changes"`
+	// NextCursor resumes the feed after the returned changes. It is the cursor of the
+	// request if there are no changes, so that clients can keep polling with it.
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// TppChanges returns a page of the changes recorded since the time or the cursor of the request.
+func (s *VerifySvc) TppChanges(ctx context.Context, req TppChangesRequest) (*TppChangesResponse, error) {
+	limit, err := pageLimit(req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	filter := db.TppChangeFilter{Since: req.Since, Limit: limit + 1}
+	position, err := decodeCursor(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if position != "" {
+		filter.After, err = strconv.ParseInt(position, 10, 64)
+		if err != nil || filter.After < 0 {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidTppSearch)
+		}
+	}
+	changes, err := s.db.GetTppChanges(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to get TPP changes: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrTppLookup, err)
+	}
+	res := &TppChangesResponse{Changes: changes[:min(len(changes), limit)], HasMore: len(changes) > limit, NextCursor: req.Cursor}
+	if len(res.Changes) > 0 {
+		res.NextCursor = encodeCursor(strconv.FormatInt(res.Changes[len(res.Changes)-1].Seq, 10))
+	}
+	return res, nil
+}
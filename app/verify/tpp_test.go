@@ -0,0 +1,266 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/clock"
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// registryDb is a repository of a few TPPs, two of which share a national reference code.
+type registryDb struct {
+	MockDb
+	tpps    []*models.TPP
+	changes []models.TppChange
+	err     error
+	filter  db.TppFilter
+}
+
+func newRegistryDb() *registryDb {
+	authorizedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	withdrawnAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &registryDb{tpps: []*models.TPP{
+		{Id: "FI_FIN-FSA!01110279", OBID: "PSDFI-FINFSA-01110279", NameLatin: "Finnish TPP", Country: "FI", AuthorizedAt: &authorizedAt,
+			Services: map[string][]models.Service{"FI": {models.AISP}, "SE": {models.AISP}}},
+		{Id: "SE_FI!44059", OBID: "PSDSE-FINA-44059", NameLatin: "Swedish TPP", Country: "SE", AuthorizedAt: &authorizedAt, WithdrawnAt: &withdrawnAt},
+		{Id: "DK_DFSA!44059", OBID: "PSDDK-DFSA-44059", NameLatin: "Danish TPP", Country: "DK", AuthorizedAt: &authorizedAt},
+	}}
+}
+
+func (r *registryDb) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
+	for _, tpp := range r.tpps {
+		if tpp.OBID == id {
+			return tpp, r.err
+		}
+	}
+	return nil, errors.Join(db.ErrTppNotFound, r.err)
+}
+
+func (r *registryDb) GetTppByEntityCode(ctx context.Context, code string) (*models.TPP, error) {
+	for _, tpp := range r.tpps {
+		if tpp.Id == code {
+			return tpp, nil
+		}
+	}
+	return nil, db.ErrTppNotFound
+}
+
+func (r *registryDb) GetTppsByNationalReference(ctx context.Context, code string) ([]*models.TPP, error) {
+	var tpps []*models.TPP
+	for _, tpp := range r.tpps {
+		if strings.HasSuffix(tpp.OBID, "-"+code) {
+			tpps = append(tpps, tpp)
+		}
+	}
+	return tpps, nil
+}
+
+// SearchTpps filters by country only, which is enough to test the pagination.
+func (r *registryDb) SearchTpps(ctx context.Context, filter db.TppFilter) ([]*models.TPP, error) {
+	r.filter = filter
+	tpps := slices.Clone(r.tpps)
+	slices.SortFunc(tpps, func(a, b *models.TPP) int { return strings.Compare(a.OBID, b.OBID) })
+	tpps = slices.DeleteFunc(tpps, func(tpp *models.TPP) bool {
+		return tpp.OBID <= filter.After || filter.Country != "" && tpp.Country != filter.Country
+	})
+	return tpps[:min(len(tpps), filter.Limit)], r.err
+}
+
+func (r *registryDb) GetTppChanges(ctx context.Context, filter db.TppChangeFilter) ([]models.TppChange, error) {
+	changes := slices.DeleteFunc(slices.Clone(r.changes), func(c models.TppChange) bool {
+		return c.Seq <= filter.After || c.RecordedAt.Before(filter.Since)
+	})
+	return changes[:min(len(changes), filter.Limit)], r.err
+}
+
+func TestLookupTpp(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		want   string
+		status models.AuthorizationStatus
+		err    error
+	}{
+		{"OB ID", "PSDFI-FINFSA-01110279", "PSDFI-FINFSA-01110279", models.AuthorizationStatusAuthorized, nil},
+		{"OB ID with dash", "PSDFI-FINFSA-0111027-9", "PSDFI-FINFSA-01110279", models.AuthorizationStatusAuthorized, nil},
+		{"Entity code", "SE_FI!44059", "PSDSE-FINA-44059", models.AuthorizationStatusWithdrawn, nil},
+		{"National reference code", "0111027-9", "PSDFI-FINFSA-01110279", models.AuthorizationStatusAuthorized, nil},
+		{"Ambiguous national reference code", "44059", "", "", ErrAmbiguousTppId},
+		{"Unknown", "PSDFI-FINFSA-99999999", "", "", db.ErrTppNotFound},
+		{"Empty", " ", "", "", db.ErrTppNotFound},
+	}
+	svc := NewVerifySvc(newRegistryDb(), NewMockHttpClient())
+	svc.SetClock(clock.Fixed(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := svc.LookupTpp(context.Background(), tt.id)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.OBID != tt.want || res.AuthorizationStatus != tt.status {
+				t.Errorf("Expected %s %s, got %s %s", tt.want, tt.status, res.OBID, res.AuthorizationStatus)
+			}
+		})
+	}
+}
+
+func TestLookupTpps(t *testing.T) {
+	repo := newRegistryDb()
+	svc := NewVerifySvc(repo, NewMockHttpClient())
+	res, err := svc.LookupTpps(context.Background(), TppLookupRequest{Ids: []string{"SE_FI!44059", "unknown", "44059"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(res.Results))
+	}
+	if res.Results[0].TPP == nil || res.Results[0].TPP.OBID != "PSDSE-FINA-44059" {
+		t.Errorf("Expected PSDSE-FINA-44059, got %+v", res.Results[0])
+	}
+	if res.Results[1].Error != db.ErrTppNotFound.Error() {
+		t.Errorf("Expected %q, got %+v", db.ErrTppNotFound, res.Results[1])
+	}
+	if !strings.Contains(res.Results[2].Error, "PSDSE-FINA-44059") || !strings.Contains(res.Results[2].Error, "PSDDK-DFSA-44059") {
+		t.Errorf("Expected the matching OB IDs, got %q", res.Results[2].Error)
+	}
+
+	repo.err = errors.New("connection refused")
+	res, err = svc.LookupTpps(context.Background(), TppLookupRequest{Ids: []string{"PSDFI-FINFSA-01110279"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Results[0].Error != ErrTppLookup.Error() {
+		t.Errorf("Expected the repository error to be hidden, got %q", res.Results[0].Error)
+	}
+
+	if _, err := svc.LookupTpps(context.Background(), TppLookupRequest{}); !errors.Is(err, ErrNoTppIds) {
+		t.Errorf("Expected error %v, got %v", ErrNoTppIds, err)
+	}
+	if _, err := svc.LookupTpps(context.Background(), TppLookupRequest{Ids: make([]string, MaxBatchSize+1)}); !errors.Is(err, ErrTooManyTppIds) {
+		t.Errorf("Expected error %v, got %v", ErrTooManyTppIds, err)
+	}
+}
+
+func TestSearchTpps(t *testing.T) {
+	repo := newRegistryDb()
+	svc := NewVerifySvc(repo, NewMockHttpClient())
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	svc.SetClock(clock.Fixed(now))
+
+	var ids []string
+	req := TppSearchRequest{Limit: 2}
+	for page := 0; ; page++ {
+		if page > 2 {
+			t.Fatal("Expected the pagination to end")
+		}
+		res, err := svc.SearchTpps(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, tpp := range res.Tpps {
+			ids = append(ids, tpp.OBID)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		req.Cursor = res.NextCursor
+	}
+	if want := "PSDDK-DFSA-44059 PSDFI-FINFSA-01110279 PSDSE-FINA-44059"; strings.Join(ids, " ") != want {
+		t.Errorf("Expected %s, got %v", want, ids)
+	}
+
+	res, err := svc.SearchTpps(context.Background(), TppSearchRequest{Country: "se", Status: "withdrawn", Service: "ais", Type: "psd_pi"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Tpps) != 1 || res.Tpps[0].AuthorizationStatus != models.AuthorizationStatusWithdrawn || res.NextCursor != "" {
+		t.Errorf("Expected the withdrawn Swedish TPP, got %+v", res)
+	}
+	want := db.TppFilter{Country: "SE", Type: "PSD_PI", Service: models.AISP, Status: models.AuthorizationStatusWithdrawn, At: now, Limit: DefaultTppSearchLimit + 1}
+	if repo.filter != want {
+		t.Errorf("Expected filter %+v, got %+v", want, repo.filter)
+	}
+
+	invalid := []TppSearchRequest{
+		{Type: "BANK"},
+		{Service: "CBPII"},
+		{Status: "REVOKED"},
+		{PassportedCountry: "SWE"},
+		{PassportedCountry: "S.E"},
+		{PassportedCountry: "$where"},
+		{Limit: MaxTppSearchLimit + 1},
+		{Cursor: "not base64!"},
+	}
+	for _, req := range invalid {
+		if _, err := svc.SearchTpps(context.Background(), req); !errors.Is(err, ErrInvalidTppSearch) {
+			t.Errorf("Expected error %v for %+v, got %v", ErrInvalidTppSearch, req, err)
+		}
+	}
+
+	repo.err = errors.New("connection refused")
+	if _, err := svc.SearchTpps(context.Background(), TppSearchRequest{}); !errors.Is(err, ErrTppLookup) {
+		t.Errorf("Expected error %v, got %v", ErrTppLookup, err)
+	}
+}
+
+func TestTppChanges(t *testing.T) {
+	repo := newRegistryDb()
+	first := time.Date(2026, 9, 1, 2, 0, 0, 0, time.UTC)
+	second := time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC)
+	repo.changes = []models.TppChange{
+		{Seq: 1, Type: models.TppAdded, OBID: "PSDFI-FINFSA-01110279", RecordedAt: first},
+		{Seq: 2, Type: models.TppAdded, OBID: "PSDSE-FINA-44059", RecordedAt: first},
+		{Seq: 3, Type: models.TppWithdrawn, OBID: "PSDSE-FINA-44059", RecordedAt: second},
+	}
+	svc := NewVerifySvc(repo, NewMockHttpClient())
+
+	res, err := svc.TppChanges(context.Background(), TppChangesRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Changes) != 2 || !res.HasMore || res.NextCursor == "" {
+		t.Fatalf("Expected a first page of 2 changes, got %+v", res)
+	}
+	res, err = svc.TppChanges(context.Background(), TppChangesRequest{Cursor: res.NextCursor, Limit: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Changes) != 1 || res.Changes[0].Type != models.TppWithdrawn || res.HasMore {
+		t.Fatalf("Expected the withdrawal, got %+v", res)
+	}
+	// Polling at the end of the feed keeps the cursor
+	cursor := res.NextCursor
+	res, err = svc.TppChanges(context.Background(), TppChangesRequest{Cursor: cursor})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Changes) != 0 || res.NextCursor != cursor {
+		t.Errorf("Expected no changes and cursor %s, got %+v", cursor, res)
+	}
+
+	res, err = svc.TppChanges(context.Background(), TppChangesRequest{Since: second.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Changes) != 1 || res.Changes[0].Seq != 3 {
+		t.Errorf("Expected the changes since the second import, got %+v", res)
+	}
+
+	for _, req := range []TppChangesRequest{{Cursor: "not base64!"}, {Cursor: encodeCursor("PSDFI")}, {Limit: MaxTppSearchLimit + 1}} {
+		if _, err := svc.TppChanges(context.Background(), req); !errors.Is(err, ErrInvalidTppSearch) {
+			t.Errorf("Expected error %v for %+v, got %v", ErrInvalidTppSearch, req, err)
+		}
+	}
+}
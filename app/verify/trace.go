@@ -0,0 +1,198 @@
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// Trace records how a result was reached: the registry lookup, the certificates
+// fetched and discovered, the chains built and the OCSP exchange. It is returned
+// in explain mode to debug failures without reading the server logs.
+type Trace struct {
+	mu sync.Mutex
+
+	Registry     *RegistryTrace     `json:"registry,omitempty"`
+	AiaFetches   []AiaFetchTrace    `json:"aia_fetches"`
+	Certificates []CertificateTrace `json:"certificates"`
+	Chains       []ChainTrace       `json:"chains"`
+	Ocsp         *OcspTrace         `json:"ocsp,omitempty"`
+}
+
+type RegistryTrace struct {
+	OrganizationId string `json:"organization_id"`
+	// LookupKey is the organization identifier after normalizeTppId, as used for the lookup.
+	LookupKey string `json:"lookup_key"`
+	Found     bool   `json:"found"`
+	Error     string `json:"error,omitempty"`
+}
+
+type AiaFetchTrace struct {
+	URL        string  `json:"url"`
+	StatusCode int     `json:"status_code,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	// Shared is set if the download was made for a concurrent verification.
+	Shared       bool   `json:"shared,omitempty"`
+	Certificates int    `json:"certificates"`
+	Error        string `json:"error,omitempty"`
+}
+
+type CertificateTrace struct {
+	Sha256  string `json:"sha256"`
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer"`
+	// Source is "request" for supplied certificates, otherwise the AIA URL.
+	Source string `json:"source"`
+}
+
+// ChainTrace is an attempt to build a chain to a trusted root.
+type ChainTrace struct {
+	// Intermediates is "known" for the pool of known and supplied intermediates,
+	// "aia" once the downloaded ones were added.
+	Intermediates string         `json:"intermediates"`
+	Candidates    []ChainSummary `json:"candidates"`
+	// Chosen is the index of the candidate used, -1 if none.
+	Chosen int    `json:"chosen"`
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ChainSummary struct {
+	Certificates []CertificateSummary `json:"certificates"`
+}
+
+type CertificateSummary struct {
+	Sha256  string `json:"sha256"`
+	Subject string `json:"subject"`
+}
+
+type OcspTrace struct {
+	Responder    string  `json:"responder"`
+	SerialNumber string  `json:"serial_number"`
+	Issuer       string  `json:"issuer"`
+	StatusCode   int     `json:"status_code,omitempty"`
+	DurationMs   float64 `json:"duration_ms"`
+	Shared       bool    `json:"shared,omitempty"`
+	// Status is good, revoked or unknown.
+	Status     string     `json:"status,omitempty"`
+	ProducedAt *time.Time `json:"produced_at,omitempty"`
+	ThisUpdate *time.Time `json:"this_update,omitempty"`
+	NextUpdate *time.Time `json:"next_update,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type traceKey struct{}
+
+func contextWithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// traceFromContext returns the trace of the verification, nil outside explain mode.
+// All recording methods are no-ops on a nil trace.
+func traceFromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+func sha256Hex(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func (t *Trace) registry(organizationId, key string, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Registry = &RegistryTrace{OrganizationId: organizationId, LookupKey: key, Found: err == nil, Error: errorString(err)}
+}
+
+func (t *Trace) aiaFetch(url string, status int, d time.Duration, shared bool, certs int, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.AiaFetches = append(t.AiaFetches, AiaFetchTrace{
+		URL: url, StatusCode: status, DurationMs: durationMs(d), Shared: shared, Certificates: certs, Error: errorString(err),
+	})
+}
+
+func (t *Trace) certificate(c *x509.Certificate, source string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Certificates = append(t.Certificates, CertificateTrace{
+		Sha256: sha256Hex(c.Raw), Subject: c.Subject.String(), Issuer: c.Issuer.String(), Source: source,
+	})
+}
+
+func (t *Trace) chains(intermediates string, chains [][]*x509.Certificate, chosen int, reason string, err error) {
+	if t == nil {
+		return
+	}
+	attempt := ChainTrace{Intermediates: intermediates, Candidates: []ChainSummary{}, Chosen: chosen, Reason: reason, Error: errorString(err)}
+	for _, chain := range chains {
+		summary := ChainSummary{}
+		for _, c := range chain {
+			summary.Certificates = append(summary.Certificates, CertificateSummary{Sha256: sha256Hex(c.Raw), Subject: c.Subject.String()})
+		}
+		attempt.Candidates = append(attempt.Candidates, summary)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Chains = append(t.Chains, attempt)
+}
+
+func (t *Trace) ocsp(responder string, c, issuer *x509.Certificate, status int, d time.Duration, shared bool, res *ocsp.Response, err error) {
+	if t == nil {
+		return
+	}
+	trace := &OcspTrace{
+		Responder:    responder,
+		SerialNumber: c.SerialNumber.String(),
+		Issuer:       issuer.Subject.String(),
+		StatusCode:   status,
+		DurationMs:   durationMs(d),
+		Shared:       shared,
+		Error:        errorString(err),
+	}
+	if res != nil {
+		switch res.Status {
+		case ocsp.Good:
+			trace.Status = "good"
+		case ocsp.Revoked:
+			trace.Status = "revoked"
+			trace.RevokedAt = &res.RevokedAt
+		default:
+			trace.Status = "unknown"
+		}
+		trace.ProducedAt, trace.ThisUpdate = &res.ProducedAt, &res.ThisUpdate
+		if !res.NextUpdate.IsZero() {
+			trace.NextUpdate = &res.NextUpdate
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Ocsp = trace
+}
@@ -1,6 +1,7 @@
 package verify
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"slices"
@@ -8,9 +9,12 @@ import (
 	"time"
 
 	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/clock"
 	"github.com/botsman/tppVerifier/app/db"
 	vhttp "github.com/botsman/tppVerifier/app/http"
 	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+	"github.com/botsman/tppVerifier/app/receipt"
 
 	"bytes"
 	"crypto/x509"
@@ -18,39 +22,123 @@ import (
 	"errors"
 	"io"
 	"log"
+	"sync"
 
-	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/ocsp"
+	"golang.org/x/sync/singleflight"
 )
 
 type VerifySvc struct {
-	db            db.TppRepository
-	httpClient    vhttp.Client
-	roots         *x509.CertPool
-	intermediates *x509.CertPool
-	hashes        map[string]any // used to avoid duplicate links
+	db             db.TppRepository
+	httpClient     vhttp.Client
+	mu             sync.RWMutex // guards roots, intermediates and hashes
+	roots          *x509.CertPool
+	intermediates  *x509.CertPool
+	hashes         map[string]any     // used to avoid duplicate links
+	rootHashes     []string           // fingerprints of the trusted roots, guarded by mu
+	lookups        singleflight.Group // shares concurrent AIA and OCSP lookups
+	batchWorkers   int
+	aspspCountries []string // countries the ASPSP operates in, empty means all
+	clock          clock.Clock
+	cache          resultCache
+	policy         *policy.Policy
+	receipts       *receipt.Signer
+
+	evidence          db.EvidenceRepository
+	evidenceRetention time.Duration
 }
 
 func NewVerifySvc(db db.TppRepository, httpClient vhttp.Client) *VerifySvc {
 	return &VerifySvc{
-		db:         db,
-		httpClient: httpClient,
+		db:           db,
+		httpClient:   httpClient,
+		batchWorkers: defaultBatchWorkers,
+		clock:        clock.System(),
+		policy:       policy.Default(),
 	}
 }
 
+// SetPolicy replaces the verification policy and drops the results cached under the previous one.
+func (s *VerifySvc) SetPolicy(p *policy.Policy) {
+	s.policy = p
+	s.cache.invalidate()
+}
+
+// SetClock replaces the clock used when a request does not ask for a specific instant.
+func (s *VerifySvc) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
 type VerifyRequest struct {
+	// Cert holds the TPP certificate. It may be a bundle (PEM or PKCS7)
+	// that also contains the intermediates presented by the TPP.
 	Cert string `json:"cert"`
+	// Chain optionally holds intermediates supplied separately from Cert.
+	Chain []string `json:"chain,omitempty"`
+	// RegistryCheck disables the registry lookup when set to false, so that
+	// only the certificate itself is verified. Defaults to true.
+	RegistryCheck *bool `json:"registry_check,omitempty"`
+	// AspspCountry restricts the returned scopes to a single ASPSP country.
+	AspspCountry string `json:"aspsp_country,omitempty"`
+	// At evaluates the verification as of the given instant instead of now.
+	At *time.Time `json:"at,omitempty"`
+	// NoCache forces a fresh verification instead of returning a cached result.
+	NoCache bool `json:"no_cache,omitempty"`
+	// ExpectedUsage fails the usage check if the certificate is not of the given usage.
+	ExpectedUsage models.CertUsage `json:"expected_usage,omitempty"`
+	// Receipt adds a signed receipt of the result to the response.
+	Receipt bool `json:"receipt,omitempty"`
+	// Explain adds a trace of the verification to the response. The result is not cached.
+	Explain bool `json:"explain,omitempty"`
+	// NoScopesAsCheck reports a certificate that is granted no scopes by the registry as a
+	// failed scope check instead of failing with ErrNoScopes. It is set by the v2 API.
+	NoScopesAsCheck bool `json:"-"`
+}
+
+func (r VerifyRequest) registryCheckEnabled() bool {
+	return r.RegistryCheck == nil || *r.RegistryCheck
 }
 
 type VerifyResponse struct {
+	// Id identifies the verification, e.g. to retrieve its evidence.
+	// A cached result keeps the Id of the verification it was computed by.
+	Id          string                      `json:"id"`
 	Certificate *models.CertificateResponse `json:"cert"`
 	TPP         *models.TppResponse         `json:"tpp"`
 	Valid       bool                        `json:"valid"`
 	Scopes      map[string][]string         `json:"scopes"`
 	Reason      string                      `json:"reason,omitempty"`
+	Checks      []models.Check              `json:"checks"`
+	VerifiedAt  time.Time                   `json:"verified_at"`
+	Cached      bool                        `json:"cached,omitempty"`
+	// Policy is the name of the policy the result was evaluated with.
+	Policy       string       `json:"policy"`
+	DataVersions DataVersions `json:"data_versions"`
+	// Receipt is the compact JWS of the result, if requested.
+	Receipt string `json:"receipt,omitempty"`
+	// Trace is only set in explain mode.
+	Trace *Trace `json:"trace,omitempty"`
+
+	artifacts Artifacts
+}
+
+// Artifacts are the certificates and revocation data a result was computed from.
+type Artifacts struct {
+	Certificate *x509.Certificate
+	// Chain is the verified chain, leaf first, nil if no trusted chain was built.
+	Chain []*x509.Certificate
+	// OcspResponse is the DER encoded OCSP response, nil if the status was not obtained.
+	OcspResponse []byte
+}
+
+// Artifacts returns the certificates and revocation data of the result, e.g. for validation reports.
+func (r *VerifyResponse) Artifacts() Artifacts {
+	return r.artifacts
 }
 
 func (s *VerifySvc) AddRoot(cert *cert.ParsedCert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.roots == nil {
 		s.roots = x509.NewCertPool()
 	}
@@ -58,18 +146,73 @@ func (s *VerifySvc) AddRoot(cert *cert.ParsedCert) {
 	if !s.addHash(cert.Sha256()) {
 		log.Printf("Link %s already exists, skipping", cert.Sha256())
 	}
+	s.rootHashes = append(s.rootHashes, cert.Sha256())
+	s.cache.invalidate()
+}
+
+// LoadRoots replaces the trusted roots with the active root certificates
+// of the repository and drops cached results, which may depend on the old roots
+// or on registry data that was reloaded in the meantime.
+func (s *VerifySvc) LoadRoots(ctx context.Context) error {
+	roots, err := s.db.GetRootCertificates(ctx)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	hashes := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if root == "" {
+			log.Println("Skipping empty root certificate")
+			continue
+		}
+		rootCerts, err := cert.ParseCerts([]byte(root))
+		if err != nil {
+			log.Printf("Error parsing root certificate: %s", err)
+			continue
+		}
+		for _, rootCert := range rootCerts {
+			if rootCert.Cert == nil {
+				log.Println("Skipping nil root certificate")
+				continue
+			}
+			pool.AddCert(rootCert.Cert)
+			hashes = append(hashes, rootCert.Sha256())
+		}
+	}
+	s.mu.Lock()
+	s.roots = pool
+	s.rootHashes = hashes
+	for _, hash := range hashes {
+		s.addHash(hash)
+	}
+	s.mu.Unlock()
+	s.cache.invalidate()
+	log.Printf("Loaded %d root certificates", len(hashes))
+	return nil
 }
 
 func (s *VerifySvc) AddIntermediate(cert *cert.ParsedCert) {
+	if !s.addIntermediate(cert) {
+		log.Printf("Link %s already exists, skipping", cert.Sha256())
+	}
+}
+
+// addIntermediate adds the certificate to the intermediate pool
+// and reports whether it was not known before.
+func (s *VerifySvc) addIntermediate(cert *cert.ParsedCert) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.addHash(cert.Sha256()) {
+		return false
+	}
 	if s.intermediates == nil {
 		s.intermediates = x509.NewCertPool()
 	}
 	s.intermediates.AddCert(cert.Cert)
-	if !s.addHash(cert.Sha256()) {
-		log.Printf("Link %s already exists, skipping", cert.Sha256())
-	}
+	return true
 }
 
+// addHash must be called with s.mu held.
 func (s *VerifySvc) addHash(link string) bool {
 	if s.hashes == nil {
 		s.hashes = make(map[string]any)
@@ -82,6 +225,8 @@ func (s *VerifySvc) addHash(link string) bool {
 }
 
 func (s *VerifySvc) HashExists(link string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.hashes == nil {
 		return false
 	}
@@ -89,7 +234,36 @@ func (s *VerifySvc) HashExists(link string) bool {
 	return exists
 }
 
-func (s *VerifySvc) Verify(c *gin.Context) {
+// Verifier verifies TPP certificates independently of the transport the
+// request arrived on, so it can be embedded into non-HTTP services.
+type Verifier interface {
+	Verify(ctx context.Context, req VerifyRequest) (*VerifyResponse, error)
+}
+
+var _ Verifier = (*VerifySvc)(nil)
+
+var (
+	ErrInvalidCertificate = errors.New("invalid certificate format")
+	ErrNoCertificate      = errors.New("no valid certificate found")
+	ErrCertificateParse   = errors.New("failed to parse certificate")
+	ErrTppLookup          = errors.New("failed to retrieve TPP information")
+	ErrCertificateVerify  = errors.New("failed to verify certificate")
+	ErrNoScopes           = errors.New("no valid scopes found in the certificate")
+	ErrReceiptsDisabled   = errors.New("receipts are not enabled")
+)
+
+func (s *VerifySvc) Verify(ctx context.Context, req VerifyRequest) (*VerifyResponse, error) {
+	if req.Receipt && s.receipts == nil {
+		return nil, ErrReceiptsDisabled
+	}
+	res, err := s.verify(ctx, req)
+	if err != nil || !req.Receipt {
+		return res, err
+	}
+	return s.signReceipt(ctx, res)
+}
+
+func (s *VerifySvc) verify(ctx context.Context, req VerifyRequest) (*VerifyResponse, error) {
 	// 1. Parse the certificate
 	// 2. Extract the TPP ID
 	// 3. Query the database for the TPP
@@ -100,64 +274,151 @@ func (s *VerifySvc) Verify(c *gin.Context) {
 	//    - Check if the certificate is signed by a trusted CA
 	// 5. Intersect the TPP's services with the certificate's scopes
 	// 5. Return the result
-	var req VerifyRequest
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request format.",
-		})
-		return
-	}
-	result := VerifyResponse{}
-	certs, err := cert.ParseCerts([]byte(req.Cert))
+	at := s.clock.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+	p, aspspCountries := s.settings(ctx)
+	countries := allowedCountries(aspspCountries, req.AspspCountry)
+	result := &VerifyResponse{VerifiedAt: at, Policy: p.Name, DataVersions: DataVersions{Roots: s.rootsVersion()}}
+	cert, intermediates, err := parseRequestCerts(req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid certificate format.",
-		})
-		return
+		return nil, err
 	}
-	if len(certs) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No valid certificate found",
-		})
-		return
+	// Point-in-time verifications are not cached as they are evaluated for a specific instant,
+	// explained ones as the trace is only recorded by a fresh verification
+	cacheKey := resultCacheKey(ctx, cert, intermediates, req, p, countries)
+	useCache := req.At == nil && !req.Explain
+	if req.Explain {
+		result.Trace = &Trace{AiaFetches: []AiaFetchTrace{}, Certificates: []CertificateTrace{}, Chains: []ChainTrace{}}
+		ctx = contextWithTrace(ctx, result.Trace)
+		result.Trace.certificate(cert.Cert, "request")
+		for _, intermediate := range intermediates {
+			result.Trace.certificate(intermediate.Cert, "request")
+		}
+	}
+	if useCache && !req.NoCache {
+		if cached := s.cache.get(cacheKey, at); cached != nil && s.registryCurrent(ctx, cert, req, cached) {
+			return cached, nil
+		}
 	}
-	cert := certs[0]
-	certResponse, err := cert.CertificateResponse()
+	certResponse, err := cert.CertificateResponseAt(at)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Failed to parse certificate.",
-		})
-		return
+		return nil, fmt.Errorf("%w: %w", ErrCertificateParse, err)
 	}
+	result.Id = NewId()
 	result.Certificate = certResponse
+	checks := certVerifyResponse{Valid: true}
+	checks.pass(models.CheckParse, models.CodeCertParsed, "")
+
+	var tppResponse *models.TppResponse
+	if req.registryCheckEnabled() {
+		tppResponse, err = s.getTppResponse(ctx, cert.CompanyId(), at)
+		switch {
+		case errors.Is(err, db.ErrTppNotFound):
+			// The certificate checks still run so the caller gets the full picture
+			log.Printf("TPP %s not found in the registry", cert.CompanyId())
+			checks.fail(models.CheckRegistry, models.CheckStatusFail, models.CodeTppNotFound, "TPP not found in the registry")
+		case err != nil:
+			return nil, fmt.Errorf("%w: %w", ErrTppLookup, err)
+		default:
+			result.TPP = tppResponse
+			result.DataVersions.Registry = tppResponse.UpdatedAt
+			checkAuthorization(&checks, tppResponse)
+		}
+	} else {
+		checks.skipWith(models.CheckRegistry, models.CodeRegistryCheckDisabled)
+	}
+	if err := checkNca(&checks, cert, tppResponse, req.registryCheckEnabled()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCertificateParse, err)
+	}
+	checkSubject(&checks, cert, tppResponse)
 
-	tppResponse, err := s.getTppResponse(c, cert.CompanyId())
+	certVerifyResponse, err := s.verifyCert(ctx, cert, intermediates, req.ExpectedUsage, p, at)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve TPP information.",
-		})
-		return
+		return nil, fmt.Errorf("%w: %w", ErrCertificateVerify, err)
 	}
-	result.TPP = tppResponse
+	checks.merge(certVerifyResponse)
+	if tppResponse != nil {
+		scopes := s.getScopes(ctx, cert, tppResponse)
+		if len(scopes) == 0 && !req.NoScopesAsCheck {
+			return nil, ErrNoScopes
+		}
+		result.Scopes = filterScopes(scopes, countries)
+		if len(scopes) == 0 {
+			checks.fail(models.CheckScope, models.CheckStatusFail, models.CodeNoScopes, "No valid scopes found in the certificate")
+		} else if len(result.Scopes) == 0 {
+			log.Printf("TPP %s is not passported to any of the ASPSP countries", tppResponse.Id)
+			checks.fail(models.CheckScope, models.CheckStatusFail, models.CodeNoAllowedCountry, "TPP is not passported to any of the ASPSP countries")
+		} else {
+			checks.pass(models.CheckScope, models.CodeScopesGranted, "")
+		}
+	} else {
+		// Scopes are derived from the registry passporting
+		checks.skip(models.CheckScope)
+	}
+	if err := checkPolicy(&checks, cert, p, at); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCertificateParse, err)
+	}
+	// Checks that could not be evaluated are not cached, even if the policy only warns about them
+	expires, cacheable := cacheExpiry(cert, checks)
+	checks.applyPolicy(p)
+	result.Valid = checks.Valid
+	result.Reason = checks.Reason
+	result.Checks = checks.Checks
+	result.artifacts = Artifacts{Certificate: cert.Cert, Chain: checks.chain, OcspResponse: checks.ocspResponse}
+	s.preserveEvidence(ctx, req, checks, tppResponse, p, result)
+	if useCache && cacheable {
+		s.cache.put(cacheKey, result, at, expires)
+	}
+	return result, nil
+}
+
+// checkAuthorization fails the registry check when the TPP is withdrawn
+// or not yet authorized at verification time.
+func checkAuthorization(res *certVerifyResponse, tpp *models.TppResponse) {
+	switch tpp.AuthorizationStatus {
+	case models.AuthorizationStatusWithdrawn:
+		log.Printf("TPP %s authorization has been withdrawn", tpp.Id)
+		res.fail(models.CheckRegistry, models.CheckStatusFail, models.CodeTppWithdrawn, "TPP authorization has been withdrawn")
+	case models.AuthorizationStatusNotAuthorized:
+		log.Printf("TPP %s is not yet authorized", tpp.Id)
+		res.fail(models.CheckRegistry, models.CheckStatusFail, models.CodeTppNotAuthorized, "TPP is not yet authorized")
+	default:
+		res.pass(models.CheckRegistry, models.CodeTppFound, tpp.Id)
+	}
+}
 
-	certVerifyResponse, err := s.verifyCert(c, cert)
+// parseRequestCerts splits the certificates of the request into the leaf
+// and the intermediates supplied alongside it.
+func parseRequestCerts(req VerifyRequest) (*cert.ParsedCert, []*cert.ParsedCert, error) {
+	certs, err := cert.ParseCerts([]byte(req.Cert))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to verify certificate.",
-		})
-		return
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
 	}
-	result.Valid = certVerifyResponse.Valid
-	result.Reason = certVerifyResponse.Reason
-	result.Scopes = s.getScopes(c, cert, tppResponse)
-	if len(result.Scopes) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No valid scopes found in the certificate",
-		})
-		return
+	for _, raw := range req.Chain {
+		chainCerts, err := cert.ParseCerts([]byte(raw))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		}
+		certs = append(certs, chainCerts...)
 	}
-
-	c.JSON(http.StatusOK, result)
+	if len(certs) == 0 {
+		return nil, nil, ErrNoCertificate
+	}
+	// PKCS7 bundles are unordered, so the leaf is the first non-CA certificate
+	leafIdx := slices.IndexFunc(certs, func(crt *cert.ParsedCert) bool { return !crt.Cert.IsCA })
+	if leafIdx < 0 {
+		return nil, nil, fmt.Errorf("%w: only CA certificates were given", ErrNoCertificate)
+	}
+	leaf := certs[leafIdx]
+	intermediates := make([]*cert.ParsedCert, 0, len(certs)-1)
+	for i, crt := range certs {
+		if i != leafIdx {
+			intermediates = append(intermediates, crt)
+		}
+	}
+	return leaf, intermediates, nil
 }
 
 func normalizeTppId(id string) string {
@@ -172,20 +433,35 @@ func normalizeTppId(id string) string {
 	return fmt.Sprintf("%s-%s-%s", parts[0], parts[1], strings.Join(parts[2:], ""))
 }
 
-func (s *VerifySvc) getTppResponse(c *gin.Context, id string) (*models.TppResponse, error) {
-	id = normalizeTppId(id)
-	tpp, err := s.db.GetTpp(c, id)
+func (s *VerifySvc) getTppResponse(ctx context.Context, id string, at time.Time) (*models.TppResponse, error) {
+	key := normalizeTppId(id)
+	tpp, err := s.db.GetTpp(ctx, key)
+	if err == nil && tpp == nil {
+		err = db.ErrTppNotFound
+	}
+	traceFromContext(ctx).registry(id, key, err)
 	if err != nil {
 		return nil, err
 	}
-	return &models.TppResponse{
-		Id:         tpp.Id,
-		NameLatin:  tpp.NameLatin,
-		NameNative: tpp.NameNative,
-		Authority:  tpp.Authority,
-		Services:   tpp.Services,
-		Country:    tpp.Country,
-	}, nil
+	res := &models.TppResponse{
+		Id:                  tpp.Id,
+		NameLatin:           tpp.NameLatin,
+		NameNative:          tpp.NameNative,
+		Authority:           tpp.Authority,
+		Services:            tpp.Services,
+		Country:             tpp.Country,
+		AuthorizationStatus: tpp.AuthorizationStatus(at),
+	}
+	if tpp.AuthorizedAt != nil && !tpp.AuthorizedAt.IsZero() {
+		res.AuthorizedAt = tpp.AuthorizedAt
+	}
+	if tpp.WithdrawnAt != nil && !tpp.WithdrawnAt.IsZero() {
+		res.WithdrawnAt = tpp.WithdrawnAt
+	}
+	if !tpp.UpdatedAt.IsZero() {
+		res.UpdatedAt = &tpp.UpdatedAt
+	}
+	return res, nil
 }
 
 type Role struct {
@@ -198,12 +474,42 @@ type URLStruct struct {
 	Lang string
 }
 
-type certVerifyResponse struct {
-	Valid  bool
-	Reason string
+var (
+	errOcspMissing     = errors.New("no OCSP server found in the certificate")
+	errOcspUnavailable = errors.New("OCSP server unavailable")
+	errOcspInvalid     = errors.New("invalid OCSP response")
+)
+
+// isRevoked reports whether the certificate was revoked at the given time.
+// The current OCSP status is used, so a certificate revoked after that time
+// is reported as not revoked.
+// The OCSP response is returned as well, as the status is not guaranteed
+// to be current after its NextUpdate time and it is kept as evidence.
+func (s *VerifySvc) isRevoked(ctx context.Context, c, issuer *x509.Certificate, at time.Time) (bool, *ocsp.Response, error) {
+	if len(c.OCSPServer) == 0 {
+		return false, nil, errOcspMissing
+	}
+	key := fmt.Sprintf("ocsp:%s:%x:%s", c.OCSPServer[0], c.AuthorityKeyId, c.SerialNumber)
+	start := time.Now()
+	lookup, shared, err := sharedLookup(ctx, &s.lookups, key, func(ctx context.Context) (ocspLookup, error) {
+		ocspResponse, status, err := s.checkOcsp(ctx, c, issuer)
+		return ocspLookup{res: ocspResponse, status: status}, err
+	})
+	traceFromContext(ctx).ocsp(c.OCSPServer[0], c, issuer, lookup.status, time.Since(start), shared, lookup.res, err)
+	if err != nil {
+		return false, nil, err
+	}
+	ocspResponse := lookup.res
+	return ocspResponse.Status == ocsp.Revoked && !at.Before(ocspResponse.RevokedAt), ocspResponse, nil
+}
+
+// ocspLookup is the shared result of an OCSP request.
+type ocspLookup struct {
+	res    *ocsp.Response
+	status int // HTTP status, zero if no response was received
 }
 
-func (s *VerifySvc) isRevoked(c, issuer *x509.Certificate) (bool, error) {
+func (s *VerifySvc) checkOcsp(ctx context.Context, c, issuer *x509.Certificate) (*ocsp.Response, int, error) {
 	ocspServer := c.OCSPServer[0]
 	// ocspUrl, err := url.Parse(ocspServer)
 	// if err != nil {
@@ -213,12 +519,12 @@ func (s *VerifySvc) isRevoked(c, issuer *x509.Certificate) (bool, error) {
 	req, err := ocsp.CreateRequest(c, issuer, nil)
 	if err != nil {
 		log.Printf("Error creating OCSP request: %s", err)
-		return false, err
+		return nil, 0, err
 	}
-	httpRequest, err := http.NewRequest("POST", ocspServer, bytes.NewReader(req))
+	httpRequest, err := http.NewRequestWithContext(ctx, "POST", ocspServer, bytes.NewReader(req))
 	if err != nil {
 		log.Printf("Error creating OCSP request: %s", err)
-		return false, err
+		return nil, 0, err
 	}
 	httpRequest.Header.Set("Content-Type", "application/ocsp-request")
 	httpRequest.Header.Set("Accept", "application/ocsp-response")
@@ -226,7 +532,7 @@ func (s *VerifySvc) isRevoked(c, issuer *x509.Certificate) (bool, error) {
 	httpResponse, err := s.httpClient.Do(httpRequest)
 	if err != nil {
 		log.Printf("Error sending OCSP request: %s", err)
-		return false, err
+		return nil, 0, fmt.Errorf("%w: %w", errOcspUnavailable, err)
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -236,22 +542,26 @@ func (s *VerifySvc) isRevoked(c, issuer *x509.Certificate) (bool, error) {
 	}(httpResponse.Body)
 	if httpResponse.StatusCode != http.StatusOK {
 		log.Printf("OCSP server returned status %d", httpResponse.StatusCode)
-		return false, errors.New("OCSP server returned non-OK status")
+		return nil, httpResponse.StatusCode, fmt.Errorf("%w: server returned status %d", errOcspUnavailable, httpResponse.StatusCode)
 	}
 	body, err := io.ReadAll(httpResponse.Body)
 	if err != nil {
 		log.Printf("Error reading OCSP response: %s", err)
-		return false, err
+		return nil, httpResponse.StatusCode, fmt.Errorf("%w: %w", errOcspUnavailable, err)
 	}
 	ocspResponse, err := ocsp.ParseResponseForCert(body, c, issuer)
 	if err != nil {
 		log.Printf("Error parsing OCSP response: %s", err)
-		return false, err
+		return nil, httpResponse.StatusCode, fmt.Errorf("%w: %w", errOcspInvalid, err)
 	}
-	return ocspResponse.Status == ocsp.Revoked, nil
+	return ocspResponse, httpResponse.StatusCode, nil
 }
 
-func (s *VerifySvc) isTrusted(cert *x509.Certificate, intermediateChain []*cert.ParsedCert) (bool, []*x509.Certificate, error) {
+// isTrusted verifies the certificate against the trusted roots. The intermediates label
+// describes the intermediate pool in the trace.
+func (s *VerifySvc) isTrusted(ctx context.Context, cert *x509.Certificate, intermediateChain []*cert.ParsedCert, at time.Time, label string) (bool, []*x509.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	var intermediates *x509.CertPool
 	if s.intermediates != nil {
 		intermediates = s.intermediates.Clone()
@@ -266,79 +576,151 @@ func (s *VerifySvc) isTrusted(cert *x509.Certificate, intermediateChain []*cert.
 		Intermediates: intermediates,
 		// TODO: add custom key usages such as 1.3.6.1.4.1.311.10.3.12, 1.2.840.113583.1.1.5
 		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageAny},
-		CurrentTime: time.Now(),
+		CurrentTime: at,
 	}
 	chains, err := cert.Verify(opts)
 	if err != nil {
 		log.Printf("Certificate verification failed: %s", err)
+		traceFromContext(ctx).chains(label, nil, -1, "", err)
 		return false, nil, err
 	}
 	log.Printf("Certificate is trusted")
+	traceFromContext(ctx).chains(label, chains, 0, "first chain returned by x509.Verify", nil)
 	return true, chains[0], nil
 }
 
-func (s *VerifySvc) verifyCert(c *gin.Context, crt *cert.ParsedCert) (certVerifyResponse, error) {
+// verifyCert verifies the certificate itself. If expected is set, the certificate
+// usage must match it. Failed checks only stop the verification if the policy makes them mandatory.
+func (s *VerifySvc) verifyCert(ctx context.Context, crt *cert.ParsedCert, supplied []*cert.ParsedCert, expected models.CertUsage, p *policy.Policy, at time.Time) (certVerifyResponse, error) {
 	result := certVerifyResponse{
 		Valid:  true,
 		Reason: "",
 	}
-	if crt.Usage() == models.UNKNOWN {
-		result.Valid = false
-		result.Reason = "Unknown certificate usage"
+	switch usage := crt.Usage(); {
+	case usage == models.UNKNOWN:
+		result.fail(models.CheckUsage, models.CheckStatusFail, models.CodeUsageUnknown, "Unknown certificate usage")
+	case !p.UsageAllowed(usage):
+		log.Printf("Certificate usage %s is not allowed by the policy", usage)
+		result.fail(models.CheckUsage, models.CheckStatusFail, models.CodeUsageNotAllowed, fmt.Sprintf("Certificate usage %s is not allowed", usage))
+	case expected != "" && usage != expected:
+		log.Printf("Certificate usage %s does not match the expected %s", usage, expected)
+		result.fail(models.CheckUsage, models.CheckStatusFail, models.CodeUsageMismatch, fmt.Sprintf("Certificate usage %s does not match the expected %s", usage, expected))
+	default:
+		result.pass(models.CheckUsage, models.CodeUsageValid, string(usage))
+	}
+	if crt.Usage() == models.UNKNOWN && p.Mandatory(models.CheckUsage) {
+		result.skip(models.CheckSandbox, models.CheckChain, models.CheckRevocation)
 		return result, nil
 	}
 
 	if crt.IsSandbox() {
-		result.Valid = false
-		result.Reason = "Certificate is from a sandbox environment"
-		return result, nil
+		result.fail(models.CheckSandbox, models.CheckStatusFail, models.CodeSandbox, "Certificate is from a sandbox environment")
+		if p.Mandatory(models.CheckSandbox) {
+			result.skip(models.CheckChain, models.CheckRevocation)
+			return result, nil
+		}
+	} else {
+		result.pass(models.CheckSandbox, models.CodeNotSandbox, "")
 	}
 
-	intermediateChain, err := s.loadCertChain(c, crt.Cert.IssuingCertificateURL[0])
-	if err != nil {
+	isTrusted, chain, err := s.buildChain(ctx, crt, supplied, at)
+	if errors.Is(err, errChainLoad) {
 		log.Printf("Error loading certificate chain: %s", err)
-		result.Valid = false
-		result.Reason = "Error loading certificate chain"
+		result.fail(models.CheckChain, models.CheckStatusError, models.CodeChainUnavailable, "Error loading certificate chain")
+		result.skip(models.CheckRevocation)
 		return result, nil
 	}
-	isTrusted, chain, err := s.isTrusted(crt.Cert, intermediateChain)
 	if err != nil {
 		log.Printf("Error checking if certificate is trusted: %s", err)
-		result.Valid = false
-		result.Reason = "Error checking if certificate is trusted"
+		result.fail(models.CheckChain, models.CheckStatusFail, models.CodeChainUntrusted, "Error checking if certificate is trusted")
+		result.skip(models.CheckRevocation)
 		return result, nil
 	}
 	if !isTrusted {
 		log.Printf("Certificate is not trusted")
-		result.Valid = false
-		result.Reason = "Certificate is not trusted"
+		result.fail(models.CheckChain, models.CheckStatusFail, models.CodeChainUntrusted, "Certificate is not trusted")
+		result.skip(models.CheckRevocation)
 		return result, nil
 	}
-	s.updateIntermediates(c, intermediateChain)
+	result.pass(models.CheckChain, models.CodeChainTrusted, "")
+	result.chain = chain
 
-	isRevoked, err := s.isRevoked(crt.Cert, chain[len(chain)-1])
+	isRevoked, ocspResponse, err := s.isRevoked(ctx, crt.Cert, chain[len(chain)-1], at)
 	if err != nil {
 		log.Printf("Error checking certificate revocation: %s", err)
-		result.Valid = false
-		result.Reason = "Error checking certificate revocation"
+		code := models.CodeOcspUnavailable
+		switch {
+		case errors.Is(err, errOcspMissing):
+			code = models.CodeOcspMissing
+		case errors.Is(err, errOcspInvalid):
+			code = models.CodeOcspInvalidResponse
+		}
+		result.fail(models.CheckRevocation, models.CheckStatusError, code, "Error checking certificate revocation")
 		return result, nil
 	}
+	result.ocspNextUpdate = ocspResponse.NextUpdate
+	result.ocspResponse = ocspResponse.Raw
 	if isRevoked {
 		log.Printf("Certificate is revoked")
-		result.Valid = false
-		result.Reason = "Certificate is revoked"
+		result.fail(models.CheckRevocation, models.CheckStatusFail, models.CodeRevoked, "Certificate is revoked")
 		return result, nil
 	}
+	result.pass(models.CheckRevocation, models.CodeNotRevoked, "")
 
 	return result, nil
 }
 
-func (s *VerifySvc) updateIntermediates(c *gin.Context, certs []*cert.ParsedCert) error {
+var errChainLoad = errors.New("error loading certificate chain")
+
+// buildChain verifies the certificate against the trusted roots using the
+// intermediates supplied with the request. The chain is only downloaded
+// through AIA when the supplied intermediates do not reach a trusted root.
+func (s *VerifySvc) buildChain(ctx context.Context, crt *cert.ParsedCert, supplied []*cert.ParsedCert, at time.Time) (bool, []*x509.Certificate, error) {
+	// Intermediates already known from earlier verifications are tried as well,
+	// so certificates of the same issuer do not trigger repeated downloads.
+	isTrusted, chain, err := s.isTrusted(ctx, crt.Cert, supplied, at, "known")
+	if err == nil && isTrusted {
+		s.updateIntermediates(ctx, chainIntermediates(chain, supplied))
+		return true, chain, nil
+	}
+	log.Printf("Known intermediates do not reach a trusted root, falling back to AIA")
+	if len(crt.Cert.IssuingCertificateURL) == 0 {
+		return false, nil, fmt.Errorf("%w: no issuing certificate URL found", errChainLoad)
+	}
+	downloaded, err := s.loadCertChain(ctx, crt.Cert.IssuingCertificateURL[0])
+	if err != nil {
+		return false, nil, fmt.Errorf("%w: %w", errChainLoad, err)
+	}
+	candidates := append(slices.Clone(supplied), downloaded...)
+	isTrusted, chain, err = s.isTrusted(ctx, crt.Cert, candidates, at, "aia")
+	if err != nil || !isTrusted {
+		return isTrusted, chain, err
+	}
+	s.updateIntermediates(ctx, chainIntermediates(chain, candidates))
+	return true, chain, nil
+}
+
+// chainIntermediates returns the candidates that are part of the verified chain.
+func chainIntermediates(chain []*x509.Certificate, candidates []*cert.ParsedCert) []*cert.ParsedCert {
+	res := make([]*cert.ParsedCert, 0)
+	for _, crt := range candidates {
+		if slices.ContainsFunc(chain, crt.Cert.Equal) {
+			res = append(res, crt)
+		}
+	}
+	return res
+}
+
+func (s *VerifySvc) updateIntermediates(ctx context.Context, certs []*cert.ParsedCert) error {
 	for _, crt := range certs {
-		if !s.HashExists(crt.Sha256()) {
-			s.AddIntermediate(crt)
-			s.addHash(crt.Sha256())
-			s.db.AddCertificate(c, crt)
+		if s.addIntermediate(crt) {
+			// Stored with the service clock rather than the requested
+			// verification time, as this is when the certificate was learned
+			now := s.clock.Now()
+			crt.CreatedAt, crt.UpdatedAt = now, now
+			crt.IsActive = true
+			crt.Position = models.PositionIntermediate
+			s.db.AddCertificate(ctx, crt)
 			log.Printf("Added intermediate certificate with SHA256 %s", crt.Sha256())
 		} else {
 			log.Printf("Intermediate certificate with SHA256 %s already exists", crt.Sha256())
@@ -347,7 +729,7 @@ func (s *VerifySvc) updateIntermediates(c *gin.Context, certs []*cert.ParsedCert
 	return nil
 }
 
-func (s *VerifySvc) loadCertChain(c *gin.Context, link string) ([]*cert.ParsedCert, error) {
+func (s *VerifySvc) loadCertChain(ctx context.Context, link string) ([]*cert.ParsedCert, error) {
 	// This function gets the certificate chain for the given certificate
 	// First it queries the database for the certificate chain
 	// If the chain is not found, it tries to download it from the OCSP server
@@ -358,24 +740,8 @@ func (s *VerifySvc) loadCertChain(c *gin.Context, link string) ([]*cert.ParsedCe
 	chain := make([]*cert.ParsedCert, 0)
 	for parentLink != "" && parentLink != prevParentLink {
 		prevParentLink = parentLink
-		req, err := http.NewRequest("GET", parentLink, nil)
-		if err != nil {
-			log.Printf("Error creating request to download certificate chain: %s", err)
-			return nil, err
-		}
-		resp, err := s.httpClient.Do(req)
-		if err != nil {
-			log.Printf("Error downloading certificate chain: %s", err)
-			return nil, err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("Error downloading certificate chain: %s", resp.Status)
-			return nil, errors.New("error downloading certificate chain")
-		}
-		certs, err := s.loadCerts(c, resp.Body)
+		certs, err := s.downloadCerts(ctx, parentLink)
 		if err != nil {
-			log.Printf("Error loading certificates from response body: %s", err)
 			return nil, err
 		}
 		if len(certs) == 0 {
@@ -401,7 +767,53 @@ func (s *VerifySvc) loadCertChain(c *gin.Context, link string) ([]*cert.ParsedCe
 	return chain, nil
 }
 
-func (s *VerifySvc) loadCerts(c *gin.Context, body io.ReadCloser) ([]*cert.ParsedCert, error) {
+// downloadCerts fetches the certificates published at the given AIA link.
+// Concurrent downloads of the same link are shared.
+func (s *VerifySvc) downloadCerts(ctx context.Context, link string) ([]*cert.ParsedCert, error) {
+	start := time.Now()
+	download, shared, err := sharedLookup(ctx, &s.lookups, "aia:"+link, func(ctx context.Context) (aiaDownload, error) {
+		download := aiaDownload{}
+		req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+		if err != nil {
+			log.Printf("Error creating request to download certificate chain: %s", err)
+			return download, err
+		}
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			log.Printf("Error downloading certificate chain: %s", err)
+			return download, err
+		}
+		defer resp.Body.Close()
+		download.status = resp.StatusCode
+		if resp.StatusCode != http.StatusOK {
+			log.Printf("Error downloading certificate chain: %s", resp.Status)
+			return download, errors.New("error downloading certificate chain")
+		}
+		download.certs, err = s.loadCerts(ctx, resp.Body)
+		if err != nil {
+			log.Printf("Error loading certificates from response body: %s", err)
+			return download, err
+		}
+		return download, nil
+	})
+	trace := traceFromContext(ctx)
+	trace.aiaFetch(link, download.status, time.Since(start), shared, len(download.certs), err)
+	if err != nil {
+		return nil, err
+	}
+	for _, crt := range download.certs {
+		trace.certificate(crt.Cert, link)
+	}
+	return download.certs, nil
+}
+
+// aiaDownload is the shared result of an AIA download.
+type aiaDownload struct {
+	certs  []*cert.ParsedCert
+	status int // HTTP status, zero if no response was received
+}
+
+func (s *VerifySvc) loadCerts(ctx context.Context, body io.ReadCloser) ([]*cert.ParsedCert, error) {
 	bodyBytes, err := io.ReadAll(body)
 	if err != nil {
 		log.Printf("Error reading response body: %s", err)
@@ -415,7 +827,7 @@ func (s *VerifySvc) loadCerts(c *gin.Context, body io.ReadCloser) ([]*cert.Parse
 	return certs, nil
 }
 
-func (s *VerifySvc) getScopes(c *gin.Context, crt *cert.ParsedCert, tpp *models.TppResponse) map[string][]string {
+func (s *VerifySvc) getScopes(ctx context.Context, crt *cert.ParsedCert, tpp *models.TppResponse) map[string][]string {
 	certServices := getCertServices(*crt)
 	if len(certServices) == 0 {
 		log.Printf("No services found in the certificate for TPP %s", tpp.Id)
@@ -432,6 +844,37 @@ func (s *VerifySvc) getScopes(c *gin.Context, crt *cert.ParsedCert, tpp *models.
 	return scopes
 }
 
+// SetAspspCountries restricts the returned scopes to the countries the ASPSP operates in.
+func (s *VerifySvc) SetAspspCountries(countries []string) {
+	s.aspspCountries = normalizeCountries(countries)
+}
+
+// allowedCountries returns the countries scopes are filtered to, or nil if all are allowed.
+// A country requested by the caller must be one of the configured ASPSP countries.
+func allowedCountries(configured []string, requested string) []string {
+	requested = strings.ToUpper(strings.TrimSpace(requested))
+	if requested == "" {
+		return configured
+	}
+	if len(configured) > 0 && !slices.Contains(configured, requested) {
+		return []string{}
+	}
+	return []string{requested}
+}
+
+func filterScopes(scopes map[string][]string, countries []string) map[string][]string {
+	if countries == nil {
+		return scopes
+	}
+	res := make(map[string][]string)
+	for country, services := range scopes {
+		if slices.Contains(countries, country) {
+			res[country] = services
+		}
+	}
+	return res
+}
+
 func getCertServices(crt cert.ParsedCert) []models.Service {
 	services := make([]models.Service, 0)
 	scopes, err := crt.OBScopes()
@@ -3,23 +3,32 @@ package verify
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
-	"encoding/json"
 	"encoding/pem"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
-	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/clock"
+	"github.com/botsman/tppVerifier/app/db"
+	vhttp "github.com/botsman/tppVerifier/app/http"
 	"github.com/botsman/tppVerifier/app/models"
-	"github.com/gin-gonic/gin"
+	"github.com/botsman/tppVerifier/app/policy"
+	"github.com/botsman/tppVerifier/app/receipt"
 	"golang.org/x/crypto/ocsp"
 )
 
@@ -82,6 +91,9 @@ xATrtuTrc1kaX09wMf2RE7A/7ZZzEzVO89u/iRZZVnVFMX4fHG5Jlw0idnsRPitw
 yg7QQy0XpA2r/vN/PrCUiZ0leQVwtN+1q6TzcMKaBf+hjQ==
 -----END CERTIFICATE-----`
 
+// registryVersion is the last update of the registry records of MockDb.
+var registryVersion = time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC)
+
 type MockDb struct {
 }
 
@@ -92,15 +104,16 @@ func (m *MockDb) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
 			Id:         "PSDFIN-FINFSA-12345678",
 			NameLatin:  "Test TPP",
 			NameNative: "Teszt TPP",
-			Authority:  "Test Authority",
+			Authority:  "FINFSA",
+			Country:    "FI",
 			Services: map[string][]models.Service{
 				"FI": {models.AISP, models.PISP},
 			},
-			AuthorizedAt: getRef(time.Now()),
+			AuthorizedAt: getRef(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
 			WithdrawnAt:  getRef(time.Time{}),
 			Type:         "TPP",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
+			CreatedAt:    registryVersion,
+			UpdatedAt:    registryVersion,
 			Registry:     "Test Registry",
 		}, nil
 	default:
@@ -108,6 +121,22 @@ func (m *MockDb) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
 	}
 }
 
+func (m *MockDb) GetTppByEntityCode(ctx context.Context, code string) (*models.TPP, error) {
+	return nil, db.ErrTppNotFound
+}
+
+func (m *MockDb) GetTppsByNationalReference(ctx context.Context, code string) ([]*models.TPP, error) {
+	return nil, nil
+}
+
+func (m *MockDb) SearchTpps(ctx context.Context, filter db.TppFilter) ([]*models.TPP, error) {
+	return nil, nil
+}
+
+func (m *MockDb) GetTppChanges(ctx context.Context, filter db.TppChangeFilter) ([]models.TppChange, error) {
+	return nil, nil
+}
+
 func getRef[T any](val T) *T {
 	return &val
 }
@@ -126,6 +155,9 @@ func NewMockDb() *MockDb {
 
 type MockHttpClient struct {
 	chainPath string
+	revokedAt time.Time // OCSP responses report the certificate revoked at this time if set
+	mu        sync.Mutex
+	requested []string
 }
 
 func (m *MockHttpClient) SetChainPath(path string) {
@@ -133,6 +165,9 @@ func (m *MockHttpClient) SetChainPath(path string) {
 }
 
 func (m *MockHttpClient) Do(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	m.requested = append(m.requested, req.URL.String())
+	m.mu.Unlock()
 	switch req.URL.String() {
 	case "http://test.company.hu/CA.crt":
 		data, err := os.ReadFile(path.Join(m.chainPath, "ca.pem"))
@@ -219,13 +254,25 @@ func (m *MockHttpClient) getMockOCSPResponseBody(req *http.Request) []byte {
 		}
 	}
 
+	// Answer for the requested certificate, so that other leaves of the chain are covered as well
+	serialNumber := leafCert.SerialNumber
+	if body, err := io.ReadAll(req.Body); err == nil {
+		if ocspReq, err := ocsp.ParseRequest(body); err == nil {
+			serialNumber = ocspReq.SerialNumber
+		}
+	}
+
 	// Create OCSP response template
 	template := ocsp.Response{
 		Status:       ocsp.Good,
-		SerialNumber: leafCert.SerialNumber,
+		SerialNumber: serialNumber,
 		ThisUpdate:   time.Now(),
 		NextUpdate:   time.Now().Add(1 * time.Hour),
 	}
+	if !m.revokedAt.IsZero() {
+		template.Status = ocsp.Revoked
+		template.RevokedAt = m.revokedAt
+	}
 
 	// Generate OCSP response
 	respDER, err := ocsp.CreateResponse(issuerCert, issuerCert, template, signerKey)
@@ -323,9 +370,9 @@ func TestGetTpp(t *testing.T) {
 	if svc == nil {
 		t.Fatal("Expected non-nil VerifySvc")
 	}
-	ctx := gin.Context{}
+	ctx := context.Background()
 	companyId := "PSDFIN-FINFSA-12345678"
-	tpp, err := svc.getTppResponse(&ctx, companyId)
+	tpp, err := svc.getTppResponse(ctx, companyId, time.Now())
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -342,8 +389,8 @@ func TestGetTpp(t *testing.T) {
 	if tpp.NameNative != "Teszt TPP" {
 		t.Errorf("Expected TPP NameNative 'Teszt TPP', got '%s'", tpp.NameNative)
 	}
-	if tpp.Authority != "Test Authority" {
-		t.Errorf("Expected TPP Authority 'Test Authority', got '%s'", tpp.Authority)
+	if tpp.Authority != "FINFSA" {
+		t.Errorf("Expected TPP Authority 'FINFSA', got '%s'", tpp.Authority)
 	}
 	if len(tpp.Services) == 0 {
 		t.Error("Expected non-empty TPP Services, got none")
@@ -360,7 +407,7 @@ func TestVerifyCert(t *testing.T) {
 	if svc == nil {
 		t.Fatal("Expected non-nil VerifySvc")
 	}
-	ctx := gin.Context{}
+	ctx := context.Background()
 	chainsPath := getTestDataPath("chains")
 	entries, err := os.ReadDir(chainsPath)
 	if err != nil {
@@ -410,7 +457,7 @@ func TestVerifyCert(t *testing.T) {
 		t.Logf("Parsed certificate: %+v", cert)
 		// Simulate a successful verification
 
-		verifyRes, err := svc.verifyCert(&ctx, cert)
+		verifyRes, err := svc.verifyCert(ctx, cert, nil, "", policy.Default(), time.Now())
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
 		}
@@ -430,9 +477,9 @@ func TestGetScopes(t *testing.T) {
 	if svc == nil {
 		t.Fatal("Expected non-nil VerifySvc")
 	}
-	ctx := gin.Context{}
+	ctx := context.Background()
 	companyId := "PSDFIN-FINFSA-12345678"
-	tpp, err := svc.getTppResponse(&ctx, companyId)
+	tpp, err := svc.getTppResponse(ctx, companyId, time.Now())
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -447,7 +494,7 @@ func TestGetScopes(t *testing.T) {
 		t.Fatal("Expected non-empty certificate slice")
 	}
 	cert := certs[0]
-	scopes := svc.getScopes(&ctx, cert, tpp)
+	scopes := svc.getScopes(ctx, cert, tpp)
 	if len(scopes) == 0 {
 		t.Fatal("Expected non-empty scopes")
 	}
@@ -455,7 +502,6 @@ func TestGetScopes(t *testing.T) {
 }
 
 func TestVerify_Success(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	db := NewMockDb()
 	if db == nil {
 		t.Fatal("Expected non-nil MockDb")
@@ -495,38 +541,17 @@ func TestVerify_Success(t *testing.T) {
 	svc.AddRoot(caCerts[0])
 	// Set the certificate content in the request
 	verifyRequest.Cert = string(certContent)
-	body, err := json.Marshal(verifyRequest)
+	verifyResponse, err := svc.Verify(context.Background(), verifyRequest)
 	if err != nil {
-		t.Fatalf("Couldn't marshal request: %v\n", err)
-	}
-
-	req, err := http.NewRequest(http.MethodPost, "/verify", bytes.NewBuffer(body))
-	if err != nil {
-		t.Fatalf("Couldn't create request: %v\n", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Request = req
-	c.Set("svc", svc)
-	svc.Verify(c)
-	if w.Code != http.StatusOK {
-		t.Fatalf("Expected status code %d, got %d\n", http.StatusOK, w.Code)
-	}
-	var verifyResponse VerifyResponse
-	res := w.Body.String()
-	t.Logf("Response: %s", res)
-	if err := json.Unmarshal(w.Body.Bytes(), &verifyResponse); err != nil {
-		t.Fatalf("Couldn't unmarshal response: %v\n", err)
+		t.Fatalf("Expected no error, got %v\n", err)
 	}
+	t.Logf("Response: %+v", verifyResponse)
 	if !verifyResponse.Valid {
 		t.Errorf("Expected valid certificate, got invalid: %s\n", verifyResponse.Reason)
 	}
 }
 
 func TestVerify_Failure(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	db := NewMockDb()
 	if db == nil {
 		t.Fatal("Expected non-nil MockDb")
@@ -540,31 +565,389 @@ func TestVerify_Failure(t *testing.T) {
 	}
 	var verifyRequest VerifyRequest
 	verifyRequest.Cert = string(certContent)
-	body, err := json.Marshal(verifyRequest)
+	verifyResponse, err := svc.Verify(context.Background(), verifyRequest)
 	if err != nil {
-		t.Fatalf("Couldn't marshal request: %v\n", err)
+		t.Fatalf("Expected no error, got %v\n", err)
+	}
+	if verifyResponse.Valid {
+		t.Error("Expected invalid certificate, got valid")
+	}
+	if check := findCheck(verifyResponse.Checks, models.CheckSandbox); check == nil || check.Code != models.CodeSandbox {
+		t.Errorf("Expected sandbox check with code %s, got %+v", models.CodeSandbox, check)
+	}
+	if check := findCheck(verifyResponse.Checks, models.CheckChain); check == nil || check.Status != models.CheckStatusSkipped {
+		t.Errorf("Expected skipped chain check, got %+v", check)
+	}
+}
+
+func findCheck(checks []models.Check, name models.CheckName) *models.Check {
+	for i := range checks {
+		if checks[i].Name == name {
+			return &checks[i]
+		}
 	}
+	return nil
+}
+
+// unavailableOcspClient behaves like MockHttpClient but the OCSP responder is down.
+type unavailableOcspClient struct {
+	*MockHttpClient
+}
 
-	req, err := http.NewRequest(http.MethodPost, "/verify", bytes.NewBuffer(body))
+func (m *unavailableOcspClient) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.String() == "http://test.company.hu/testca" {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(bytes.NewReader(nil)),
+		}, nil
+	}
+	return m.MockHttpClient.Do(req)
+}
+
+// readTestFile returns the content of a file in testdata.
+func readTestFile(t *testing.T, relPath string) string {
+	t.Helper()
+	content, err := os.ReadFile(getTestDataPath(relPath))
 	if err != nil {
-		t.Fatalf("Couldn't create request: %v\n", err)
+		t.Fatalf("Couldn't read %s: %v", relPath, err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return string(content)
+}
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Request = req
-	c.Set("svc", svc)
-	svc.Verify(c)
-	if w.Code != http.StatusOK {
-		t.Fatalf("Expected status code %d, got %d\n", http.StatusOK, w.Code)
+// newProductionSvc returns a VerifySvc trusting the production test chain root.
+func newProductionSvc(t *testing.T, repo db.TppRepository, httpClient vhttp.Client) *VerifySvc {
+	t.Helper()
+	svc := NewVerifySvc(repo, httpClient)
+	caCerts, err := cert.ParseCerts([]byte(readTestFile(t, "chains/production/ca.pem")))
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
 	}
-	var verifyResponse VerifyResponse
-	if err := json.Unmarshal(w.Body.Bytes(), &verifyResponse); err != nil {
-		t.Fatalf("Couldn't unmarshal response: %v\n", err)
+	svc.AddRoot(caCerts[0])
+	return svc
+}
+
+func TestVerifyCert_OcspUnavailable(t *testing.T) {
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	svc := newProductionSvc(t, NewMockDb(), &unavailableOcspClient{httpClient})
+	certs, err := cert.ParseCerts([]byte(readTestFile(t, "chains/production/leaf.pem")))
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
 	}
-	if verifyResponse.Valid {
-		t.Error("Expected invalid certificate, got valid")
+	res, err := svc.verifyCert(context.Background(), certs[0], nil, "", policy.Default(), time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Valid {
+		t.Error("Expected invalid result when OCSP is unavailable")
+	}
+	check := findCheck(res.Checks, models.CheckRevocation)
+	if check == nil || check.Status != models.CheckStatusError || check.Code != models.CodeOcspUnavailable {
+		t.Errorf("Expected revocation error with code %s, got %+v", models.CodeOcspUnavailable, check)
+	}
+	if check := findCheck(res.Checks, models.CheckChain); check == nil || check.Status != models.CheckStatusPass {
+		t.Errorf("Expected passed chain check, got %+v", check)
+	}
+}
+
+func TestVerify_SuppliedChain(t *testing.T) {
+	leaf, intermediate := readTestFile(t, "chains/production/leaf.pem"), readTestFile(t, "chains/production/intermediate.pem")
+	tests := []struct {
+		name string
+		req  VerifyRequest
+	}{
+		{"PEM bundle", VerifyRequest{Cert: leaf + intermediate}},
+		{"PEM bundle intermediate first", VerifyRequest{Cert: intermediate + leaf}},
+		{"Separate chain", VerifyRequest{Cert: leaf, Chain: []string{intermediate}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := NewMockHttpClient()
+			httpClient.SetChainPath(getTestDataPath("chains/production"))
+			svc := newProductionSvc(t, NewMockDb(), httpClient)
+			res, err := svc.Verify(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !res.Valid {
+				t.Errorf("Expected valid certificate, got invalid: %s", res.Reason)
+			}
+			if slices.Contains(httpClient.requested, "http://yourdomain.com/certs/intermediate.crt") {
+				t.Error("Expected supplied intermediate to be used instead of AIA download")
+			}
+		})
+	}
+}
+
+func TestVerify_OnlyCACertificates(t *testing.T) {
+	intermediate, ca := readTestFile(t, "chains/production/intermediate.pem"), readTestFile(t, "chains/production/ca.pem")
+	svc := newProductionSvc(t, NewMockDb(), NewMockHttpClient())
+	for _, req := range []VerifyRequest{{Cert: intermediate + ca}, {Cert: ca, Chain: []string{intermediate}}} {
+		if _, err := svc.Verify(context.Background(), req); !errors.Is(err, ErrNoCertificate) {
+			t.Errorf("Expected error %v, got %v", ErrNoCertificate, err)
+		}
+	}
+}
+
+func TestItemError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: %w", ErrCertificateParse, errors.New("asn1: syntax error")), "failed to parse certificate: asn1: syntax error"},
+		{db.ErrTppNotFound, db.ErrTppNotFound.Error()},
+		{fmt.Errorf("%w: %w", ErrTppLookup, errors.New("mongo: connection refused")), ErrTppLookup.Error()},
+		{fmt.Errorf("%w: %w", ErrCertificateVerify, errors.New("mongo: connection refused")), "Failed to process the request."},
+		{context.DeadlineExceeded, context.DeadlineExceeded.Error()},
+	}
+	for _, tt := range tests {
+		if got := itemError(tt.err); got != tt.want {
+			t.Errorf("Expected %q for %v, got %q", tt.want, tt.err, got)
+		}
+	}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	svc := newProductionSvc(t, NewMockDb(), httpClient)
+	svc.SetBatchWorkers(2)
+	leafContent := readTestFile(t, "chains/production/leaf.pem")
+	items := []VerifyRequest{{Cert: "not a certificate"}}
+	for range 5 {
+		items = append(items, VerifyRequest{Cert: leafContent})
+	}
+	res, err := svc.VerifyBatch(context.Background(), BatchVerifyRequest{Items: items})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(res.Results) != len(items) {
+		t.Fatalf("Expected %d results, got %d", len(items), len(res.Results))
+	}
+	if res.Results[0].Error == "" || res.Results[0].Result != nil {
+		t.Errorf("Expected first item to fail, got %+v", res.Results[0])
+	}
+	for _, item := range res.Results[1:] {
+		if item.Result == nil || !item.Result.Valid {
+			t.Errorf("Expected item %d to be valid, got %+v", item.Index, item)
+		}
+	}
+	downloads := 0
+	for _, url := range httpClient.requested {
+		if url == "http://yourdomain.com/certs/intermediate.crt" {
+			downloads++
+		}
+	}
+	if downloads > 2 {
+		t.Errorf("Expected the intermediate to be shared across items, downloaded %d times", downloads)
+	}
+
+	if _, err := svc.VerifyBatch(context.Background(), BatchVerifyRequest{}); !errors.Is(err, ErrEmptyBatch) {
+		t.Errorf("Expected ErrEmptyBatch, got %v", err)
+	}
+}
+
+// emptyRegistryDb is a repository without any TPPs.
+type emptyRegistryDb struct {
+	MockDb
+}
+
+func (m *emptyRegistryDb) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
+	return nil, db.ErrTppNotFound
+}
+
+func TestVerify_RegistryMiss(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name          string
+		registryCheck *bool
+		valid         bool
+		status        models.CheckStatus
+		code          models.CheckCode
+	}{
+		{"Registry miss", nil, false, models.CheckStatusFail, models.CodeTppNotFound},
+		{"Certificate only", &disabled, true, models.CheckStatusSkipped, models.CodeRegistryCheckDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := NewMockHttpClient()
+			httpClient.SetChainPath(getTestDataPath("chains/production"))
+			svc := newProductionSvc(t, &emptyRegistryDb{}, httpClient)
+			res, err := svc.Verify(context.Background(), VerifyRequest{
+				Cert:          readTestFile(t, "chains/production/leaf.pem"),
+				RegistryCheck: tt.registryCheck,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v: %s", tt.valid, res.Valid, res.Reason)
+			}
+			if res.TPP != nil {
+				t.Errorf("Expected no TPP, got %+v", res.TPP)
+			}
+			check := findCheck(res.Checks, models.CheckRegistry)
+			if check == nil || check.Status != tt.status || check.Code != tt.code {
+				t.Errorf("Expected registry check %s/%s, got %+v", tt.status, tt.code, check)
+			}
+			if check := findCheck(res.Checks, models.CheckChain); check == nil || check.Status != models.CheckStatusPass {
+				t.Errorf("Expected chain check to run and pass, got %+v", check)
+			}
+		})
+	}
+}
+
+func TestVerify_AspspCountries(t *testing.T) {
+	tests := []struct {
+		name      string
+		countries []string
+		requested string
+		valid     bool
+		scopes    int
+	}{
+		{"No restriction", nil, "", true, 1},
+		{"Deployment country", []string{"fi", "SE"}, "", true, 1},
+		{"Requested country", nil, "FI", true, 1},
+		{"Requested country outside deployment", []string{"SE"}, "FI", false, 0},
+		{"No allowed country", []string{"SE", "NO"}, "", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := NewMockHttpClient()
+			httpClient.SetChainPath(getTestDataPath("chains/production"))
+			svc := newProductionSvc(t, NewMockDb(), httpClient)
+			svc.SetAspspCountries(tt.countries)
+			res, err := svc.Verify(context.Background(), VerifyRequest{
+				Cert:         readTestFile(t, "chains/production/leaf.pem"),
+				AspspCountry: tt.requested,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v: %s", tt.valid, res.Valid, res.Reason)
+			}
+			if len(res.Scopes) != tt.scopes {
+				t.Errorf("Expected %d scope countries, got %v", tt.scopes, res.Scopes)
+			}
+			if !tt.valid {
+				if check := findCheck(res.Checks, models.CheckScope); check == nil || check.Code != models.CodeNoAllowedCountry {
+					t.Errorf("Expected scope check with code %s, got %+v", models.CodeNoAllowedCountry, check)
+				}
+			}
+		})
+	}
+}
+
+// servicelessDb returns the TPP of MockDb without any services.
+type servicelessDb struct {
+	MockDb
+}
+
+func (m *servicelessDb) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
+	tpp, err := m.MockDb.GetTpp(ctx, id)
+	if tpp != nil {
+		tpp.Services = map[string][]models.Service{}
+	}
+	return tpp, err
+}
+
+func TestVerify_NoScopes(t *testing.T) {
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	svc := newProductionSvc(t, &servicelessDb{}, httpClient)
+	svc.SetCacheTTL(time.Hour)
+	req := VerifyRequest{Cert: readTestFile(t, "chains/production/leaf.pem")}
+	if _, err := svc.Verify(context.Background(), req); !errors.Is(err, ErrNoScopes) {
+		t.Fatalf("Expected error %v, got %v", ErrNoScopes, err)
+	}
+
+	req.NoScopesAsCheck = true
+	res, err := svc.Verify(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Valid || len(res.Scopes) != 0 {
+		t.Errorf("Expected an invalid result without scopes, got %v %v", res.Valid, res.Scopes)
+	}
+	if check := findCheck(res.Checks, models.CheckScope); check == nil || check.Code != models.CodeNoScopes {
+		t.Errorf("Expected scope check with code %s, got %+v", models.CodeNoScopes, check)
+	}
+
+	// The cached result of the check is not returned to requests expecting the error
+	req.NoScopesAsCheck = false
+	if _, err := svc.Verify(context.Background(), req); !errors.Is(err, ErrNoScopes) {
+		t.Errorf("Expected error %v, got %v", ErrNoScopes, err)
+	}
+}
+
+func TestVerify_At(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	revokedAt := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		at      *time.Time
+		valid   bool
+		expired bool
+		check   models.CheckName
+		code    models.CheckCode
+	}{
+		{"Now", nil, false, false, models.CheckRevocation, models.CodeRevoked},
+		{"Before revocation", getRef(time.Date(2026, 3, 14, 10, 2, 0, 0, time.UTC)), true, false, models.CheckRevocation, models.CodeNotRevoked},
+		{"Before certificate validity", getRef(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)), false, true, models.CheckChain, models.CodeChainUntrusted},
+		{"Before authorization", getRef(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)), false, true, models.CheckRegistry, models.CodeTppNotAuthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := NewMockHttpClient()
+			httpClient.SetChainPath(getTestDataPath("chains/production"))
+			httpClient.revokedAt = revokedAt
+			svc := newProductionSvc(t, NewMockDb(), httpClient)
+			svc.SetClock(clock.Fixed(now))
+			res, err := svc.Verify(context.Background(), VerifyRequest{
+				Cert: readTestFile(t, "chains/production/leaf.pem"),
+				At:   tt.at,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v: %s", tt.valid, res.Valid, res.Reason)
+			}
+			if res.Certificate.Expired != tt.expired {
+				t.Errorf("Expected expired %v, got %v", tt.expired, res.Certificate.Expired)
+			}
+			if tt.at != nil && !res.VerifiedAt.Equal(*tt.at) {
+				t.Errorf("Expected verified_at %s, got %s", tt.at, res.VerifiedAt)
+			}
+			if check := findCheck(res.Checks, tt.check); check == nil || check.Code != tt.code {
+				t.Errorf("Expected %s check with code %s, got %+v", tt.check, tt.code, check)
+			}
+		})
+	}
+}
+
+func TestCheckAuthorization(t *testing.T) {
+	tests := []struct {
+		status models.AuthorizationStatus
+		valid  bool
+		code   models.CheckCode
+	}{
+		{models.AuthorizationStatusAuthorized, true, models.CodeTppFound},
+		{models.AuthorizationStatusWithdrawn, false, models.CodeTppWithdrawn},
+		{models.AuthorizationStatusNotAuthorized, false, models.CodeTppNotAuthorized},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			res := certVerifyResponse{Valid: true}
+			checkAuthorization(&res, &models.TppResponse{Id: "PSDFIN-FINFSA-12345678", AuthorizationStatus: tt.status})
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, res.Valid)
+			}
+			if check := findCheck(res.Checks, models.CheckRegistry); check == nil || check.Code != tt.code {
+				t.Errorf("Expected registry check with code %s, got %+v", tt.code, check)
+			}
+		})
 	}
 }
 
@@ -586,3 +969,336 @@ func TestNormalizeTppId(t *testing.T) {
 		})
 	}
 }
+
+// rootsDb is a repository returning the given root certificates.
+type rootsDb struct {
+	MockDb
+	roots []string
+}
+
+func (m *rootsDb) GetRootCertificates(ctx context.Context) ([]string, error) {
+	return m.roots, nil
+}
+
+func TestVerify_Cache(t *testing.T) {
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	repo := &rootsDb{roots: []string{readTestFile(t, "chains/production/ca.pem")}}
+	svc := NewVerifySvc(repo, httpClient)
+	if err := svc.LoadRoots(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	svc.SetCacheTTL(3 * time.Hour)
+	req := VerifyRequest{Cert: readTestFile(t, "chains/production/leaf.pem")}
+	withChain := VerifyRequest{Cert: req.Cert, Chain: []string{readTestFile(t, "chains/production/intermediate.pem")}}
+	ocspRequests := func() int {
+		n := 0
+		for _, url := range httpClient.requested {
+			if url == "http://test.company.hu/testca" {
+				n++
+			}
+		}
+		return n
+	}
+	tests := []struct {
+		name   string
+		before func()
+		req    VerifyRequest
+		cached bool
+		ocsp   int
+	}{
+		{"First verification", nil, req, false, 1},
+		{"Cached", nil, req, true, 1},
+		{"Different ASPSP country", nil, VerifyRequest{Cert: req.Cert, AspspCountry: "FI"}, false, 2},
+		{"Bypass", nil, VerifyRequest{Cert: req.Cert, NoCache: true}, false, 3},
+		{"Cached after bypass", nil, req, true, 3},
+		{"Supplied intermediates", nil, withChain, false, 4},
+		{"Cached with intermediates", nil, withChain, true, 4},
+		{"Point in time", nil, VerifyRequest{Cert: req.Cert, At: getRef(time.Now())}, false, 5},
+		{"Roots reloaded", func() {
+			if err := svc.LoadRoots(context.Background()); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}, req, false, 6},
+		{"Past OCSP next update", func() {
+			svc.SetClock(clock.Fixed(time.Now().Add(2 * time.Hour)))
+		}, req, false, 7},
+	}
+	for _, tt := range tests {
+		if tt.before != nil {
+			tt.before()
+		}
+		res, err := svc.Verify(context.Background(), tt.req)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.name, err)
+		}
+		if !res.Valid {
+			t.Errorf("%s: expected valid certificate, got invalid: %s", tt.name, res.Reason)
+		}
+		if res.Cached != tt.cached {
+			t.Errorf("%s: expected cached %v, got %v", tt.name, tt.cached, res.Cached)
+		}
+		if n := ocspRequests(); n != tt.ocsp {
+			t.Errorf("%s: expected %d OCSP requests, got %d", tt.name, tt.ocsp, n)
+		}
+	}
+}
+
+// reloadedDb is a registry that can be reloaded with a withdrawn TPP.
+type reloadedDb struct {
+	rootsDb
+	updatedAt   time.Time
+	withdrawnAt *time.Time
+}
+
+func (m *reloadedDb) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
+	tpp, err := m.MockDb.GetTpp(ctx, id)
+	if tpp != nil {
+		tpp.UpdatedAt = m.updatedAt
+		tpp.WithdrawnAt = m.withdrawnAt
+	}
+	return tpp, err
+}
+
+func TestVerify_CacheRegistryReload(t *testing.T) {
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	repo := &reloadedDb{rootsDb: rootsDb{roots: []string{readTestFile(t, "chains/production/ca.pem")}}, updatedAt: registryVersion}
+	svc := NewVerifySvc(repo, httpClient)
+	if err := svc.LoadRoots(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	svc.SetCacheTTL(3 * time.Hour)
+	req := VerifyRequest{Cert: readTestFile(t, "chains/production/leaf.pem")}
+	for _, cached := range []bool{false, true} {
+		res, err := svc.Verify(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res.Cached != cached {
+			t.Errorf("Expected cached %v, got %v", cached, res.Cached)
+		}
+	}
+
+	// The import withdraws the TPP and updates its record
+	repo.updatedAt = registryVersion.Add(24 * time.Hour)
+	repo.withdrawnAt = getRef(registryVersion.Add(12 * time.Hour))
+	res, err := svc.Verify(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Cached {
+		t.Error("Expected a fresh result after the registry was reloaded")
+	}
+	if res.TPP == nil || res.TPP.AuthorizationStatus != models.AuthorizationStatusWithdrawn {
+		t.Errorf("Expected the withdrawn TPP, got %+v", res.TPP)
+	}
+	if res, err := svc.Verify(context.Background(), req); err != nil || !res.Cached {
+		t.Errorf("Expected the fresh result to be cached, got %v", err)
+	}
+}
+
+func TestVerify_CacheSkipsErrors(t *testing.T) {
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	svc := newProductionSvc(t, NewMockDb(), &unavailableOcspClient{httpClient})
+	svc.SetCacheTTL(time.Hour)
+	req := VerifyRequest{Cert: readTestFile(t, "chains/production/leaf.pem")}
+	for range 2 {
+		res, err := svc.Verify(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res.Cached {
+			t.Error("Expected result with an OCSP error not to be cached")
+		}
+	}
+}
+
+func TestVerify_Policy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *policy.Policy
+		ocspDown bool
+		valid    bool
+		check    models.CheckName
+		status   models.CheckStatus
+		code     models.CheckCode
+	}{
+		{"Default", policy.Default(), false, true, models.CheckRevocation, models.CheckStatusPass, models.CodeNotRevoked},
+		{"Usage not allowed", &policy.Policy{Name: "qwac", AllowedUsages: []models.CertUsage{models.QWAC}}, false, false, models.CheckUsage, models.CheckStatusFail, models.CodeUsageNotAllowed},
+		{"Usage warning", &policy.Policy{Name: "qwac", AllowedUsages: []models.CertUsage{models.QWAC}, Checks: map[models.CheckName]policy.Enforcement{models.CheckUsage: policy.Warning}}, false, true, models.CheckUsage, models.CheckStatusWarning, models.CodeUsageNotAllowed},
+		{"Role not allowed", &policy.Policy{Name: "roles", AllowedRoles: []models.ObRole{models.PSP_IC}}, false, false, models.CheckRole, models.CheckStatusFail, models.CodeRoleNotAllowed},
+		{"Role allowed", &policy.Policy{Name: "roles", AllowedRoles: []models.ObRole{models.PSP_AI}}, false, true, models.CheckRole, models.CheckStatusPass, models.CodeRoleAllowed},
+		{"Certificate too old", &policy.Policy{Name: "age", MaxCertAge: "1h"}, false, false, models.CheckCertAge, models.CheckStatusFail, models.CodeCertTooOld},
+		{"QTSP country not allowed", &policy.Policy{Name: "qtsp", AllowedQtspCountries: []string{"fi"}}, false, false, models.CheckQtspCountry, models.CheckStatusFail, models.CodeQtspCountryNotAllowed},
+		{"Strict revocation", policy.Default(), true, false, models.CheckRevocation, models.CheckStatusError, models.CodeOcspUnavailable},
+		{"Soft revocation", &policy.Policy{Name: "soft", Revocation: policy.RevocationSoft}, true, true, models.CheckRevocation, models.CheckStatusWarning, models.CodeOcspUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err != nil {
+				t.Fatalf("Expected valid policy, got %v", err)
+			}
+			httpClient := NewMockHttpClient()
+			httpClient.SetChainPath(getTestDataPath("chains/production"))
+			var client vhttp.Client = httpClient
+			if tt.ocspDown {
+				client = &unavailableOcspClient{httpClient}
+			}
+			svc := newProductionSvc(t, NewMockDb(), client)
+			svc.SetPolicy(tt.policy)
+			res, err := svc.Verify(context.Background(), VerifyRequest{Cert: readTestFile(t, "chains/production/leaf.pem")})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v: %s", tt.valid, res.Valid, res.Reason)
+			}
+			if res.Policy != tt.policy.Name {
+				t.Errorf("Expected policy %s, got %s", tt.policy.Name, res.Policy)
+			}
+			if check := findCheck(res.Checks, tt.check); check == nil || check.Status != tt.status || check.Code != tt.code {
+				t.Errorf("Expected %s check with status %s and code %s, got %+v", tt.check, tt.status, tt.code, check)
+			}
+		})
+	}
+}
+
+func TestVerify_Tenant(t *testing.T) {
+	roles := &policy.Policy{Name: "corporate", AllowedRoles: []models.ObRole{models.PSP_IC}}
+	if err := roles.Validate(); err != nil {
+		t.Fatalf("Expected valid policy, got %v", err)
+	}
+	tests := []struct {
+		name   string
+		tenant *Tenant
+		valid  bool
+		policy string
+	}{
+		{"No tenant", nil, true, "default"},
+		{"Tenant without overrides", &Tenant{Id: "retail"}, true, "default"},
+		{"Tenant policy", &Tenant{Id: "corporate", Policy: roles}, false, "corporate"},
+		{"Tenant countries", &Tenant{Id: "subsidiary", AspspCountries: []string{"se"}}, false, "default"},
+	}
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	// A single service shows that neither the cache nor the settings leak between tenants
+	svc := newProductionSvc(t, NewMockDb(), httpClient)
+	svc.SetAspspCountries([]string{"FI"})
+	svc.SetCacheTTL(time.Hour)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.tenant != nil {
+				ctx = ContextWithTenant(ctx, tt.tenant)
+			}
+			res, err := svc.Verify(ctx, VerifyRequest{Cert: readTestFile(t, "chains/production/leaf.pem")})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %v: %s", tt.valid, res.Valid, res.Reason)
+			}
+			if res.Policy != tt.policy {
+				t.Errorf("Expected policy %s, got %s", tt.policy, res.Policy)
+			}
+		})
+	}
+}
+
+func TestVerify_Receipt(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := receipt.NewSigner(key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	svc := newProductionSvc(t, NewMockDb(), httpClient)
+	req := VerifyRequest{Cert: readTestFile(t, "chains/production/leaf.pem"), Receipt: true}
+	if _, err := svc.Verify(context.Background(), req); !errors.Is(err, ErrReceiptsDisabled) {
+		t.Fatalf("Expected receipts to be disabled, got %v", err)
+	}
+
+	svc.SetReceiptSigner(signer)
+	svc.SetCacheTTL(time.Hour)
+	ctx := ContextWithTenant(context.Background(), &Tenant{Id: "retail"})
+	res, err := svc.Verify(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Receipt == "" {
+		t.Fatal("Expected a receipt")
+	}
+	claims, err := svc.VerifyReceipt(res.Receipt)
+	if err != nil {
+		t.Fatalf("Expected a valid receipt, got %v", err)
+	}
+	if claims.CertSha256 != res.Certificate.Sha256 || claims.Policy != "default" || claims.Tenant != "retail" || !claims.Valid {
+		t.Errorf("Expected the claims of the result, got %+v", claims)
+	}
+	if claims.DataVersions.Roots == "" || claims.DataVersions.Roots != res.DataVersions.Roots {
+		t.Errorf("Expected the roots version %s, got %+v", res.DataVersions.Roots, claims.DataVersions)
+	}
+	if claims.Result == nil || claims.Result.Receipt != "" {
+		t.Errorf("Expected the result without its receipt, got %+v", claims.Result)
+	}
+
+	req.Receipt = false
+	cached, err := svc.Verify(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !cached.Cached || cached.Receipt != "" {
+		t.Errorf("Expected a cached result without a receipt, got cached %v receipt %q", cached.Cached, cached.Receipt)
+	}
+}
+
+func TestVerify_Explain(t *testing.T) {
+	httpClient := NewMockHttpClient()
+	httpClient.SetChainPath(getTestDataPath("chains/production"))
+	svc := newProductionSvc(t, NewMockDb(), httpClient)
+	svc.SetCacheTTL(time.Hour)
+	req := VerifyRequest{Cert: readTestFile(t, "chains/production/leaf.pem"), Explain: true}
+	res, err := svc.Verify(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	trace := res.Trace
+	if trace == nil {
+		t.Fatal("Expected a trace")
+	}
+	if trace.Registry == nil || trace.Registry.OrganizationId != "PSDFIN-FINFSA-1234567-8" || trace.Registry.LookupKey != "PSDFIN-FINFSA-12345678" || !trace.Registry.Found {
+		t.Errorf("Expected the normalized registry lookup, got %+v", trace.Registry)
+	}
+	// The intermediate is not known yet, so the chain is only built after following the AIA links up to the root
+	if len(trace.AiaFetches) != 2 || trace.AiaFetches[0].StatusCode != http.StatusOK || trace.AiaFetches[1].StatusCode != http.StatusOK {
+		t.Errorf("Expected AIA fetches of the intermediate and the root, got %+v", trace.AiaFetches)
+	}
+	if len(trace.Certificates) != 3 || trace.Certificates[0].Source != "request" || trace.Certificates[1].Source != trace.AiaFetches[0].URL {
+		t.Errorf("Expected the leaf and the downloaded certificates, got %+v", trace.Certificates)
+	}
+	if len(trace.Chains) != 2 || trace.Chains[0].Chosen != -1 || trace.Chains[0].Error == "" {
+		t.Fatalf("Expected a failed and a successful chain attempt, got %+v", trace.Chains)
+	}
+	if aia := trace.Chains[1]; aia.Intermediates != "aia" || aia.Chosen != 0 || len(aia.Candidates) == 0 || len(aia.Candidates[0].Certificates) != 3 {
+		t.Errorf("Expected a chain of 3 certificates built with the AIA intermediates, got %+v", aia)
+	}
+	if trace.Ocsp == nil || trace.Ocsp.Status != "good" || trace.Ocsp.StatusCode != http.StatusOK || trace.Ocsp.NextUpdate == nil {
+		t.Errorf("Expected a good OCSP response, got %+v", trace.Ocsp)
+	}
+
+	// Explained results are neither cached nor served from the cache
+	req.Explain = false
+	res, err = svc.Verify(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Cached || res.Trace != nil {
+		t.Errorf("Expected a fresh result without a trace, got cached %v trace %+v", res.Cached, res.Trace)
+	}
+}
@@ -0,0 +1,29 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetTppChanges reads the tpp_changes collection, which tools/tppdb writes.
+func (r *TppMongoRepository) GetTppChanges(ctx context.Context, filter db.TppChangeFilter) ([]models.TppChange, error) {
+	query := bson.M{"seq": bson.M{"$gt": filter.After}}
+	if !filter.Since.IsZero() {
+		query["recorded_at"] = bson.M{"$gte": filter.Since}
+	}
+	opts := options.Find().SetSort(bson.M{"seq": 1}).SetLimit(int64(filter.Limit))
+	cursor, err := r.db.Collection("tpp_changes").Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	changes := []models.TppChange{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
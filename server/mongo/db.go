@@ -3,17 +3,20 @@ package mongo
 import (
 	"context"
 	"errors"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/botsman/tppVerifier/app/cert"
 	"github.com/botsman/tppVerifier/app/db"
 	"github.com/botsman/tppVerifier/app/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func NewMongoRepo(ctx context.Context, connStr string) (db.TppRepository, error) {
+func NewMongoRepo(ctx context.Context, connStr string) (*TppMongoRepository, error) {
 	opts := options.Client().ApplyURI(connStr)
 	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
@@ -53,12 +56,115 @@ type TppMongoRepository struct {
 func (r *TppMongoRepository) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
 	tpp := &models.TPP{}
 	err := r.db.Collection("tpps").FindOne(ctx, bson.M{"ob_id": id}).Decode(&tpp)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, db.ErrTppNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tpp, nil
+}
+
+func (r *TppMongoRepository) GetTppByEntityCode(ctx context.Context, code string) (*models.TPP, error) {
+	tpp := &models.TPP{}
+	err := r.db.Collection("tpps").FindOne(ctx, bson.M{"id": code}).Decode(tpp)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, db.ErrTppNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 	return tpp, nil
 }
 
+// GetTppsByNationalReference relies on the national_reference field and its index
+// that tools/tppdb adds to the tpps collection.
+func (r *TppMongoRepository) GetTppsByNationalReference(ctx context.Context, code string) ([]*models.TPP, error) {
+	cursor, err := r.db.Collection("tpps").Find(ctx, bson.M{"national_reference": code})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	tpps := []*models.TPP{}
+	if err := cursor.All(ctx, &tpps); err != nil {
+		return nil, err
+	}
+	return tpps, nil
+}
+
+// SearchTpps relies on the passported_countries and service_types fields and the indexes
+// that tools/tppdb adds to the tpps collection.
+func (r *TppMongoRepository) SearchTpps(ctx context.Context, filter db.TppFilter) ([]*models.TPP, error) {
+	opts := options.Find().SetSort(bson.M{"ob_id": 1}).SetLimit(int64(filter.Limit))
+	cursor, err := r.db.Collection("tpps").Find(ctx, tppFilter(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	tpps := []*models.TPP{}
+	if err := cursor.All(ctx, &tpps); err != nil {
+		return nil, err
+	}
+	return tpps, nil
+}
+
+func tppFilter(filter db.TppFilter) bson.M {
+	and := bson.A{}
+	if filter.After != "" {
+		and = append(and, bson.M{"ob_id": bson.M{"$gt": filter.After}})
+	}
+	if filter.Name != "" {
+		name := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Name), Options: "i"}
+		and = append(and, bson.M{"$or": bson.A{bson.M{"name_latin": name}, bson.M{"name_native": name}}})
+	}
+	if filter.Country != "" {
+		and = append(and, bson.M{"country": filter.Country})
+	}
+	if filter.Authority != "" {
+		and = append(and, bson.M{"authority": filter.Authority})
+	}
+	if filter.Type != "" {
+		and = append(and, bson.M{"type": filter.Type})
+	}
+	switch {
+	case filter.PassportedCountry != "" && filter.Service != "":
+		and = append(and, bson.M{"passported_countries": filter.PassportedCountry, "services." + filter.PassportedCountry: filter.Service})
+	case filter.PassportedCountry != "":
+		and = append(and, bson.M{"passported_countries": filter.PassportedCountry})
+	case filter.Service != "":
+		and = append(and, bson.M{"service_types": filter.Service})
+	}
+	// Mirrors models.TPP.AuthorizationStatus, which treats zero times as missing
+	zero := time.Time{}
+	switch filter.Status {
+	case models.AuthorizationStatusAuthorized:
+		and = append(and,
+			bson.M{"authorized_at": bson.M{"$gt": zero, "$lte": filter.At}},
+			bson.M{"$or": bson.A{
+				bson.M{"withdrawn_at": nil},
+				bson.M{"withdrawn_at": bson.M{"$gt": filter.At}},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$withdrawn_at", "$authorized_at"}}},
+			}},
+		)
+	case models.AuthorizationStatusWithdrawn:
+		and = append(and,
+			bson.M{"authorized_at": bson.M{"$gt": zero, "$lte": filter.At}},
+			bson.M{"withdrawn_at": bson.M{"$lte": filter.At}},
+			bson.M{"$expr": bson.M{"$gte": bson.A{"$withdrawn_at", "$authorized_at"}}},
+		)
+	case models.AuthorizationStatusNotAuthorized:
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"authorized_at": nil},
+			bson.M{"authorized_at": bson.M{"$lte": zero}},
+			bson.M{"authorized_at": bson.M{"$gt": filter.At}},
+		}})
+	}
+	if len(and) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": and}
+}
+
 func (r *TppMongoRepository) GetRootCertificates(ctx context.Context) ([]string, error) {
 	filter := bson.M{
 		"is_active": true,
@@ -102,3 +208,10 @@ func (r *TppMongoRepository) AddCertificate(ctx context.Context, cert *cert.Pars
 func NewTppMongoRepository(db *mongo.Database) *TppMongoRepository {
 	return &TppMongoRepository{db: db}
 }
+
+var (
+	_ db.TppRepository      = (*TppMongoRepository)(nil)
+	_ db.TenantRepository   = (*TppMongoRepository)(nil)
+	_ db.EvidenceRepository = (*TppMongoRepository)(nil)
+	_ db.JobRepository      = (*TppMongoRepository)(nil)
+)
@@ -0,0 +1,37 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (r *TppMongoRepository) AddEvidence(ctx context.Context, evidence *models.Evidence) error {
+	_, err := r.db.Collection("evidence").InsertOne(ctx, evidence)
+	return err
+}
+
+func (r *TppMongoRepository) GetEvidence(ctx context.Context, id string, now time.Time) (*models.Evidence, error) {
+	evidence := &models.Evidence{}
+	err := r.db.Collection("evidence").FindOne(ctx, bson.M{"id": id, "expires_at": bson.M{"$gt": now}}).Decode(evidence)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, db.ErrEvidenceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+func (r *TppMongoRepository) DeleteExpiredEvidence(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.Collection("evidence").DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": before}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *TppMongoRepository) AddJob(ctx context.Context, job *models.Job) error {
+	_, err := r.db.Collection("jobs").InsertOne(ctx, job)
+	return err
+}
+
+func (r *TppMongoRepository) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	job := &models.Job{}
+	err := r.db.Collection("jobs").FindOne(ctx, bson.M{"id": id}).Decode(job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, db.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *TppMongoRepository) ClaimJob(ctx context.Context, now time.Time, until time.Time) (*models.Job, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": bson.M{"$in": bson.A{models.JobQueued, models.JobRunning}}},
+			bson.M{"callback_status": models.CallbackPending},
+		},
+		"leased_until": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"leased_until": until}}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"created_at": 1}).SetReturnDocument(options.After)
+	job := &models.Job{}
+	err := r.db.Collection("jobs").FindOneAndUpdate(ctx, filter, update, opts).Decode(job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, db.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *TppMongoRepository) UpdateJob(ctx context.Context, job *models.Job) error {
+	res, err := r.db.Collection("jobs").ReplaceOne(ctx, bson.M{"id": job.Id}, job)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return db.ErrJobNotFound
+	}
+	return nil
+}
+
+func (r *TppMongoRepository) DeleteExpiredJobs(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.Collection("jobs").DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": before}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *TppMongoRepository) GetTenantByApiKey(ctx context.Context, keyHash string) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	err := r.db.Collection("tenants").FindOne(ctx, bson.M{"api_key_hashes": keyHash, "is_active": true}).Decode(tenant)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, db.ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (r *TppMongoRepository) GetTenant(ctx context.Context, id string) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	err := r.db.Collection("tenants").FindOne(ctx, bson.M{"id": id, "is_active": true}).Decode(tenant)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, db.ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (r *TppMongoRepository) GetPolicy(ctx context.Context, name string) (*policy.Policy, error) {
+	p := &policy.Policy{}
+	err := r.db.Collection("policies").FindOne(ctx, bson.M{"name": name}).Decode(p)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, db.ErrPolicyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *TppMongoRepository) AddUsage(ctx context.Context, tenantId string, day string, requests int64) error {
+	filter := bson.M{"tenant_id": tenantId, "day": day}
+	update := bson.M{"$inc": bson.M{"requests": requests}}
+	_, err := r.db.Collection("tenant_usage").UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *TppMongoRepository) GetUsage(ctx context.Context, tenantId string, since string) ([]models.TenantUsage, error) {
+	filter := bson.M{"tenant_id": tenantId, "day": bson.M{"$gte": since}}
+	cursor, err := r.db.Collection("tenant_usage").Find(ctx, filter, options.Find().SetSort(bson.M{"day": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	usage := []models.TenantUsage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
@@ -3,11 +3,19 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/botsman/tppVerifier/app"
-	"github.com/botsman/tppVerifier/app/cert"
+	"github.com/botsman/tppVerifier/app/jobs"
+	"github.com/botsman/tppVerifier/app/policy"
+	"github.com/botsman/tppVerifier/app/receipt"
+	"github.com/botsman/tppVerifier/app/rpc"
+	"github.com/botsman/tppVerifier/app/tenant"
 	"github.com/botsman/tppVerifier/app/verify"
 
 	"github.com/botsman/tppVerifier/server/mongo"
@@ -27,28 +35,155 @@ func main() {
 
 	httpClient := &http.Client{}
 	vs := verify.NewVerifySvc(repo, httpClient)
-	roots, err := repo.GetRootCertificates(ctx)
-	if err != nil {
-		log.Fatalf("Failed to get root certificates: %v", err)
+	if workers := os.Getenv("BATCH_WORKERS"); workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil {
+			log.Fatalf("Invalid BATCH_WORKERS value: %v", err)
+		}
+		vs.SetBatchWorkers(n)
+	}
+	if countries := os.Getenv("ASPSP_COUNTRIES"); countries != "" {
+		vs.SetAspspCountries(strings.Split(countries, ","))
 	}
-	for _, root := range roots {
-		if root == "" {
-			log.Println("Skipping empty root certificate")
-			continue
+	if path := os.Getenv("POLICY_FILE"); path != "" {
+		p, err := policy.Load(path)
+		if err != nil {
+			log.Fatalf("Failed to load policy: %v", err)
 		}
-		rootCerts, err := cert.ParseCerts([]byte(root))
+		vs.SetPolicy(p)
+		log.Printf("Using policy %s", p.Name)
+	}
+	if ttl := os.Getenv("CACHE_MAX_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("Invalid CACHE_MAX_TTL value: %v", err)
+		}
+		vs.SetCacheTTL(d)
+	}
+	var signer *receipt.Signer
+	if path := os.Getenv("RECEIPT_KEY_FILE"); path != "" {
+		signer, err = receipt.LoadSigner(path)
 		if err != nil {
-			log.Printf("Error parsing root certificate: %s", err)
-			continue
+			log.Fatalf("Failed to load receipt key: %v", err)
 		}
-		for _, rootCert := range rootCerts {
-			if rootCert.Cert == nil {
-				log.Println("Skipping nil root certificate")
-				continue
+		if path := os.Getenv("RECEIPT_PREVIOUS_KEYS_FILE"); path != "" {
+			if err := signer.LoadVerificationKeys(path); err != nil {
+				log.Fatalf("Failed to load previous receipt keys: %v", err)
 			}
-			vs.AddRoot(rootCert)
 		}
+		vs.SetReceiptSigner(signer)
+		log.Printf("Signing receipts with key %s", signer.KeyId())
+	}
+	if retention := os.Getenv("EVIDENCE_RETENTION"); retention != "" {
+		d, err := time.ParseDuration(retention)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid EVIDENCE_RETENTION value: %q", retention)
+		}
+		vs.SetEvidenceRepository(repo, d)
+		go func() {
+			for range time.Tick(time.Hour) {
+				n, err := vs.PurgeEvidence(ctx)
+				if err != nil {
+					log.Printf("Failed to purge expired evidence: %v", err)
+					continue
+				}
+				log.Printf("Purged %d expired evidence bundles", n)
+			}
+		}()
+	}
+	if err := vs.LoadRoots(ctx); err != nil {
+		log.Fatalf("Failed to get root certificates: %v", err)
+	}
+	if interval := os.Getenv("ROOTS_RELOAD_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid ROOTS_RELOAD_INTERVAL value: %q", interval)
+		}
+		go func() {
+			for range time.Tick(d) {
+				if err := vs.LoadRoots(ctx); err != nil {
+					log.Printf("Failed to reload root certificates: %v", err)
+				}
+			}
+		}()
+	}
+	var auth *tenant.Authenticator
+	if os.Getenv("TENANTS_ENABLED") == "true" {
+		auth = tenant.NewAuthenticator(repo)
+		if ttl := os.Getenv("TENANT_CACHE_TTL"); ttl != "" {
+			d, err := time.ParseDuration(ttl)
+			if err != nil {
+				log.Fatalf("Invalid TENANT_CACHE_TTL value: %v", err)
+			}
+			auth.SetCacheTTL(d)
+		}
+		interval := time.Minute
+		if v := os.Getenv("USAGE_FLUSH_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				log.Fatalf("Invalid USAGE_FLUSH_INTERVAL value: %q", v)
+			}
+			interval = d
+		}
+		go func() {
+			for range time.Tick(interval) {
+				// Failed counters are kept for the next flush and logged by the authenticator
+				_ = auth.Flush(ctx)
+			}
+		}()
+	}
+	var q *jobs.Queue
+	if os.Getenv("JOBS_ENABLED") == "true" {
+		q = jobs.NewQueue(repo, vs, jobs.NewCallbackClient())
+		if hosts := os.Getenv("CALLBACK_HOSTS"); hosts != "" {
+			q.SetCallbackHosts(strings.Split(hosts, ","))
+		}
+		if auth != nil {
+			q.SetTenants(auth)
+		}
+		if signer != nil {
+			q.SetCallbackSigner(signer)
+		}
+		if retention := os.Getenv("JOB_RETENTION"); retention != "" {
+			d, err := time.ParseDuration(retention)
+			if err != nil || d <= 0 {
+				log.Fatalf("Invalid JOB_RETENTION value: %q", retention)
+			}
+			q.SetRetention(d)
+		}
+		workers := 4
+		if v := os.Getenv("JOB_WORKERS"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				log.Fatalf("Invalid JOB_WORKERS value: %q", v)
+			}
+			workers = n
+		}
+		go q.Run(ctx, workers)
+		go func() {
+			for range time.Tick(time.Hour) {
+				n, err := q.Purge(ctx)
+				if err != nil {
+					log.Printf("Failed to purge expired jobs: %v", err)
+					continue
+				}
+				log.Printf("Purged %d expired jobs", n)
+			}
+		}()
+	}
+	r := app.SetupRouter(vs, auth, q)
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		lis, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			log.Fatalf("Failed to listen on GRPC_PORT: %v", err)
+		}
+		s := rpc.NewServer(vs, auth)
+		go func() {
+			if err := s.Serve(lis); err != nil {
+				log.Fatalf("Failed to serve gRPC: %v", err)
+			}
+		}()
+		log.Printf("Serving gRPC on port %s", port)
 	}
-	r := app.SetupRouter(vs)
 	r.Run()
 }
@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// GetTppChanges reads the tpp_changes table, which tools/tppdb writes.
+func (r *TppSqliteRepository) GetTppChanges(ctx context.Context, filter db.TppChangeFilter) ([]models.TppChange, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT seq, type, id, ob_id, recorded_at, document FROM tpp_changes WHERE seq > ? AND recorded_at >= ? ORDER BY seq LIMIT ?`,
+		filter.After, filter.Since.UTC(), filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	changes := []models.TppChange{}
+	for rows.Next() {
+		var change models.TppChange
+		var document []byte
+		if err := rows.Scan(&change.Seq, &change.Type, &change.Id, &change.OBID, &change.RecordedAt, &document); err != nil {
+			return nil, err
+		}
+		change.TPP = &models.TPP{}
+		if err := json.Unmarshal(document, change.TPP); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/botsman/tppVerifier/app/cert"
 	"github.com/botsman/tppVerifier/app/db"
@@ -11,8 +13,8 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// NewSQLiteRepo creates a TppRepository backed by SQLite, using the given database file path.
-func NewSQLiteRepo(_ context.Context, path string) (db.TppRepository, error) {
+// NewSQLiteRepo creates a repository backed by SQLite, using the given database file path.
+func NewSQLiteRepo(_ context.Context, path string) (*TppSqliteRepository, error) {
 	dbConn, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, err
@@ -31,11 +33,19 @@ func NewTppSqliteRepository(db *sql.DB) *TppSqliteRepository {
 	return &TppSqliteRepository{db: db}
 }
 
-func (r *TppSqliteRepository) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT name_latin, name_native, id, ob_id, authority, country, type, registry, authorized_at, withdrawn_at, created_at, updated_at FROM tpps WHERE ob_id = ?`, id)
+const tppColumns = `name_latin, name_native, id, ob_id, authority, country, type, registry, authorized_at, withdrawn_at, created_at, updated_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTpp(row scanner) (*models.TPP, error) {
 	tpp := &models.TPP{}
 	var authorizedAt, withdrawnAt, createdAt, updatedAt sql.NullTime
 	err := row.Scan(&tpp.NameLatin, &tpp.NameNative, &tpp.Id, &tpp.OBID, &tpp.Authority, &tpp.Country, &tpp.Type, &tpp.Registry, &authorizedAt, &withdrawnAt, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrTppNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -47,25 +57,145 @@ func (r *TppSqliteRepository) GetTpp(ctx context.Context, id string) (*models.TP
 	}
 	tpp.CreatedAt = createdAt.Time
 	tpp.UpdatedAt = updatedAt.Time
+	return tpp, nil
+}
 
+// loadServices sets the services of the TPP, by country.
+func (r *TppSqliteRepository) loadServices(ctx context.Context, tpp *models.TPP) error {
 	services := make(map[string][]models.Service)
 	rows, err := r.db.QueryContext(ctx, `SELECT country, service FROM tpp_services WHERE tpp_ob_id = ?`, tpp.OBID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var country, service string
 		if err := rows.Scan(&country, &service); err != nil {
-			return nil, err
+			return err
 		}
 		services[country] = append(services[country], models.Service(service))
 	}
 	tpp.Services = services
+	return rows.Err()
+}
 
+func (r *TppSqliteRepository) getTpp(ctx context.Context, column string, value string) (*models.TPP, error) {
+	tpp, err := scanTpp(r.db.QueryRowContext(ctx, `SELECT `+tppColumns+` FROM tpps WHERE `+column+` = ?`, value))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadServices(ctx, tpp); err != nil {
+		return nil, err
+	}
 	return tpp, nil
 }
 
+func (r *TppSqliteRepository) GetTpp(ctx context.Context, id string) (*models.TPP, error) {
+	return r.getTpp(ctx, "ob_id", id)
+}
+
+func (r *TppSqliteRepository) GetTppByEntityCode(ctx context.Context, code string) (*models.TPP, error) {
+	return r.getTpp(ctx, "id", code)
+}
+
+func (r *TppSqliteRepository) GetTppsByNationalReference(ctx context.Context, code string) ([]*models.TPP, error) {
+	return r.queryTpps(ctx, `SELECT `+tppColumns+` FROM tpps WHERE ob_id LIKE ? ESCAPE '\'`, "%-"+escapeLike(code))
+}
+
+// queryTpps returns the TPPs selected by the query, which selects tppColumns.
+func (r *TppSqliteRepository) queryTpps(ctx context.Context, query string, args ...any) ([]*models.TPP, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	tpps := []*models.TPP{}
+	for rows.Next() {
+		tpp, err := scanTpp(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tpps = append(tpps, tpp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Services are loaded once the rows are closed, as the database may allow a single connection
+	for _, tpp := range tpps {
+		if err := r.loadServices(ctx, tpp); err != nil {
+			return nil, err
+		}
+	}
+	return tpps, nil
+}
+
+func (r *TppSqliteRepository) SearchTpps(ctx context.Context, filter db.TppFilter) ([]*models.TPP, error) {
+	where, args := tppWhere(filter)
+	query := `SELECT ` + tppColumns + ` FROM tpps`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY ob_id LIMIT ?`
+	return r.queryTpps(ctx, query, append(args, filter.Limit)...)
+}
+
+func tppWhere(filter db.TppFilter) ([]string, []any) {
+	var where []string
+	var args []any
+	if filter.After != "" {
+		where = append(where, `ob_id > ?`)
+		args = append(args, filter.After)
+	}
+	if filter.Name != "" {
+		// LIKE only ignores the case of ASCII letters
+		where = append(where, `(name_latin LIKE ? ESCAPE '\' OR name_native LIKE ? ESCAPE '\')`)
+		name := "%" + escapeLike(filter.Name) + "%"
+		args = append(args, name, name)
+	}
+	if filter.Country != "" {
+		where = append(where, `country = ?`)
+		args = append(args, filter.Country)
+	}
+	if filter.Authority != "" {
+		where = append(where, `authority = ?`)
+		args = append(args, filter.Authority)
+	}
+	if filter.Type != "" {
+		where = append(where, `type = ?`)
+		args = append(args, filter.Type)
+	}
+	switch {
+	case filter.PassportedCountry != "" && filter.Service != "":
+		where = append(where, `EXISTS (SELECT 1 FROM tpp_services s WHERE s.tpp_ob_id = tpps.ob_id AND s.country = ? AND s.service = ? AND s.country != tpps.country)`)
+		args = append(args, filter.PassportedCountry, string(filter.Service))
+	case filter.PassportedCountry != "":
+		where = append(where, `EXISTS (SELECT 1 FROM tpp_services s WHERE s.tpp_ob_id = tpps.ob_id AND s.country = ? AND s.country != tpps.country)`)
+		args = append(args, filter.PassportedCountry)
+	case filter.Service != "":
+		where = append(where, `EXISTS (SELECT 1 FROM tpp_services s WHERE s.tpp_ob_id = tpps.ob_id AND s.service = ?)`)
+		args = append(args, string(filter.Service))
+	}
+	// Mirrors models.TPP.AuthorizationStatus, which treats zero times as missing
+	at, zero := filter.At.UTC(), time.Time{}
+	switch filter.Status {
+	case models.AuthorizationStatusAuthorized:
+		where = append(where, `authorized_at > ? AND authorized_at <= ? AND (withdrawn_at IS NULL OR withdrawn_at > ? OR withdrawn_at < authorized_at)`)
+		args = append(args, zero, at, at)
+	case models.AuthorizationStatusWithdrawn:
+		where = append(where, `authorized_at > ? AND authorized_at <= ? AND withdrawn_at <= ? AND withdrawn_at >= authorized_at`)
+		args = append(args, zero, at, at)
+	case models.AuthorizationStatusNotAuthorized:
+		where = append(where, `(authorized_at IS NULL OR authorized_at <= ? OR authorized_at > ?)`)
+		args = append(args, zero, at)
+	}
+	return where, args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
 func (r *TppSqliteRepository) GetRootCertificates(ctx context.Context) ([]string, error) {
 	rows, err := r.db.QueryContext(ctx, `SELECT pem FROM certs WHERE is_active = 1 AND position = ?`, models.PositionRoot)
 	if err != nil {
@@ -95,3 +225,10 @@ func (r *TppSqliteRepository) AddCertificate(ctx context.Context, c *cert.Parsed
 	_, err = r.db.ExecContext(ctx, `INSERT INTO certs (cert_raw, is_active, position) VALUES (?, ?, ?)`, c.Cert.Raw, certBson["is_active"], certBson["position"])
 	return err
 }
+
+var (
+	_ db.TppRepository      = (*TppSqliteRepository)(nil)
+	_ db.TenantRepository   = (*TppSqliteRepository)(nil)
+	_ db.EvidenceRepository = (*TppSqliteRepository)(nil)
+	_ db.JobRepository      = (*TppSqliteRepository)(nil)
+)
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+func (r *TppSqliteRepository) AddEvidence(ctx context.Context, evidence *models.Evidence) error {
+	document, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO evidence (id, tenant_id, created_at, expires_at, document) VALUES (?, ?, ?, ?, ?)`,
+		evidence.Id, evidence.TenantId, evidence.CreatedAt.UTC(), evidence.ExpiresAt.UTC(), document)
+	return err
+}
+
+func (r *TppSqliteRepository) GetEvidence(ctx context.Context, id string, now time.Time) (*models.Evidence, error) {
+	var document []byte
+	err := r.db.QueryRowContext(ctx, `SELECT document FROM evidence WHERE id = ? AND expires_at > ?`, id, now.UTC()).Scan(&document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrEvidenceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	evidence := &models.Evidence{}
+	if err := json.Unmarshal(document, evidence); err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+func (r *TppSqliteRepository) DeleteExpiredEvidence(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM evidence WHERE expires_at <= ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+func (r *TppSqliteRepository) AddJob(ctx context.Context, job *models.Job) error {
+	document, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO jobs (id, tenant_id, status, callback_status, leased_until, created_at, expires_at, request, document)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.Id, job.TenantId, job.Status, job.CallbackStatus, job.LeasedUntil.UTC(), job.CreatedAt.UTC(), utcOrNil(job.ExpiresAt), string(job.Request), document)
+	return err
+}
+
+func (r *TppSqliteRepository) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT leased_until, request, document FROM jobs WHERE id = ?`, id)
+	return scanJob(row)
+}
+
+func (r *TppSqliteRepository) ClaimJob(ctx context.Context, now time.Time, until time.Time) (*models.Job, error) {
+	row := r.db.QueryRowContext(ctx, `UPDATE jobs SET leased_until = ?
+		WHERE id = (SELECT id FROM jobs
+			WHERE (status IN (?, ?) OR callback_status = ?) AND leased_until <= ?
+			ORDER BY created_at LIMIT 1)
+		RETURNING leased_until, request, document`,
+		until.UTC(), models.JobQueued, models.JobRunning, models.CallbackPending, now.UTC())
+	return scanJob(row)
+}
+
+func (r *TppSqliteRepository) UpdateJob(ctx context.Context, job *models.Job) error {
+	document, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE jobs SET status = ?, callback_status = ?, leased_until = ?, expires_at = ?, document = ? WHERE id = ?`,
+		job.Status, job.CallbackStatus, job.LeasedUntil.UTC(), utcOrNil(job.ExpiresAt), document, job.Id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return db.ErrJobNotFound
+	}
+	return nil
+}
+
+func (r *TppSqliteRepository) DeleteExpiredJobs(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE expires_at <= ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// scanJob reads a job stored as a JSON document, with the fields
+// that are not part of its JSON form kept in their own columns.
+func scanJob(row *sql.Row) (*models.Job, error) {
+	var leasedUntil time.Time
+	var request string
+	var document []byte
+	err := row.Scan(&leasedUntil, &request, &document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	job := &models.Job{}
+	if err := json.Unmarshal(document, job); err != nil {
+		return nil, err
+	}
+	job.LeasedUntil = leasedUntil
+	job.Request = json.RawMessage(request)
+	return job, nil
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
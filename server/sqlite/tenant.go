@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/models"
+	"github.com/botsman/tppVerifier/app/policy"
+)
+
+func (r *TppSqliteRepository) GetTenantByApiKey(ctx context.Context, keyHash string) (*models.Tenant, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT t.id, t.name, t.aspsp_countries, t.policy, t.is_active, t.created_at, t.updated_at
+		FROM tenants t JOIN tenant_api_keys k ON k.tenant_id = t.id
+		WHERE k.key_hash = ? AND t.is_active = 1`, keyHash)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, err
+	}
+	tenant.ApiKeyHashes = []string{keyHash}
+	return tenant, nil
+}
+
+func (r *TppSqliteRepository) GetTenant(ctx context.Context, id string) (*models.Tenant, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, name, aspsp_countries, policy, is_active, created_at, updated_at
+		FROM tenants WHERE id = ? AND is_active = 1`, id)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT key_hash FROM tenant_api_keys WHERE tenant_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		tenant.ApiKeyHashes = append(tenant.ApiKeyHashes, hash)
+	}
+	return tenant, rows.Err()
+}
+
+func scanTenant(row *sql.Row) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	var countries, policyName sql.NullString
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(&tenant.Id, &tenant.Name, &countries, &policyName, &tenant.IsActive, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if countries.String != "" {
+		tenant.AspspCountries = strings.Split(countries.String, ",")
+	}
+	tenant.Policy = policyName.String
+	tenant.CreatedAt = createdAt.Time
+	tenant.UpdatedAt = updatedAt.Time
+	return tenant, nil
+}
+
+func (r *TppSqliteRepository) GetPolicy(ctx context.Context, name string) (*policy.Policy, error) {
+	var document string
+	err := r.db.QueryRowContext(ctx, `SELECT document FROM policies WHERE name = ?`, name).Scan(&document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrPolicyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Policies are stored as JSON documents, as in policy files
+	p := &policy.Policy{}
+	if err := json.Unmarshal([]byte(document), p); err != nil {
+		return nil, err
+	}
+	p.Name = name
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *TppSqliteRepository) AddUsage(ctx context.Context, tenantId string, day string, requests int64) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO tenant_usage (tenant_id, day, requests) VALUES (?, ?, ?)
+		ON CONFLICT (tenant_id, day) DO UPDATE SET requests = requests + excluded.requests`, tenantId, day, requests)
+	return err
+}
+
+func (r *TppSqliteRepository) GetUsage(ctx context.Context, tenantId string, since string) ([]models.TenantUsage, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT tenant_id, day, requests FROM tenant_usage WHERE tenant_id = ? AND day >= ? ORDER BY day`, tenantId, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	usage := []models.TenantUsage{}
+	for rows.Next() {
+		var u models.TenantUsage
+		if err := rows.Scan(&u.TenantId, &u.Day, &u.Requests); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
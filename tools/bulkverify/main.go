@@ -0,0 +1,109 @@
+// Command bulkverify verifies a directory of certificates against the configured
+// repository without the HTTP server, and writes a CSV or NDJSON report.
+//
+//	DATABASE_URL=mongodb://localhost:27017/tpp go run ./tools/bulkverify -dir ./partners -format csv -out report.csv
+//
+// The exit code is 0 if every certificate is valid, 1 if any is invalid or could not be
+// verified, and 2 if the verification could not be run.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/botsman/tppVerifier/app/bulk"
+	"github.com/botsman/tppVerifier/app/db"
+	"github.com/botsman/tppVerifier/app/policy"
+	"github.com/botsman/tppVerifier/app/verify"
+	"github.com/botsman/tppVerifier/server/mongo"
+	"github.com/botsman/tppVerifier/server/sqlite"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	dir := flag.String("dir", "", "directory of PEM, DER and PKCS7 certificates")
+	format := flag.String("format", "csv", "report format, csv or ndjson")
+	out := flag.String("out", "", "report file (default standard output)")
+	backend := flag.String("backend", "mongo", "repository backend, mongo or sqlite")
+	policyFile := flag.String("policy", "", "verification policy file (default built-in policy)")
+	countries := flag.String("aspsp-countries", "", "comma-separated countries the scopes are restricted to (default all)")
+	workers := flag.Int("workers", 8, "number of concurrently verified certificates")
+	flag.Parse()
+	if *dir == "" {
+		flag.Usage()
+		return 2
+	}
+	if *format != "csv" && *format != "ndjson" {
+		log.Printf("Unknown format %q", *format)
+		return 2
+	}
+
+	ctx := context.Background()
+	connStr := os.Getenv("DATABASE_URL")
+	var repo db.TppRepository
+	var err error
+	switch *backend {
+	case "mongo":
+		repo, err = mongo.NewMongoRepo(ctx, connStr)
+	case "sqlite":
+		repo, err = sqlite.NewSQLiteRepo(ctx, connStr)
+	default:
+		err = fmt.Errorf("unknown backend %q", *backend)
+	}
+	if err != nil {
+		log.Printf("Failed to initialize repository: %v", err)
+		return 2
+	}
+
+	vs := verify.NewVerifySvc(repo, &http.Client{})
+	vs.SetBatchWorkers(*workers)
+	if *countries != "" {
+		vs.SetAspspCountries(strings.Split(*countries, ","))
+	}
+	if *policyFile != "" {
+		p, err := policy.Load(*policyFile)
+		if err != nil {
+			log.Printf("Failed to load policy: %v", err)
+			return 2
+		}
+		vs.SetPolicy(p)
+	}
+	if err := vs.LoadRoots(ctx); err != nil {
+		log.Printf("Failed to get root certificates: %v", err)
+		return 2
+	}
+
+	rows, err := bulk.Verify(ctx, vs, *dir)
+	if err != nil {
+		log.Printf("Failed to verify certificates: %v", err)
+		return 2
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Printf("Failed to create report: %v", err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := bulk.Write(w, *format, rows); err != nil {
+		log.Printf("Failed to write report: %v", err)
+		return 2
+	}
+	log.Print(bulk.Summary(rows))
+	if bulk.Failed(rows) {
+		return 1
+	}
+	return 0
+}
@@ -23,6 +23,23 @@ CREATE TABLE IF NOT EXISTS tpp_services (
     PRIMARY KEY (tpp_ob_id, country, service)
 );
 
+-- TPP search filters, results are ordered by ob_id. Service filters use the primary key of tpp_services.
+CREATE INDEX IF NOT EXISTS tpps_country ON tpps (country, ob_id);
+CREATE INDEX IF NOT EXISTS tpps_authority ON tpps (authority, ob_id);
+CREATE INDEX IF NOT EXISTS tpps_type ON tpps (type, ob_id);
+CREATE INDEX IF NOT EXISTS tpps_authorized_at ON tpps (authorized_at);
+
+CREATE TABLE IF NOT EXISTS tpp_changes (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL,
+    id TEXT NOT NULL,
+    ob_id TEXT NOT NULL,
+    recorded_at DATETIME NOT NULL,
+    document TEXT NOT NULL -- JSON TPP
+);
+
+CREATE INDEX IF NOT EXISTS tpp_changes_recorded_at ON tpp_changes (recorded_at);
+
 CREATE TABLE IF NOT EXISTS certs (
     sha256 TEXT PRIMARY KEY,
     pem BLOB NOT NULL,
@@ -34,3 +51,58 @@ CREATE TABLE IF NOT EXISTS certs (
     is_active BOOLEAN NOT NULL,
     position TEXT NOT NULL
 );
+
+CREATE TABLE IF NOT EXISTS policies (
+    name TEXT PRIMARY KEY,
+    document TEXT NOT NULL -- JSON policy, as in policy files
+);
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    aspsp_countries TEXT, -- comma-separated, empty means the countries of the deployment
+    policy TEXT, -- empty means the policy of the deployment
+    is_active BOOLEAN NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY (policy) REFERENCES policies(name)
+);
+
+CREATE TABLE IF NOT EXISTS tenant_api_keys (
+    key_hash TEXT PRIMARY KEY, -- hex SHA-256 of the API key
+    tenant_id TEXT NOT NULL,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+);
+
+CREATE TABLE IF NOT EXISTS tenant_usage (
+    tenant_id TEXT NOT NULL,
+    day TEXT NOT NULL, -- YYYY-MM-DD (UTC)
+    requests INTEGER NOT NULL,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id),
+    PRIMARY KEY (tenant_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS evidence (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    document TEXT NOT NULL -- JSON evidence bundle
+);
+
+CREATE INDEX IF NOT EXISTS evidence_expires_at ON evidence (expires_at);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT,
+    status TEXT NOT NULL,
+    callback_status TEXT,
+    leased_until DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME,
+    request TEXT NOT NULL, -- JSON verification request
+    document TEXT NOT NULL -- JSON job, without the request
+);
+
+CREATE INDEX IF NOT EXISTS jobs_claim ON jobs (leased_until, created_at) WHERE status IN ('queued', 'running') OR callback_status = 'pending';
+CREATE INDEX IF NOT EXISTS jobs_expires_at ON jobs (expires_at);
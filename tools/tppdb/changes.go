@@ -0,0 +1,174 @@
+package tppdb
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+// errEmptyRegister prevents an empty register from removing every stored TPP.
+var errEmptyRegister = errors.New("register contains no TPPs")
+
+// diffTpp returns the changes from the stored record of a TPP, nil if it is new, to the imported one.
+func diffTpp(stored *models.TPP, tpp models.TPP, at time.Time) []models.TppChange {
+	change := func(t models.TppChangeType) models.TppChange {
+		return models.TppChange{Type: t, Id: tpp.Id, OBID: tpp.OBID, TPP: &tpp, RecordedAt: at}
+	}
+	if stored == nil {
+		return []models.TppChange{change(models.TppAdded)}
+	}
+	var changes []models.TppChange
+	// The stored TPP is compared as of its import, so that a withdrawal dated after it is still reported
+	if tpp.AuthorizationStatus(at) == models.AuthorizationStatusWithdrawn && stored.AuthorizationStatus(stored.UpdatedAt) != models.AuthorizationStatusWithdrawn {
+		changes = append(changes, change(models.TppWithdrawn))
+	}
+	if !equalServices(stored.Services, tpp.Services) {
+		changes = append(changes, change(models.TppServicesChanged))
+	}
+	if stored.NameLatin != tpp.NameLatin || stored.NameNative != tpp.NameNative {
+		changes = append(changes, change(models.TppNameChanged))
+	}
+	return changes
+}
+
+// equalServices compares services regardless of their order in the register.
+func equalServices(a, b map[string][]models.Service) bool {
+	return maps.EqualFunc(a, b, func(x, y []models.Service) bool {
+		x, y = slices.Clone(x), slices.Clone(y)
+		slices.Sort(x)
+		slices.Sort(y)
+		return slices.Equal(slices.Compact(x), slices.Compact(y))
+	})
+}
+
+// recordedChanges are the recorded changes by entity code, see replayed.
+type recordedChanges map[string][]models.TppChange
+
+// replayed reports whether the change was recorded by an import that failed before it saved
+// the TPP: the change was then recorded after the stored version of the TPP, the time it was
+// last saved or removed. An import saves its TPPs as of the time it records their changes.
+func (r recordedChanges) replayed(change models.TppChange, stored *models.TPP) bool {
+	var version time.Time
+	if stored != nil {
+		version = stored.UpdatedAt
+	} else {
+		for _, c := range r[change.Id] {
+			if c.Type == models.TppRemoved && c.RecordedAt.After(version) {
+				version = c.RecordedAt
+			}
+		}
+	}
+	return slices.ContainsFunc(r[change.Id], func(c models.TppChange) bool {
+		return c.Type == change.Type && c.RecordedAt.After(version)
+	})
+}
+
+// importTPPs saves the parsed TPPs in batches, with the changes from the stored TPPs. The TPPs
+// that are stored but were not parsed are removed once the whole register has been parsed.
+// Changes are recorded before the TPPs are saved, and skipped if a failed import recorded them,
+// so that a failure in between neither loses nor repeats them.
+func importTPPs(ctx context.Context, dbImpl db, out <-chan models.TPP, errc <-chan error, at time.Time) error {
+	stored, err := dbImpl.GetTPPs(ctx)
+	if err != nil {
+		return err
+	}
+	storedById := make(map[string]*models.TPP, len(stored))
+	var since time.Time
+	for i := range stored {
+		storedById[stored[i].Id] = &stored[i]
+		if i == 0 || stored[i].UpdatedAt.Before(since) {
+			since = stored[i].UpdatedAt
+		}
+	}
+	recordedSince, err := dbImpl.GetTppChanges(ctx, since)
+	if err != nil {
+		return err
+	}
+	recorded := make(recordedChanges)
+	for _, change := range recordedSince {
+		recorded[change.Id] = append(recorded[change.Id], change)
+	}
+	newChanges := func(changes []models.TppChange) []models.TppChange {
+		return slices.DeleteFunc(changes, func(c models.TppChange) bool {
+			return recorded.replayed(c, storedById[c.Id])
+		})
+	}
+
+	batchSize := 1000
+	batch := make([]models.TPP, 0, batchSize)
+	var changes []models.TppChange
+	seen := make(map[string]bool)
+	seenOBIDs := make(map[string]string)
+	save := func() error {
+		if err := dbImpl.AddTppChanges(ctx, changes); err != nil {
+			return err
+		}
+		if err := dbImpl.SaveTPPs(ctx, "tpps", batch); err != nil {
+			return err
+		}
+		batch, changes = batch[:0], nil
+		return nil
+	}
+	for tpp := range out {
+		// TPPs with an invalid authority or national reference code are returned empty
+		if tpp.Id == "" || seen[tpp.Id] {
+			continue
+		}
+		seen[tpp.Id] = true
+		// The parser passes TPPs without an authorization date only if their dates could not be read
+		if tpp.AuthorizedAt == nil {
+			stored := storedById[tpp.Id]
+			if stored == nil {
+				log.Printf("Skipping TPP %s, its authorization dates could not be read", tpp.Id)
+				continue
+			}
+			tpp.AuthorizedAt, tpp.WithdrawnAt = stored.AuthorizedAt, stored.WithdrawnAt
+		}
+		// The OB ID is unique as well, a TPP whose national reference code derives the OB ID of
+		// another one is skipped as before. It is kept if stored, so that it is not reported removed.
+		if id, ok := seenOBIDs[tpp.OBID]; ok {
+			log.Printf("Skipping TPP %s, OB ID %s is already used by TPP %s", tpp.Id, tpp.OBID, id)
+			continue
+		}
+		seenOBIDs[tpp.OBID] = tpp.Id
+		tpp.UpdatedAt = at
+		changes = append(changes, newChanges(diffTpp(storedById[tpp.Id], tpp, at))...)
+		batch = append(batch, tpp)
+		if len(batch) == batchSize {
+			if err := save(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	if len(seen) == 0 {
+		return errEmptyRegister
+	}
+
+	var removed []string
+	for _, tpp := range stored {
+		if !seen[tpp.Id] {
+			removed = append(removed, tpp.Id)
+			changes = append(changes, models.TppChange{Type: models.TppRemoved, Id: tpp.Id, OBID: tpp.OBID, TPP: storedById[tpp.Id], RecordedAt: at})
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	if err := dbImpl.AddTppChanges(ctx, newChanges(changes)); err != nil {
+		return err
+	}
+	return dbImpl.DeleteTPPs(ctx, removed)
+}
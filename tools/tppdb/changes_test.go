@@ -0,0 +1,254 @@
+package tppdb
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+type memoryDb struct {
+	tpps    map[string]models.TPP
+	changes []models.TppChange
+	// saveErr fails the next save of TPPs
+	saveErr error
+}
+
+func (m *memoryDb) GetTPPs(ctx context.Context) ([]models.TPP, error) {
+	var tpps []models.TPP
+	for _, tpp := range m.tpps {
+		tpps = append(tpps, tpp)
+	}
+	slices.SortFunc(tpps, func(a, b models.TPP) int { return strings.Compare(a.Id, b.Id) })
+	return tpps, nil
+}
+
+func (m *memoryDb) SaveTPPs(ctx context.Context, _ string, tpps []models.TPP) error {
+	if err := m.saveErr; err != nil {
+		m.saveErr = nil
+		return err
+	}
+	for _, tpp := range tpps {
+		m.tpps[tpp.Id] = tpp
+	}
+	return nil
+}
+
+func (m *memoryDb) DeleteTPPs(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		delete(m.tpps, id)
+	}
+	return nil
+}
+
+func (m *memoryDb) GetTppChanges(ctx context.Context, since time.Time) ([]models.TppChange, error) {
+	var changes []models.TppChange
+	for _, change := range m.changes {
+		if change.RecordedAt.After(since) {
+			change.TPP = nil
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (m *memoryDb) AddTppChanges(ctx context.Context, changes []models.TppChange) error {
+	for _, change := range changes {
+		change.Seq = int64(len(m.changes)) + 1
+		m.changes = append(m.changes, change)
+	}
+	return nil
+}
+
+func (m *memoryDb) Disconnect(ctx context.Context) error {
+	return nil
+}
+
+// register returns the TPPs as parsed from a register, and its parse error.
+func register(err error, tpps ...models.TPP) (<-chan models.TPP, <-chan error) {
+	out := make(chan models.TPP, len(tpps))
+	for _, tpp := range tpps {
+		out <- tpp
+	}
+	close(out)
+	errc := make(chan error, 1)
+	errc <- err
+	return out, errc
+}
+
+func TestImportTPPs(t *testing.T) {
+	authorizedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	withdrawnAt := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	tpp := func(id, name string, services map[string][]models.Service) models.TPP {
+		return models.TPP{Id: id, OBID: "PSDFI-FINFSA-" + id, NameLatin: name, Country: "FI", AuthorizedAt: &authorizedAt, Services: services}
+	}
+	fi := map[string][]models.Service{"FI": {models.AISP, models.PISP}}
+
+	m := &memoryDb{tpps: map[string]models.TPP{}}
+	out, errc := register(nil, tpp("1", "One", fi), tpp("2", "Two", fi), tpp("3", "Three", fi), models.TPP{})
+	if err := importTPPs(context.Background(), m, out, errc, at); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	if len(m.tpps) != 3 || len(m.changes) != 3 || m.changes[0].Type != models.TppAdded {
+		t.Fatalf("importTPPs() = %d TPPs, %+v, want 3 added", len(m.tpps), m.changes)
+	}
+
+	withdrawn := tpp("1", "One", map[string][]models.Service{"FI": {models.PISP, models.AISP}})
+	withdrawn.WithdrawnAt = &withdrawnAt
+	renamed := tpp("2", "Two Oy", map[string][]models.Service{"FI": {models.AISP}, "SE": {models.AISP}})
+	out, errc = register(nil, withdrawn, renamed, tpp("4", "Four", nil))
+	if err := importTPPs(context.Background(), m, out, errc, at); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	want := []struct {
+		typ models.TppChangeType
+		id  string
+	}{
+		{models.TppWithdrawn, "1"},
+		{models.TppServicesChanged, "2"},
+		{models.TppNameChanged, "2"},
+		{models.TppAdded, "4"},
+		{models.TppRemoved, "3"},
+	}
+	changes := m.changes[3:]
+	if len(changes) != len(want) {
+		t.Fatalf("importTPPs() changes = %+v, want %v", changes, want)
+	}
+	for i, w := range want {
+		if changes[i].Type != w.typ || changes[i].Id != w.id || changes[i].Seq != int64(i+4) || !changes[i].RecordedAt.Equal(at) {
+			t.Errorf("importTPPs() change %d = %+v, want %s of %s", i, changes[i], w.typ, w.id)
+		}
+	}
+	if _, ok := m.tpps["3"]; ok {
+		t.Error("importTPPs() kept the removed TPP")
+	}
+	if changes[4].TPP == nil || changes[4].TPP.NameLatin != "Three" {
+		t.Errorf("importTPPs() removed TPP = %+v, want the stored one", changes[4].TPP)
+	}
+
+	// A TPP with the OB ID of another one is skipped, but not removed
+	count := len(m.changes)
+	duplicate := tpp("4", "Four", nil)
+	duplicate.OBID = withdrawn.OBID
+	out, errc = register(nil, withdrawn, renamed, tpp("5", "Five", fi), duplicate)
+	if err := importTPPs(context.Background(), m, out, errc, at); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	if changes := m.changes[count:]; len(changes) != 1 || changes[0].Type != models.TppAdded || changes[0].Id != "5" {
+		t.Errorf("importTPPs() changes = %+v, want 5 added", changes)
+	}
+	if stored := m.tpps["4"]; len(m.tpps) != 4 || stored.OBID != "PSDFI-FINFSA-4" {
+		t.Errorf("importTPPs() TPPs = %+v, want 4 kept with its OB ID", m.tpps)
+	}
+
+	// A TPP whose dates could not be read keeps the stored ones, the others are still removed
+	count = len(m.changes)
+	undated := renamed
+	undated.AuthorizedAt = nil
+	out, errc = register(nil, withdrawn, undated, tpp("5", "Five", fi), tpp("6", "Six", nil))
+	if err := importTPPs(context.Background(), m, out, errc, at); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	if changes := m.changes[count:]; len(changes) != 2 || changes[0].Type != models.TppAdded || changes[1].Type != models.TppRemoved || changes[1].Id != "4" {
+		t.Errorf("importTPPs() changes = %+v, want 6 added and 4 removed", changes)
+	}
+	if stored := m.tpps["2"]; stored.AuthorizedAt == nil || !stored.AuthorizedAt.Equal(authorizedAt) {
+		t.Errorf("importTPPs() TPP 2 = %+v, want the stored authorization date", stored)
+	}
+	undated = tpp("7", "Seven", fi)
+	undated.AuthorizedAt = nil
+	out, errc = register(nil, withdrawn, renamed, tpp("5", "Five", fi), tpp("6", "Six", nil), undated)
+	if err := importTPPs(context.Background(), m, out, errc, at); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	if _, ok := m.tpps["7"]; ok {
+		t.Error("importTPPs() added a TPP without an authorization date")
+	}
+
+	// An incomplete or empty register removes nothing
+	count = len(m.changes)
+	out, errc = register(errors.New("expected end of inner array"), tpp("1", "One", fi))
+	if err := importTPPs(context.Background(), m, out, errc, at); err == nil {
+		t.Error("importTPPs() error = nil, want the parse error")
+	}
+	out, errc = register(nil)
+	if err := importTPPs(context.Background(), m, out, errc, at); !errors.Is(err, errEmptyRegister) {
+		t.Errorf("importTPPs() error = %v, want %v", err, errEmptyRegister)
+	}
+	if len(m.tpps) != 4 || slices.ContainsFunc(m.changes[count:], func(c models.TppChange) bool { return c.Type == models.TppRemoved }) {
+		t.Errorf("importTPPs() removed TPPs of an incomplete register: %+v", m.changes[count:])
+	}
+}
+
+func TestDiffTpp_ScheduledWithdrawal(t *testing.T) {
+	authorizedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	withdrawnAt := time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC)
+	stored := models.TPP{Id: "1", AuthorizedAt: &authorizedAt, WithdrawnAt: &withdrawnAt, UpdatedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)}
+	imported := stored
+	changes := diffTpp(&stored, imported, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if len(changes) != 1 || changes[0].Type != models.TppWithdrawn {
+		t.Errorf("diffTpp() = %+v, want withdrawn", changes)
+	}
+	stored.UpdatedAt = withdrawnAt.Add(time.Hour)
+	if changes := diffTpp(&stored, imported, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)); len(changes) != 0 {
+		t.Errorf("diffTpp() = %+v, want no changes once the withdrawal was imported", changes)
+	}
+}
+
+func TestImportTPPs_SaveFailure(t *testing.T) {
+	authorizedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	fi := map[string][]models.Service{"FI": {models.AISP}}
+	tpp := func(id string, services map[string][]models.Service) models.TPP {
+		return models.TPP{Id: id, OBID: "PSDFI-FINFSA-" + id, Country: "FI", AuthorizedAt: &authorizedAt, Services: services}
+	}
+
+	m := &memoryDb{tpps: map[string]models.TPP{}}
+	out, errc := register(nil, tpp("1", fi), tpp("2", fi))
+	if err := importTPPs(context.Background(), m, out, errc, at); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+
+	// The import records its changes, then fails to save the TPPs
+	saveErr := errors.New("connection reset")
+	m.saveErr = saveErr
+	changed := map[string][]models.Service{"FI": {models.AISP, models.PISP}}
+	out, errc = register(nil, tpp("1", changed), tpp("3", fi))
+	if err := importTPPs(context.Background(), m, out, errc, at.Add(time.Hour)); !errors.Is(err, saveErr) {
+		t.Fatalf("importTPPs() error = %v, want %v", err, saveErr)
+	}
+	if len(m.changes) != 4 || len(m.tpps["1"].Services["FI"]) != 1 {
+		t.Fatalf("importTPPs() = %+v, %d changes, want the changes recorded and the TPPs not saved", m.tpps, len(m.changes))
+	}
+
+	// The next import saves the TPPs without recording the changes again
+	out, errc = register(nil, tpp("1", changed), tpp("3", fi))
+	if err := importTPPs(context.Background(), m, out, errc, at.Add(2*time.Hour)); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	want := []models.TppChangeType{models.TppAdded, models.TppAdded, models.TppServicesChanged, models.TppAdded, models.TppRemoved}
+	var got []models.TppChangeType
+	for _, change := range m.changes {
+		got = append(got, change.Type)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("importTPPs() changes = %v, want %v", got, want)
+	}
+	if len(m.tpps) != 2 || len(m.tpps["1"].Services["FI"]) != 2 {
+		t.Errorf("importTPPs() TPPs = %+v, want 1 and 3 saved", m.tpps)
+	}
+
+	// Later changes of the same type are recorded
+	out, errc = register(nil, tpp("1", fi), tpp("3", fi))
+	if err := importTPPs(context.Background(), m, out, errc, at.Add(3*time.Hour)); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	if last := m.changes[len(m.changes)-1]; len(m.changes) != 6 || last.Type != models.TppServicesChanged || last.Id != "1" {
+		t.Errorf("importTPPs() changes = %+v, want services_changed of 1", m.changes[5:])
+	}
+}
@@ -3,7 +3,9 @@ package tppdb
 import (
 	"context"
 	"errors"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/botsman/tppVerifier/app/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -32,12 +34,75 @@ func setupMongoDb(ctx context.Context, connStr string) (*MongoDb, error) {
 		return nil, err
 	}
 	db := client.Database(dbName)
+	if err := createTppIndexes(ctx, db.Collection("tpps")); err != nil {
+		return nil, err
+	}
+	_, err = db.Collection("tpp_changes").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "seq", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "recorded_at", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &MongoDb{
 		Client:   client,
 		Database: db,
 	}, nil
 }
 
+// createTppIndexes creates the indexes of the TPP lookups and searches of server/mongo.
+// Searches are ordered by ob_id, so it is the last key of the filter indexes.
+func createTppIndexes(ctx context.Context, collection *mongo.Collection) error {
+	keys := []bson.D{
+		{{Key: "ob_id", Value: 1}},
+		{{Key: "id", Value: 1}},
+		{{Key: "national_reference", Value: 1}},
+		{{Key: "country", Value: 1}, {Key: "ob_id", Value: 1}},
+		{{Key: "authority", Value: 1}, {Key: "ob_id", Value: 1}},
+		{{Key: "type", Value: 1}, {Key: "ob_id", Value: 1}},
+		{{Key: "passported_countries", Value: 1}, {Key: "ob_id", Value: 1}},
+		{{Key: "service_types", Value: 1}, {Key: "ob_id", Value: 1}},
+		{{Key: "authorized_at", Value: 1}},
+	}
+	indexes := make([]mongo.IndexModel, len(keys))
+	for i, k := range keys {
+		indexes[i] = mongo.IndexModel{Keys: k}
+	}
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// tppDocument is the stored TPP. The countries and services are also stored as arrays,
+// as the keys of the services map cannot be indexed.
+type tppDocument struct {
+	models.TPP `bson:",inline"`
+	// NationalReference is the last part of the OB ID, the national reference code without dashes.
+	NationalReference string `bson:"national_reference"`
+	// PassportedCountries are the countries of the services besides the home country.
+	PassportedCountries []string         `bson:"passported_countries"`
+	ServiceTypes        []models.Service `bson:"service_types"`
+}
+
+func newTppDocument(tpp models.TPP) tppDocument {
+	doc := tppDocument{TPP: tpp, PassportedCountries: []string{}, ServiceTypes: []models.Service{}}
+	if i := strings.LastIndex(tpp.OBID, "-"); i >= 0 {
+		doc.NationalReference = tpp.OBID[i+1:]
+	}
+	for country, services := range tpp.Services {
+		if country != tpp.Country {
+			doc.PassportedCountries = append(doc.PassportedCountries, country)
+		}
+		for _, service := range services {
+			if !slices.Contains(doc.ServiceTypes, service) {
+				doc.ServiceTypes = append(doc.ServiceTypes, service)
+			}
+		}
+	}
+	slices.Sort(doc.PassportedCountries)
+	slices.Sort(doc.ServiceTypes)
+	return doc
+}
+
 func extractDatabaseName(connStr string) (string, error) {
 	opts := options.Client().ApplyURI(connStr)
 	if opts.Auth != nil && opts.Auth.AuthSource != "" {
@@ -59,7 +124,7 @@ func (db *MongoDb) SaveTPPs(ctx context.Context, collectionName string, tpps []m
 	opts := options.Update().SetUpsert(true)
 	for _, tpp := range tpps {
 		filter := bson.M{"id": tpp.Id}
-		update := bson.M{"$set": tpp}
+		update := bson.M{"$set": newTppDocument(tpp)}
 		_, err := collection.UpdateOne(ctx, filter, update, opts)
 		if err != nil {
 			return err
@@ -67,3 +132,55 @@ func (db *MongoDb) SaveTPPs(ctx context.Context, collectionName string, tpps []m
 	}
 	return nil
 }
+
+func (db *MongoDb) GetTPPs(ctx context.Context) ([]models.TPP, error) {
+	cursor, err := db.Database.Collection("tpps").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var tpps []models.TPP
+	if err := cursor.All(ctx, &tpps); err != nil {
+		return nil, err
+	}
+	return tpps, nil
+}
+
+func (db *MongoDb) DeleteTPPs(ctx context.Context, ids []string) error {
+	_, err := db.Database.Collection("tpps").DeleteMany(ctx, bson.M{"id": bson.M{"$in": ids}})
+	return err
+}
+
+func (db *MongoDb) GetTppChanges(ctx context.Context, since time.Time) ([]models.TppChange, error) {
+	opts := options.Find().SetSort(bson.M{"seq": 1}).SetProjection(bson.M{"tpp": 0})
+	cursor, err := db.Database.Collection("tpp_changes").Find(ctx, bson.M{"recorded_at": bson.M{"$gt": since}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var changes []models.TppChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// AddTppChanges numbers the changes after the last recorded one. Only one import may run at a time.
+func (db *MongoDb) AddTppChanges(ctx context.Context, changes []models.TppChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	collection := db.Database.Collection("tpp_changes")
+	var last models.TppChange
+	err := collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"seq": -1})).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	docs := make([]any, len(changes))
+	for i, change := range changes {
+		change.Seq = last.Seq + int64(i) + 1
+		docs[i] = change
+	}
+	_, err = collection.InsertMany(ctx, docs)
+	return err
+}
@@ -3,7 +3,6 @@ package tppdb
 import (
 	"archive/zip"
 	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,6 +27,8 @@ type RawTPP struct {
 	Names                 []string
 	Country               string
 	Services              map[string][]models.Service
+	// invalidDates is set if the authorization dates could not be parsed.
+	invalidDates bool
 }
 
 func (r *RawTPP) GetLatinName() string {
@@ -117,8 +118,9 @@ func (r *RawTPP) UnmarshalJSON(data []byte) error {
 	if len(authorizedAtVal) > 0 {
 		authorizedAt, withdrawnAt, err := parseAuthorizedAt(authorizedAtVal)
 		if err != nil {
-			// log.Printf("Error parsing authorized date: %v, skipping this TPP\n", err)
-			return nil
+			// The TPP is still imported, keeping its stored dates, so that it is not removed
+			log.Printf("Entity %s: %v, keeping the stored dates", code, err)
+			r.invalidDates = true
 		}
 		r.AuthorizedAt = authorizedAt
 		r.WithdrawnAt = withdrawnAt
@@ -387,22 +389,32 @@ func unzipFile(f *zip.File) error {
 	return nil
 }
 
-func parseRegistry() (<-chan models.TPP, error) {
+// errUndecodedRecords reports register records that could not be decoded. The other
+// records are still imported, but no TPPs are removed as the register is incomplete.
+var errUndecodedRecords = errors.New("register records could not be decoded")
+
+// parseRegistry returns the TPPs of the register. Once they are all read, the error channel
+// receives the error that stopped the parsing, errUndecodedRecords if records were skipped,
+// or nil if the whole register was parsed.
+func parseRegistry() (<-chan models.TPP, <-chan error, error) {
 	file, err := os.Open(RegisterJsonName)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	res := make(chan models.TPP)
+	errc := make(chan error, 1)
 	go func() {
 		defer file.Close()
 		defer close(res)
 		dec := json.NewDecoder(file)
+		undecoded := 0
 
 		// Expect the outer array
 		t, err := dec.Token()
 		if err != nil || t != json.Delim('[') {
 			log.Printf("Expected outer array: %v", err)
+			errc <- fmt.Errorf("expected outer array: %v", err)
 			return
 		}
 
@@ -412,6 +424,7 @@ func parseRegistry() (<-chan models.TPP, error) {
 			t, err := dec.Token()
 			if err != nil || t != json.Delim('[') {
 				log.Printf("Expected inner array: %v", err)
+				errc <- fmt.Errorf("expected inner array: %v", err)
 				return
 			}
 			// Iterate over RawTPP objects in inner array
@@ -419,6 +432,7 @@ func parseRegistry() (<-chan models.TPP, error) {
 				var rawTpp RawTPP
 				if err := dec.Decode(&rawTpp); err != nil {
 					log.Printf("Error decoding RawTPP: %v", err)
+					undecoded++
 					continue
 				}
 				if rawTpp.CA_OwnerID == "" || rawTpp.Code == "" {
@@ -427,7 +441,7 @@ func parseRegistry() (<-chan models.TPP, error) {
 				if rawTpp.Type == "" {
 					continue
 				}
-				if rawTpp.AuthorizedAt == nil || rawTpp.AuthorizedAt.IsZero() {
+				if (rawTpp.AuthorizedAt == nil || rawTpp.AuthorizedAt.IsZero()) && !rawTpp.invalidDates {
 					continue
 				}
 				if !slices.Contains([]string{"PSD_AISP", "PSD_PI", "PSD_EMI"}, rawTpp.Type) {
@@ -438,36 +452,17 @@ func parseRegistry() (<-chan models.TPP, error) {
 			// End of inner array
 			if t, err := dec.Token(); err != nil || t != json.Delim(']') {
 				log.Printf("Expected end of inner array: %v", err)
+				errc <- fmt.Errorf("expected end of inner array: %v", err)
 				return
 			}
 		}
 		// End of outer array
 		_, _ = dec.Token()
-	}()
-	return res, nil
-}
-
-func saveTPPs(dbImpl db, out <-chan models.TPP) error {
-	batchSize := 1000
-	batch := make([]models.TPP, 0, batchSize)
-	idx := 0
-	for tpp := range out {
-		idx += 1
-		batch = append(batch, tpp)
-		if idx == batchSize {
-			err := dbImpl.SaveTPPs(context.TODO(), "tpps", batch)
-			if err != nil {
-				return err
-			}
-			batch = make([]models.TPP, 0, batchSize)
-			idx = 0
-		}
-	}
-	if len(batch) > 0 {
-		err := dbImpl.SaveTPPs(context.TODO(), "tpps", batch)
-		if err != nil {
-			return err
+		if undecoded > 0 {
+			errc <- fmt.Errorf("%w: %d records", errUndecodedRecords, undecoded)
+			return
 		}
-	}
-	return nil
+		errc <- nil
+	}()
+	return res, errc, nil
 }
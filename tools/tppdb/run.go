@@ -3,12 +3,21 @@ package tppdb
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/botsman/tppVerifier/app/models"
 )
 
 type db interface {
+	// GetTPPs returns the stored TPPs, to find the changes of an import.
+	GetTPPs(ctx context.Context) ([]models.TPP, error)
 	SaveTPPs(ctx context.Context, collection string, tpp []models.TPP) error
+	// DeleteTPPs deletes the TPPs with the given entity codes.
+	DeleteTPPs(ctx context.Context, ids []string) error
+	// GetTppChanges returns the changes recorded after the given time in order, without their TPP.
+	GetTppChanges(ctx context.Context, since time.Time) ([]models.TppChange, error)
+	// AddTppChanges records the changes in order, numbering them after the recorded ones.
+	AddTppChanges(ctx context.Context, changes []models.TppChange) error
 	Disconnect(ctx context.Context) error
 }
 
@@ -19,7 +28,7 @@ func Run(ctx context.Context, connStr string) error {
 	// 2. Download the zip file at `golden_copy_path_context` + `latest_version_relative_zip_path`
 	// 3. Unzip the file
 	// 4. Parse the file
-	// 5. Save the parsed data to the DB
+	// 5. Save the parsed data to the DB, recording the changes from the stored data
 	client, err := setupMongoDb(ctx, connStr)
 	// client, err := setupSqliteDb(ctx, connStr)
 	if err != nil {
@@ -33,10 +42,10 @@ func Run(ctx context.Context, connStr string) error {
 	}
 	defer deleteRegistry()
 
-	tppChan, err := parseRegistry()
+	tppChan, errChan, err := parseRegistry()
 	if err != nil {
 		return err
 	}
 	defer client.Disconnect(ctx)
-	return saveTPPs(client, tppChan)
+	return importTPPs(ctx, client, tppChan, errChan, time.Now().UTC())
 }
@@ -3,6 +3,8 @@ package tppdb
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
 
 	"github.com/botsman/tppVerifier/app/models"
 	_ "github.com/mattn/go-sqlite3"
@@ -28,6 +30,53 @@ func (db *SqliteDb) Disconnect(ctx context.Context) error {
 	return db.DB.Close()
 }
 
+func (db *SqliteDb) GetTPPs(ctx context.Context) ([]models.TPP, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT id, ob_id, name_latin, name_native, authority, country, type, registry, authorized_at, withdrawn_at, created_at, updated_at FROM tpps`)
+	if err != nil {
+		return nil, err
+	}
+	var tpps []models.TPP
+	byObId := make(map[string]int)
+	for rows.Next() {
+		var tpp models.TPP
+		var authorizedAt, withdrawnAt sql.NullTime
+		if err := rows.Scan(&tpp.Id, &tpp.OBID, &tpp.NameLatin, &tpp.NameNative, &tpp.Authority, &tpp.Country, &tpp.Type, &tpp.Registry, &authorizedAt, &withdrawnAt, &tpp.CreatedAt, &tpp.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if authorizedAt.Valid {
+			tpp.AuthorizedAt = &authorizedAt.Time
+		}
+		if withdrawnAt.Valid {
+			tpp.WithdrawnAt = &withdrawnAt.Time
+		}
+		tpp.Services = make(map[string][]models.Service)
+		byObId[tpp.OBID] = len(tpps)
+		tpps = append(tpps, tpp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.DB.QueryContext(ctx, `SELECT tpp_ob_id, country, service FROM tpp_services`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var obId, country, service string
+		if err := rows.Scan(&obId, &country, &service); err != nil {
+			return nil, err
+		}
+		if i, ok := byObId[obId]; ok {
+			tpps[i].Services[country] = append(tpps[i].Services[country], models.Service(service))
+		}
+	}
+	return tpps, rows.Err()
+}
+
+// SaveTPPs inserts or updates the TPPs, replacing their services.
 func (db *SqliteDb) SaveTPPs(ctx context.Context, _ string, tpps []models.TPP) error {
 	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -39,7 +88,29 @@ func (db *SqliteDb) SaveTPPs(ctx context.Context, _ string, tpps []models.TPP) e
 		}
 	}()
 
-	tppStmt, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO tpps (id, ob_id, name_latin, name_native, authority, country, type, registry, authorized_at, withdrawn_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	// The OB ID of a TPP changes with its national reference code, so services are deleted by entity code
+	deleteStmt, err := tx.PrepareContext(ctx, `DELETE FROM tpp_services WHERE tpp_ob_id IN (SELECT ob_id FROM tpps WHERE id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer deleteStmt.Close()
+
+	// The register may reassign the OB ID of a TPP to another entity. The TPP holding it is
+	// deleted; it is saved again with its new OB ID, or reported removed, later in the import.
+	conflictStmt, err := tx.PrepareContext(ctx, `DELETE FROM tpps WHERE ob_id = ? AND id != ?`)
+	if err != nil {
+		return err
+	}
+	defer conflictStmt.Close()
+	conflictServicesStmt, err := tx.PrepareContext(ctx, `DELETE FROM tpp_services WHERE tpp_ob_id = ?`)
+	if err != nil {
+		return err
+	}
+	defer conflictServicesStmt.Close()
+
+	tppStmt, err := tx.PrepareContext(ctx, `INSERT INTO tpps (id, ob_id, name_latin, name_native, authority, country, type, registry, authorized_at, withdrawn_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET ob_id = excluded.ob_id, name_latin = excluded.name_latin, name_native = excluded.name_native, authority = excluded.authority,
+		country = excluded.country, type = excluded.type, registry = excluded.registry, authorized_at = excluded.authorized_at, withdrawn_at = excluded.withdrawn_at, updated_at = excluded.updated_at`)
 	if err != nil {
 		return err
 	}
@@ -52,6 +123,15 @@ func (db *SqliteDb) SaveTPPs(ctx context.Context, _ string, tpps []models.TPP) e
 	defer serviceStmt.Close()
 
 	for _, tpp := range tpps {
+		if _, err = deleteStmt.ExecContext(ctx, tpp.Id); err != nil {
+			return err
+		}
+		if _, err = conflictServicesStmt.ExecContext(ctx, tpp.OBID); err != nil {
+			return err
+		}
+		if _, err = conflictStmt.ExecContext(ctx, tpp.OBID, tpp.Id); err != nil {
+			return err
+		}
 		_, err = tppStmt.ExecContext(ctx, tpp.Id, tpp.OBID, tpp.NameLatin, tpp.NameNative, tpp.Authority, tpp.Country, tpp.Type, tpp.Registry, tpp.AuthorizedAt, tpp.WithdrawnAt, tpp.CreatedAt, tpp.UpdatedAt)
 		if err != nil {
 			return err
@@ -67,3 +147,70 @@ func (db *SqliteDb) SaveTPPs(ctx context.Context, _ string, tpps []models.TPP) e
 	}
 	return tx.Commit()
 }
+
+func (db *SqliteDb) DeleteTPPs(ctx context.Context, ids []string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	for _, id := range ids {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM tpp_services WHERE tpp_ob_id IN (SELECT ob_id FROM tpps WHERE id = ?)`, id); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM tpps WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db *SqliteDb) GetTppChanges(ctx context.Context, since time.Time) ([]models.TppChange, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT seq, type, id, ob_id, recorded_at FROM tpp_changes WHERE recorded_at > ? ORDER BY seq`, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []models.TppChange
+	for rows.Next() {
+		var change models.TppChange
+		if err := rows.Scan(&change.Seq, &change.Type, &change.Id, &change.OBID, &change.RecordedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// AddTppChanges relies on the AUTOINCREMENT sequence of tpp_changes to number the changes.
+func (db *SqliteDb) AddTppChanges(ctx context.Context, changes []models.TppChange) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO tpp_changes (type, id, ob_id, recorded_at, document) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, change := range changes {
+		var document []byte
+		document, err = json.Marshal(change.TPP)
+		if err != nil {
+			return err
+		}
+		if _, err = stmt.ExecContext(ctx, string(change.Type), change.Id, change.OBID, change.RecordedAt.UTC(), document); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
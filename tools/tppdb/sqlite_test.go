@@ -0,0 +1,75 @@
+package tppdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/botsman/tppVerifier/app/models"
+)
+
+func newTestSqliteDb(t *testing.T) *SqliteDb {
+	t.Helper()
+	db, err := setupSqliteDb(filepath.Join(t.TempDir(), "tpps.db"))
+	if err != nil {
+		t.Fatalf("setupSqliteDb() error = %v", err)
+	}
+	t.Cleanup(func() { db.Disconnect(context.Background()) })
+	schema, err := os.ReadFile("../sqlite/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB.Exec(string(schema)); err != nil {
+		t.Fatalf("schema error = %v", err)
+	}
+	return db
+}
+
+func TestSqliteDb_ReassignedOBID(t *testing.T) {
+	db := newTestSqliteDb(t)
+	ctx := context.Background()
+	authorizedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	tpp := func(id, obID string) models.TPP {
+		return models.TPP{Id: id, OBID: obID, Country: "FI", AuthorizedAt: &authorizedAt, Services: map[string][]models.Service{"FI": {models.AISP}}}
+	}
+
+	out, errc := register(nil, tpp("1", "PSDFI-FINFSA-1"), tpp("2", "PSDFI-FINFSA-2"))
+	if err := importTPPs(ctx, db, out, errc, at); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	// The OB ID of 1 is reassigned to 3, and 1 gets a new one
+	out, errc = register(nil, tpp("3", "PSDFI-FINFSA-1"), tpp("2", "PSDFI-FINFSA-2"), tpp("1", "PSDFI-FINFSA-1A"))
+	if err := importTPPs(ctx, db, out, errc, at.Add(time.Hour)); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	tpps, err := db.GetTPPs(ctx)
+	if err != nil {
+		t.Fatalf("GetTPPs() error = %v", err)
+	}
+	obIDs := make(map[string]string)
+	for _, tpp := range tpps {
+		obIDs[tpp.Id] = tpp.OBID
+		if len(tpp.Services["FI"]) != 1 {
+			t.Errorf("GetTPPs() services of %s = %v, want [AIS]", tpp.Id, tpp.Services)
+		}
+	}
+	if len(obIDs) != 3 || obIDs["1"] != "PSDFI-FINFSA-1A" || obIDs["3"] != "PSDFI-FINFSA-1" {
+		t.Errorf("GetTPPs() OB IDs = %v, want 1 and 3 with their new OB IDs", obIDs)
+	}
+
+	// 3 gives the OB ID back to 1, which is no longer in the register
+	out, errc = register(nil, tpp("3", "PSDFI-FINFSA-3"), tpp("2", "PSDFI-FINFSA-2"), tpp("4", "PSDFI-FINFSA-1A"))
+	if err := importTPPs(ctx, db, out, errc, at.Add(2*time.Hour)); err != nil {
+		t.Fatalf("importTPPs() error = %v", err)
+	}
+	changes, err := db.GetTppChanges(ctx, at.Add(2*time.Hour).Add(-time.Second))
+	if err != nil {
+		t.Fatalf("GetTppChanges() error = %v", err)
+	}
+	if len(changes) != 2 || changes[0].Type != models.TppAdded || changes[1].Type != models.TppRemoved || changes[1].Id != "1" {
+		t.Errorf("GetTppChanges() = %+v, want 4 added and 1 removed", changes)
+	}
+}
@@ -1,7 +1,14 @@
 package tppdb
 
 import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/botsman/tppVerifier/app/models"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestParseOBID(t *testing.T) {
@@ -68,3 +75,82 @@ func TestParseOBID(t *testing.T) {
 		})
 	}
 }
+
+func TestNewTppDocument(t *testing.T) {
+	tpp := models.TPP{
+		OBID:    "PSDFI-FINFSA-01110279",
+		Country: "FI",
+		Services: map[string][]models.Service{
+			"FI": {models.AISP, models.PISP},
+			"SE": {models.AISP},
+			"DK": {models.PISP},
+		},
+	}
+	doc := newTppDocument(tpp)
+	if got := strings.Join(doc.PassportedCountries, ","); got != "DK,SE" {
+		t.Errorf("newTppDocument() passported countries = %v, want DK,SE", got)
+	}
+	if !slices.Equal(doc.ServiceTypes, []models.Service{models.AISP, models.PISP}) {
+		t.Errorf("newTppDocument() service types = %v, want [AIS PIS]", doc.ServiceTypes)
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("bson.Marshal() error = %v", err)
+	}
+	if obID := bson.Raw(raw).Lookup("ob_id").StringValue(); obID != tpp.OBID {
+		t.Errorf("newTppDocument() ob_id = %v, want the inlined TPP", obID)
+	}
+	if ref := bson.Raw(raw).Lookup("national_reference").StringValue(); ref != "01110279" {
+		t.Errorf("newTppDocument() national_reference = %v, want 01110279", ref)
+	}
+}
+
+func TestParseRegistry(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+
+	entity := func(code, authorizedAt string) string {
+		return `{"CA_OwnerID": "FI_FIN-FSA", "EntityCode": "` + code + `", "EntityType": "PSD_PI", "Properties": [
+			{"ENT_AUT": "` + authorizedAt + `"}, {"ENT_NAT_REF_COD": "` + code + `"}, {"ENT_NAM": "TPP ` + code + `"}, {"ENT_COU_RES": "FI"}
+		], "Services": [{"FI": ["PS_080", "PS_070"]}]}`
+	}
+	register := "[[" + entity("1", "2020-01-01") + "," + entity("2", "01.01.2020") + "," + entity("3", "2021-06-30") + "]]"
+	if err := os.WriteFile(RegisterJsonName, []byte(register), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errc, err := parseRegistry()
+	if err != nil {
+		t.Fatalf("parseRegistry() error = %v", err)
+	}
+	var tpps []models.TPP
+	for tpp := range out {
+		tpps = append(tpps, tpp)
+	}
+	if len(tpps) != 3 || tpps[0].AuthorizedAt == nil || tpps[1].Id != "2" || tpps[1].AuthorizedAt != nil || tpps[2].AuthorizedAt == nil {
+		t.Errorf("parseRegistry() = %+v, want 1 to 3 with the dates of 2 unset", tpps)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("parseRegistry() error = %v, want nil", err)
+	}
+
+	// A record that cannot be decoded makes the register incomplete
+	if err := os.WriteFile(RegisterJsonName, []byte(`[[`+entity("1", "2020-01-01")+`, {"CA_OwnerID": "FI_FIN-FSA"}]]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, errc, err = parseRegistry()
+	if err != nil {
+		t.Fatalf("parseRegistry() error = %v", err)
+	}
+	for range out {
+	}
+	if err := <-errc; !errors.Is(err, errUndecodedRecords) {
+		t.Errorf("parseRegistry() error = %v, want %v", err, errUndecodedRecords)
+	}
+}